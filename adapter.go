@@ -0,0 +1,66 @@
+package lex
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// OpenOptions describe how an adapter opens a store.
+type OpenOptions struct {
+	// ReadOnly opens the store read-only, where the storage engine allows it.
+	ReadOnly bool
+
+	// Params holds adapter-specific parameters, such as the bucket of a Bolt
+	// database.
+	Params map[string]string
+}
+
+// Opener opens a KVStore on a storage engine, from a path or an address. The
+// returned Closer releases the underlying database.
+type Opener func(path string, o OpenOptions) (KVStore, io.Closer, error)
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   = map[string]Opener{}
+)
+
+// RegisterAdapter makes an adapter available under the given name. Adapter
+// packages register themselves from an init function, so that importing them
+// (even with a blank import) lets tools open stores by adapter name.
+// RegisterAdapter panics if an adapter is already registered under the name.
+func RegisterAdapter(name string, open Opener) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+
+	if _, ok := adapters[name]; ok {
+		panic(fmt.Sprintf("adapter %q is already registered", name))
+	}
+	adapters[name] = open
+}
+
+// Open opens a store with the adapter registered under the given name.
+func Open(adapter, path string, o OpenOptions) (KVStore, io.Closer, error) {
+	adaptersMu.RLock()
+	open, ok := adapters[adapter]
+	adaptersMu.RUnlock()
+
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown adapter %q (forgotten import?)", adapter)
+	}
+	return open(path, o)
+}
+
+// Adapters returns the sorted names of the registered adapters.
+func Adapters() []string {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
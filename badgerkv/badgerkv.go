@@ -0,0 +1,188 @@
+// Package badgerkv adapts BadgerDB (https://github.com/dgraph-io/badger) to the
+// lex.KVStore interface, so that the tuple and subspace layers can be used
+// directly on top of a Badger database.
+//
+// Ranges and key selectors are resolved with Badger iterators. A Store runs
+// every call in its own Badger transaction; use Txn to group several calls
+// into a single transaction.
+package badgerkv
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Store implements lex.KVStore on top of a Badger database.
+type Store struct {
+	db *badger.DB
+}
+
+// New returns a Store backed by the provided Badger database.
+func New(db *badger.DB) *Store {
+	return &Store{db}
+}
+
+// GetRange implements lex.KVStore in a read-only Badger transaction.
+func (s *Store) GetRange(r lex.Range, options lex.RangeOptions) (kvs []lex.KeyValue, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		kvs, err = Txn(txn).GetRange(r, options)
+		return err
+	})
+	return
+}
+
+// GetKey implements lex.KVStore in a read-only Badger transaction.
+func (s *Store) GetKey(sel lex.Selectable) (k lex.Key, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		k, err = Txn(txn).GetKey(sel)
+		return err
+	})
+	return
+}
+
+// Set implements lex.KVStore in a read-write Badger transaction.
+func (s *Store) Set(key lex.KeyConvertible, value []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return Txn(txn).Set(key, value)
+	})
+}
+
+// Clear implements lex.KVStore in a read-write Badger transaction.
+func (s *Store) Clear(key lex.KeyConvertible) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return Txn(txn).Clear(key)
+	})
+}
+
+// Txn returns a lex.KVStore which performs all reads and writes in the
+// provided Badger transaction. The caller remains responsible for committing
+// or discarding the transaction.
+func Txn(txn *badger.Txn) lex.KVStore {
+	return txnStore{txn}
+}
+
+type txnStore struct {
+	txn *badger.Txn
+}
+
+// iterator returns an iterator over the keys of the transaction, which
+// prefetches values only if values is set: resolving key selectors only
+// reads keys.
+func (t txnStore) iterator(reverse, values bool) *badger.Iterator {
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = reverse
+	opts.PrefetchValues = values
+	return t.txn.NewIterator(opts)
+}
+
+func (t txnStore) GetKey(sel lex.Selectable) (lex.Key, error) {
+	ks, n := sel.LexKeySelector().Normalize()
+	switch {
+	case ks.BeforeAll():
+		return lex.MinKey, nil
+	case ks.AfterAll():
+		return lex.MaxAppKey, nil
+	}
+	k := []byte(ks.Key.LexKey())
+
+	if ks.Forward() {
+		// The first key greater than (or equal to, unless OrEqual is set) the
+		// selector key, then n keys forward.
+		it := t.iterator(false, false)
+		defer it.Close()
+
+		it.Seek(k)
+		if ks.OrEqual && it.Valid() && bytes.Equal(it.Item().Key(), k) {
+			it.Next()
+		}
+		for i := 0; i < n && it.Valid(); i++ {
+			it.Next()
+		}
+		if !it.Valid() {
+			return lex.MaxAppKey, nil
+		}
+		return lex.Key(it.Item().KeyCopy(nil)), nil
+	}
+
+	// The last key less than (or equal to, if OrEqual is set) the selector
+	// key, then -n keys backward. Backward selectors of the empty key, which
+	// a reverse Badger iterator would treat as a seek to the end, were
+	// resolved above as before all keys.
+	it := t.iterator(true, false)
+	defer it.Close()
+
+	it.Seek(k)
+	if !ks.OrEqual && it.Valid() && bytes.Equal(it.Item().Key(), k) {
+		it.Next()
+	}
+	for i := 0; i < -n && it.Valid(); i++ {
+		it.Next()
+	}
+	if !it.Valid() {
+		return lex.MinKey, nil
+	}
+	return lex.Key(it.Item().KeyCopy(nil)), nil
+}
+
+func (t txnStore) GetRange(r lex.Range, options lex.RangeOptions) ([]lex.KeyValue, error) {
+	bsel, esel := r.LexRangeKeySelectors()
+
+	begin, err := t.GetKey(bsel)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.GetKey(esel)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Compare(begin, end) >= 0 {
+		return nil, nil
+	}
+
+	it := t.iterator(options.Reverse, true)
+	defer it.Close()
+
+	var kvs []lex.KeyValue
+
+	if options.Reverse {
+		it.Seek(end)
+		if it.Valid() && bytes.Equal(it.Item().Key(), end) {
+			it.Next()
+		}
+	} else {
+		it.Seek(begin)
+	}
+
+	for ; it.Valid(); it.Next() {
+		item := it.Item()
+		if options.Reverse && bytes.Compare(item.Key(), begin) < 0 {
+			break
+		}
+		if !options.Reverse && bytes.Compare(item.Key(), end) >= 0 {
+			break
+		}
+
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, lex.KeyValue{Key: lex.Key(item.KeyCopy(nil)), Value: v})
+
+		if options.Limit > 0 && len(kvs) == options.Limit {
+			break
+		}
+	}
+
+	return kvs, nil
+}
+
+func (t txnStore) Set(key lex.KeyConvertible, value []byte) error {
+	return t.txn.Set(key.LexKey(), value)
+}
+
+func (t txnStore) Clear(key lex.KeyConvertible) error {
+	return t.txn.Delete(key.LexKey())
+}
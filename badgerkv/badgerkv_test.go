@@ -0,0 +1,22 @@
+package badgerkv
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+)
+
+func TestConformance(t *testing.T) {
+	lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		o := badger.DefaultOptions("").WithInMemory(true).WithLoggingLevel(badger.ERROR)
+		db, err := badger.Open(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return New(db)
+	})
+}
@@ -0,0 +1,20 @@
+package badgerkv
+
+import (
+	"io"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/abdullin/lex-go"
+)
+
+// The "badger" adapter opens the Badger database in the given directory.
+func init() {
+	lex.RegisterAdapter("badger", func(path string, o lex.OpenOptions) (lex.KVStore, io.Closer, error) {
+		db, err := badger.Open(badger.DefaultOptions(path).WithReadOnly(o.ReadOnly).WithLogger(nil))
+		if err != nil {
+			return nil, nil, err
+		}
+		return New(db), db, nil
+	})
+}
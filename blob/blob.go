@@ -0,0 +1,110 @@
+// Package blob stores values larger than an ordered key-value store accepts,
+// such as the 100 kB of FoundationDB, by splitting them into chunks of a fixed
+// size stored under consecutive keys: the chunk i of the blob id is stored
+// under the key (id, i) of the subspace of the blobs, so a blob is read back
+// in order with a single range read.
+//
+// A blob is written with one Set per chunk: unless the store applies them in
+// a transaction, a reader may observe a blob being written, or a writer
+// failing in the middle of a write may leave a mix of two versions of a blob,
+// which Read reports as an error if the blob has lost chunks.
+package blob
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/kvutil"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// DefaultChunkSize is the size of chunks when none is given to New. It is the
+// largest value FoundationDB recommends for good performance.
+const DefaultChunkSize = 10000
+
+// Blobs stores the blobs of a subspace, identified by any tuple element, such
+// as a string or a UUID.
+type Blobs struct {
+	Subspace  subspace.Subspace
+	ChunkSize int
+}
+
+// New returns the Blobs of the subspace s, split into chunks of chunkSize
+// bytes, or of DefaultChunkSize bytes if chunkSize is not positive. New will
+// panic if chunkSize is greater than lex.MaxValueSize.
+func New(s subspace.Subspace, chunkSize int) *Blobs {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > lex.MaxValueSize {
+		panic(fmt.Sprintf("blob: chunk size %d exceeds the limit of %d bytes", chunkSize, lex.MaxValueSize))
+	}
+	return &Blobs{Subspace: s, ChunkSize: chunkSize}
+}
+
+// Chunk returns the key of a chunk of a blob.
+func (b *Blobs) Chunk(id tuple.Element, chunk int64) lex.Key {
+	return b.Subspace.Pack(tuple.Tuple{id, chunk})
+}
+
+// Range returns the range of every chunk of a blob.
+func (b *Blobs) Range(id tuple.Element) lex.KeyRange {
+	begin, end := b.Subspace.Sub(id).LexRangeKeys()
+	return lex.KeyRange{Begin: begin, End: end}
+}
+
+// Write stores the data as the blob id, replacing any previous blob of that
+// id. An empty blob is stored as a single empty chunk, so that Read tells it
+// apart from a missing blob.
+func (b *Blobs) Write(store lex.KVStore, id tuple.Element, data []byte) error {
+	var n int64
+	for {
+		size := len(data)
+		if size > b.ChunkSize {
+			size = b.ChunkSize
+		}
+		if err := store.Set(b.Chunk(id, n), data[:size]); err != nil {
+			return err
+		}
+		n++
+		data = data[size:]
+		if len(data) == 0 {
+			break
+		}
+	}
+
+	// Clear the chunks of a longer previous blob.
+	r := lex.KeyRange{Begin: b.Chunk(id, n), End: b.Range(id).End}
+	_, err := kvutil.ClearRange(store, r, kvutil.Options{Samples: -1})
+	return err
+}
+
+// Read returns the blob id, or nil if there is no such blob. It returns an
+// error if a chunk of the blob is missing or misplaced.
+func (b *Blobs) Read(store lex.KVStore, id tuple.Element) ([]byte, error) {
+	kvs, err := store.GetRange(b.Range(id), lex.RangeOptions{})
+	if err != nil || len(kvs) == 0 {
+		return nil, err
+	}
+
+	s := b.Subspace.Sub(id)
+	data := make([]byte, 0, len(kvs)*b.ChunkSize)
+	for i, kv := range kvs {
+		t, err := s.Unpack(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("blob: %v", err)
+		}
+		if len(t) != 1 || t[0] != int64(i) {
+			return nil, fmt.Errorf("blob: expected chunk %d, got key %s", i, kvutil.FormatKey(kv.Key))
+		}
+		data = append(data, kv.Value...)
+	}
+	return data, nil
+}
+
+// Delete removes the blob id. Deleting a missing blob is not an error.
+func (b *Blobs) Delete(store lex.KVStore, id tuple.Element) error {
+	_, err := kvutil.ClearRange(store, b.Range(id), kvutil.Options{Samples: -1})
+	return err
+}
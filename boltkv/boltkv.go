@@ -0,0 +1,389 @@
+// Package boltkv adapts bbolt (https://go.etcd.io/bbolt) to the lex.KVStore
+// interface, so that embedded applications can use the tuple and subspace
+// layers on top of a Bolt database.
+//
+// Keys may either be stored in a single bucket with their full prefixes (see
+// New), or each subspace may be mapped onto a bucket of its own named after
+// its prefix (see NewBucketed). In both cases ranges and key selectors are
+// resolved with Bolt cursors, and the store presents a single ordered
+// keyspace.
+package boltkv
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+)
+
+// Store implements lex.KVStore on top of a Bolt database. Every call runs in
+// its own Bolt transaction; use Tx to group several calls into one.
+type Store struct {
+	db *bbolt.DB
+	l  layout
+}
+
+// New returns a Store keeping all keys, with their full prefixes, in the
+// named bucket. The bucket is created on the first write.
+func New(db *bbolt.DB, bucket []byte) *Store {
+	return &Store{db, single(bucket)}
+}
+
+// NewBucketed returns a Store which maps each of the provided subspaces onto a
+// bucket named after the subspace prefix, storing keys without that prefix.
+// Every key read or written through the store must belong to one of the
+// subspaces, and no subspace may contain another. Buckets are created on the
+// first write.
+//
+// Keys are stored in their bucket after a marker byte, which keeps the key of
+// a subspace itself (whose suffix is empty, and which Bolt would reject)
+// valid.
+func NewBucketed(db *bbolt.DB, spaces ...subspace.Subspace) (*Store, error) {
+	b := make(buckets, len(spaces))
+	for i, s := range spaces {
+		if len(s.Bytes()) == 0 {
+			return nil, errors.New("cannot map the empty subspace onto a bucket")
+		}
+		b[i] = s.Bytes()
+	}
+	sort.Slice(b, func(i, j int) bool { return bytes.Compare(b[i], b[j]) < 0 })
+	for i := 1; i < len(b); i++ {
+		if bytes.HasPrefix(b[i], b[i-1]) {
+			return nil, errors.New("mapped subspaces overlap")
+		}
+	}
+	return &Store{db, b}, nil
+}
+
+// Tx returns a lex.KVStore which performs all reads and writes in the
+// provided Bolt transaction. Writes require a writable transaction. The
+// caller remains responsible for committing or rolling back the transaction.
+func (s *Store) Tx(tx *bbolt.Tx) lex.KVStore {
+	return txStore{tx, s.l}
+}
+
+// GetRange implements lex.KVStore in a read-only Bolt transaction.
+func (s *Store) GetRange(r lex.Range, options lex.RangeOptions) (kvs []lex.KeyValue, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		kvs, err = s.Tx(tx).GetRange(r, options)
+		return err
+	})
+	return
+}
+
+// GetKey implements lex.KVStore in a read-only Bolt transaction.
+func (s *Store) GetKey(sel lex.Selectable) (k lex.Key, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		k, err = s.Tx(tx).GetKey(sel)
+		return err
+	})
+	return
+}
+
+// Set implements lex.KVStore in a read-write Bolt transaction.
+func (s *Store) Set(key lex.KeyConvertible, value []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return s.Tx(tx).Set(key, value)
+	})
+}
+
+// Clear implements lex.KVStore in a read-write Bolt transaction.
+func (s *Store) Clear(key lex.KeyConvertible) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return s.Tx(tx).Clear(key)
+	})
+}
+
+type txStore struct {
+	tx *bbolt.Tx
+	l  layout
+}
+
+func (t txStore) bucket(k []byte, create bool) (*bbolt.Bucket, []byte, error) {
+	name, suffix, err := t.l.locate(k)
+	if err != nil {
+		return nil, nil, err
+	}
+	if create {
+		b, err := t.tx.CreateBucketIfNotExists(name)
+		return b, suffix, err
+	}
+	return t.tx.Bucket(name), suffix, nil
+}
+
+func (t txStore) GetKey(sel lex.Selectable) (lex.Key, error) {
+	ks, n := sel.LexKeySelector().Normalize()
+	switch {
+	case ks.BeforeAll():
+		return lex.MinKey, nil
+	case ks.AfterAll():
+		return lex.MaxAppKey, nil
+	}
+	k := []byte(ks.Key.LexKey())
+	c := t.l.cursor(t.tx)
+
+	if ks.Forward() {
+		// The first key greater than (or equal to, unless OrEqual is set) the
+		// selector key, then n keys forward.
+		ck, _ := c.seek(k)
+		if ks.OrEqual && ck != nil && bytes.Equal(ck, k) {
+			ck, _ = c.next()
+		}
+		for i := 0; i < n && ck != nil; i++ {
+			ck, _ = c.next()
+		}
+		if ck == nil {
+			return lex.MaxAppKey, nil
+		}
+		return lex.Key(ck), nil
+	}
+
+	// The last key less than (or equal to, if OrEqual is set) the selector
+	// key, then -n keys backward.
+	ck, _ := c.seek(k)
+	switch {
+	case ck == nil:
+		ck, _ = c.last()
+	case !ks.OrEqual || !bytes.Equal(ck, k):
+		ck, _ = c.prev()
+	}
+	for i := 0; i < -n && ck != nil; i++ {
+		ck, _ = c.prev()
+	}
+	if ck == nil {
+		return lex.MinKey, nil
+	}
+	return lex.Key(ck), nil
+}
+
+func (t txStore) GetRange(r lex.Range, options lex.RangeOptions) ([]lex.KeyValue, error) {
+	bsel, esel := r.LexRangeKeySelectors()
+
+	begin, err := t.GetKey(bsel)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.GetKey(esel)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Compare(begin, end) >= 0 {
+		return nil, nil
+	}
+
+	c := t.l.cursor(t.tx)
+
+	var kvs []lex.KeyValue
+	var k, v []byte
+
+	if options.Reverse {
+		if k, v = c.seek(end); k == nil {
+			k, v = c.last()
+		} else {
+			k, v = c.prev()
+		}
+	} else {
+		k, v = c.seek(begin)
+	}
+
+	for k != nil {
+		if options.Reverse && bytes.Compare(k, begin) < 0 {
+			break
+		}
+		if !options.Reverse && bytes.Compare(k, end) >= 0 {
+			break
+		}
+
+		kvs = append(kvs, lex.KeyValue{Key: lex.Key(k), Value: concat(nil, v...)})
+
+		if options.Limit > 0 && len(kvs) == options.Limit {
+			break
+		}
+
+		if options.Reverse {
+			k, v = c.prev()
+		} else {
+			k, v = c.next()
+		}
+	}
+
+	return kvs, nil
+}
+
+func (t txStore) Set(key lex.KeyConvertible, value []byte) error {
+	b, k, err := t.bucket(key.LexKey(), true)
+	if err != nil {
+		return err
+	}
+	return b.Put(k, value)
+}
+
+func (t txStore) Clear(key lex.KeyConvertible) error {
+	b, k, err := t.bucket(key.LexKey(), false)
+	if err != nil || b == nil {
+		return err
+	}
+	return b.Delete(k)
+}
+
+// A layout maps the logical keyspace onto Bolt buckets.
+type layout interface {
+	// locate returns the bucket holding the key and the key within it.
+	locate(k []byte) (bucket, key []byte, err error)
+
+	// cursor returns a cursor over the whole logical keyspace.
+	cursor(tx *bbolt.Tx) cursor
+}
+
+// A cursor walks the logical keyspace. Keys it returns are full keys and are
+// always copies; values are only valid for the life of the transaction. All
+// methods return a nil key once the cursor moves past either end.
+type cursor interface {
+	seek(k []byte) ([]byte, []byte)
+	next() ([]byte, []byte)
+	prev() ([]byte, []byte)
+	last() ([]byte, []byte)
+}
+
+type single []byte
+
+func (s single) locate(k []byte) ([]byte, []byte, error) {
+	return s, k, nil
+}
+
+func (s single) cursor(tx *bbolt.Tx) cursor {
+	return newSegmentCursor(tx, [][]byte{s}, [][]byte{nil}, nil)
+}
+
+type buckets [][]byte
+
+func (b buckets) locate(k []byte) ([]byte, []byte, error) {
+	for _, p := range b {
+		if bytes.HasPrefix(k, p) {
+			return p, concat(bucketMarker, k[len(p):]...), nil
+		}
+	}
+	return nil, nil, errors.New("key is not in any mapped subspace")
+}
+
+func (b buckets) cursor(tx *bbolt.Tx) cursor {
+	return newSegmentCursor(tx, b, b, bucketMarker)
+}
+
+// bucketMarker precedes the keys stored in the buckets of a bucketed store.
+var bucketMarker = []byte{0x00}
+
+// segmentCursor chains the cursors of several buckets into a single cursor
+// over full keys. Each bucket holds the keys of one segment of the keyspace
+// with the segment prefix removed and the marker added; segments are disjoint
+// and ordered by prefix. Missing buckets are treated as empty segments.
+type segmentCursor struct {
+	prefixes [][]byte
+	marker   []byte
+	cursors  []*bbolt.Cursor
+	i        int
+}
+
+func newSegmentCursor(tx *bbolt.Tx, names, prefixes [][]byte, marker []byte) *segmentCursor {
+	c := &segmentCursor{prefixes: prefixes, marker: marker, cursors: make([]*bbolt.Cursor, len(names))}
+	for i, name := range names {
+		if b := tx.Bucket(name); b != nil {
+			c.cursors[i] = b.Cursor()
+		}
+	}
+	return c
+}
+
+func (c *segmentCursor) full(k, v []byte) ([]byte, []byte) {
+	if k == nil {
+		return nil, nil
+	}
+	return concat(c.prefixes[c.i], k[len(c.marker):]...), v
+}
+
+func (c *segmentCursor) first(i int) ([]byte, []byte) {
+	for c.i = i; c.i < len(c.cursors); c.i++ {
+		if c.cursors[c.i] == nil {
+			continue
+		}
+		if k, v := c.cursors[c.i].First(); k != nil {
+			return c.full(k, v)
+		}
+	}
+	c.i = len(c.cursors)
+	return nil, nil
+}
+
+func (c *segmentCursor) lastFrom(i int) ([]byte, []byte) {
+	for c.i = i; c.i >= 0; c.i-- {
+		if c.cursors[c.i] == nil {
+			continue
+		}
+		if k, v := c.cursors[c.i].Last(); k != nil {
+			return c.full(k, v)
+		}
+	}
+	c.i = -1
+	return nil, nil
+}
+
+func (c *segmentCursor) seek(k []byte) ([]byte, []byte) {
+	for i, p := range c.prefixes {
+		switch {
+		case bytes.HasPrefix(k, p):
+			if c.cursors[i] == nil {
+				return c.first(i + 1)
+			}
+			c.i = i
+			if ck, v := c.cursors[i].Seek(concat(c.marker, k[len(p):]...)); ck != nil {
+				return c.full(ck, v)
+			}
+			return c.first(i + 1)
+		case bytes.Compare(k, p) < 0:
+			return c.first(i)
+		}
+	}
+	c.i = len(c.cursors)
+	return nil, nil
+}
+
+func (c *segmentCursor) next() ([]byte, []byte) {
+	if c.i < 0 {
+		return c.first(0)
+	}
+	if c.i >= len(c.cursors) {
+		return nil, nil
+	}
+	if k, v := c.cursors[c.i].Next(); k != nil {
+		return c.full(k, v)
+	}
+	return c.first(c.i + 1)
+}
+
+func (c *segmentCursor) prev() ([]byte, []byte) {
+	if c.i >= len(c.cursors) {
+		return c.last()
+	}
+	if c.i < 0 {
+		return nil, nil
+	}
+	if k, v := c.cursors[c.i].Prev(); k != nil {
+		return c.full(k, v)
+	}
+	return c.lastFrom(c.i - 1)
+}
+
+func (c *segmentCursor) last() ([]byte, []byte) {
+	return c.lastFrom(len(c.cursors) - 1)
+}
+
+func concat(a []byte, b ...byte) []byte {
+	r := make([]byte, len(a)+len(b))
+	copy(r, a)
+	copy(r[len(a):], b)
+	return r
+}
@@ -0,0 +1,44 @@
+package boltkv
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+	"github.com/abdullin/lex-go/subspace"
+)
+
+func open(t *testing.T) *bbolt.DB {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "bolt.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		return New(open(t), []byte("lex"))
+	})
+}
+
+func TestConformanceBucketed(t *testing.T) {
+	// Every key written by the suite starts with a byte below 0xFF, and is
+	// mapped onto the bucket of its first byte; keys of a single byte are
+	// the keys of their subspace.
+	var spaces []subspace.Subspace
+	for b := 0; b < 0xFF; b++ {
+		spaces = append(spaces, subspace.FromBytes([]byte{byte(b)}))
+	}
+	lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		s, err := NewBucketed(open(t), spaces...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,25 @@
+package boltkv
+
+import (
+	"io"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/abdullin/lex-go"
+)
+
+// The "bolt" adapter opens the Bolt database at the given path, keeping keys
+// in the bucket named by the "bucket" parameter ("data" by default).
+func init() {
+	lex.RegisterAdapter("bolt", func(path string, o lex.OpenOptions) (lex.KVStore, io.Closer, error) {
+		bucket := o.Params["bucket"]
+		if bucket == "" {
+			bucket = "data"
+		}
+		db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: o.ReadOnly})
+		if err != nil {
+			return nil, nil, err
+		}
+		return New(db, []byte(bucket)), db, nil
+	})
+}
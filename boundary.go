@@ -0,0 +1,65 @@
+package lex
+
+import "bytes"
+
+// MinKey is the empty key, which sorts before every other key. A key selector
+// resolving before the first key of a store resolves to MinKey.
+//
+// MinKey and MaxAppKey are shared and must not be modified.
+var MinKey = Key{}
+
+// MaxAppKey is the key made of the single byte 0xFF, which sorts after every
+// application key: keys starting with 0xFF are reserved for the system
+// keyspace of FoundationDB. A key selector resolving past the last key of a
+// store resolves to MaxAppKey, and it ends every range covering the whole
+// application keyspace.
+var MaxAppKey = Key{0xFF}
+
+// AppKeys returns the range of every application key, from MinKey included
+// to MaxAppKey excluded.
+func AppKeys() KeyRange {
+	return KeyRange{MinKey, MaxAppKey}
+}
+
+// Compare returns an integer comparing two keys lexicographically: 0 if
+// a == b, -1 if a < b and +1 if a > b. A nil KeyConvertible compares as
+// MinKey, like the zero-value of KeyRange.
+func Compare(a, b KeyConvertible) int {
+	return bytes.Compare(bytesOf(a), bytesOf(b))
+}
+
+// IsMinKey returns true if the key is empty, such as the result of a key
+// selector resolving before the first key of a store.
+func IsMinKey(k KeyConvertible) bool {
+	return len(bytesOf(k)) == 0
+}
+
+// IsAppKey returns true if the key sorts before MaxAppKey. A key selector
+// resolving past the last key of a store resolves to a key for which IsAppKey
+// returns false.
+func IsAppKey(k KeyConvertible) bool {
+	b := bytesOf(k)
+	return len(b) == 0 || b[0] != 0xFF
+}
+
+func bytesOf(k KeyConvertible) []byte {
+	if k == nil {
+		return nil
+	}
+	return k.LexKey()
+}
+
+// PrefixEnd returns the first key greater than every key starting with
+// prefix, which ends the range of these keys: prefix without its trailing
+// 0xFF bytes, and with its last byte incremented. It returns false if there is
+// no such key, when prefix is empty or consists only of 0xFF bytes.
+func PrefixEnd(prefix []byte) (Key, bool) {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			r := append(Key{}, prefix[:i+1]...)
+			r[i]++
+			return r, true
+		}
+	}
+	return nil, false
+}
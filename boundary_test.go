@@ -0,0 +1,26 @@
+package lex
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrefixEnd(t *testing.T) {
+	for _, c := range []struct {
+		prefix, end []byte
+		ok          bool
+	}{
+		{[]byte{0x01}, []byte{0x02}, true},
+		{[]byte{0x15, 0x80}, []byte{0x15, 0x81}, true},
+		{[]byte{0x01, 0xFE}, []byte{0x01, 0xFF}, true},
+		{[]byte{0x01, 0xFF, 0xFF}, []byte{0x02}, true},
+		{[]byte{0x80, 0xFF}, []byte{0x81}, true},
+		{[]byte{0xFF, 0xFF}, nil, false},
+		{nil, nil, false},
+	} {
+		end, ok := PrefixEnd(c.prefix)
+		if ok != c.ok || !bytes.Equal(end, c.end) {
+			t.Errorf("PrefixEnd(%x) = %x, %v, want %x, %v", c.prefix, end, ok, c.end, c.ok)
+		}
+	}
+}
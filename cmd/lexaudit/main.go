@@ -0,0 +1,25 @@
+// Command lexaudit verifies that packed tuples of every supported element
+// type sort according to the FoundationDB tuple ordering. It prints the audit
+// report as JSON and exits with a non-zero status if any violation is found.
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/abdullin/lex-go/tuple"
+)
+
+func main() {
+	r := tuple.AuditOrdering()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		os.Exit(2)
+	}
+
+	if !r.OK() {
+		os.Exit(1)
+	}
+}
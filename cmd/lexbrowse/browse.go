@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// A node is one level of the subspace tree: the packed prefix shared by all
+// keys below it, and a label for display.
+type node struct {
+	prefix []byte
+	label  string
+	leaf   bool
+}
+
+// rangeOf returns the range of all keys strictly below the prefix.
+func rangeOf(prefix []byte) lex.KeyRange {
+	return lex.KeyRange{
+		Begin: lex.Key(concat(prefix, 0x00)),
+		End:   lex.Key(concat(prefix, 0xFF)),
+	}
+}
+
+// children lists up to limit distinct child nodes of prefix, starting at the
+// first child greater than or equal to from. Each child is found with a
+// single range read: after a child is found, the scan skips past every key
+// below it.
+func children(store lex.KVStore, prefix []byte, from lex.KeySelector, limit int) ([]node, error) {
+	_, end := rangeOf(prefix).LexRangeKeys()
+	r := lex.SelectorRange{Begin: from, End: lex.FirstGreaterOrEqual(end)}
+
+	var nodes []node
+	for len(nodes) < limit {
+		kvs, err := store.GetRange(r, lex.RangeOptions{Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(kvs) == 0 {
+			break
+		}
+		k := kvs[0].Key
+
+		spans, err := tuple.Spans(k[len(prefix):])
+		if err != nil || len(spans) == 0 {
+			// Not a tuple below this prefix: show the raw key as a leaf.
+			nodes = append(nodes, node{k, strconv.Quote(string(k[len(prefix):])), true})
+			r.Begin = lex.FirstGreaterThan(k)
+			continue
+		}
+
+		child := k[:len(prefix)+spans[0].End]
+		el, _ := tuple.UnpackElement(k[len(prefix):], spans[0])
+		nodes = append(nodes, node{child, formatElement(el), len(spans) == 1})
+		r.Begin = lex.FirstGreaterOrEqual(lex.Key(concat(child, 0xFF)))
+	}
+	return nodes, nil
+}
+
+// keys returns up to limit key-value pairs below prefix, starting at from.
+func keys(store lex.KVStore, prefix []byte, from lex.KeySelector, limit int) ([]lex.KeyValue, error) {
+	_, end := rangeOf(prefix).LexRangeKeys()
+	r := lex.SelectorRange{Begin: from, End: lex.FirstGreaterOrEqual(end)}
+	return store.GetRange(r, lex.RangeOptions{Limit: limit})
+}
+
+// search returns the selector of the first child of prefix whose string
+// element starts with s.
+func search(prefix []byte, s string) lex.KeySelector {
+	// A string element is encoded as 0x02 followed by its escaped bytes, so
+	// all strings starting with s share the encoding of s without terminator.
+	enc := tuple.Tuple{s}.Pack()
+	return lex.FirstGreaterOrEqual(lex.Key(concat(prefix, enc[:len(enc)-1]...)))
+}
+
+func formatElement(el tuple.Element) string {
+	switch el := el.(type) {
+	case []byte:
+		return "b" + strconv.Quote(string(el))
+	case string:
+		return strconv.Quote(el)
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprint(el)
+}
+
+func formatValue(v []byte, max int) string {
+	s := strconv.Quote(string(v))
+	if len(s) > max {
+		s = s[:max] + "…"
+	}
+	return s
+}
+
+func concat(a []byte, b ...byte) []byte {
+	r := make([]byte, len(a)+len(b))
+	copy(r, a)
+	copy(r[len(a):], b)
+	return r
+}
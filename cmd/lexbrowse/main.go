@@ -0,0 +1,259 @@
+// Command lexbrowse is a terminal keyspace browser. It opens a database
+// through one of the lex.KVStore adapters and lets operators navigate
+// subspaces as a tree of tuple elements, page through decoded keys and
+// values, and search for children by string prefix.
+//
+// Usage:
+//
+//	lexbrowse -engine bolt -bucket data path/to/db
+//	lexbrowse -engine lmdb -db data path/to/env
+//	lexbrowse -engine redis -name lex redis://localhost:6379/0
+//
+// The database is opened read-only, except with Redis, which has no
+// read-only mode; lexbrowse only ever reads from it.
+//
+// Keys: up/down to move, enter to open a subspace, backspace to go up, tab
+// to switch between the subspace tree and the keys below it, n/p to page,
+// / to search, q to quit.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/kvutil"
+)
+
+const pageSize = 20
+
+type mode int
+
+const (
+	treeMode mode = iota
+	keysMode
+	searchMode
+)
+
+type model struct {
+	store lex.KVStore
+
+	path  []node
+	mode  mode
+	input string
+
+	// pages holds the selectors at which the visible page and every page
+	// before it start, so that p can go back.
+	pages  []lex.KeySelector
+	nodes  []node
+	kvs    []lex.KeyValue
+	cursor int
+	err    error
+}
+
+func (m *model) prefix() []byte {
+	if len(m.path) == 0 {
+		return nil
+	}
+	return m.path[len(m.path)-1].prefix
+}
+
+func (m *model) first() lex.KeySelector {
+	b, _ := rangeOf(m.prefix()).LexRangeKeys()
+	return lex.FirstGreaterOrEqual(b)
+}
+
+// load reads the page starting at the last selector in m.pages.
+func (m *model) load() {
+	from := m.pages[len(m.pages)-1]
+	m.cursor = 0
+	if m.mode == keysMode {
+		m.kvs, m.err = keys(m.store, m.prefix(), from, pageSize)
+		return
+	}
+	m.nodes, m.err = children(m.store, m.prefix(), from, pageSize)
+}
+
+func (m *model) reset(from lex.KeySelector) {
+	m.pages = []lex.KeySelector{from}
+	m.load()
+}
+
+func (m *model) next() {
+	switch {
+	case m.mode == keysMode && len(m.kvs) == pageSize:
+		m.pages = append(m.pages, lex.FirstGreaterThan(m.kvs[len(m.kvs)-1].Key))
+	case m.mode == treeMode && len(m.nodes) == pageSize:
+		last := m.nodes[len(m.nodes)-1].prefix
+		m.pages = append(m.pages, lex.FirstGreaterOrEqual(lex.Key(concat(last, 0xFF))))
+	default:
+		return
+	}
+	m.load()
+}
+
+func (m *model) prev() {
+	if len(m.pages) > 1 {
+		m.pages = m.pages[:len(m.pages)-1]
+		m.load()
+	}
+}
+
+func (m *model) items() int {
+	if m.mode == keysMode {
+		return len(m.kvs)
+	}
+	return len(m.nodes)
+}
+
+func (m model) Init() tea.Cmd {
+	return nil
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if m.mode == searchMode {
+		switch key.Type {
+		case tea.KeyEnter:
+			m.mode = treeMode
+			m.reset(search(m.prefix(), m.input))
+		case tea.KeyEsc:
+			m.mode = treeMode
+		case tea.KeyBackspace:
+			if len(m.input) > 0 {
+				m.input = m.input[:len(m.input)-1]
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.input += string(key.Runes)
+		}
+		return m, nil
+	}
+
+	switch key.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < m.items()-1 {
+			m.cursor++
+		}
+	case "n", "pgdown":
+		m.next()
+	case "p", "pgup":
+		m.prev()
+	case "tab":
+		if m.mode == keysMode {
+			m.mode = treeMode
+		} else {
+			m.mode = keysMode
+		}
+		m.reset(m.first())
+	case "enter", "right", "l":
+		if m.mode == treeMode && m.cursor < len(m.nodes) && !m.nodes[m.cursor].leaf {
+			m.path = append(m.path, m.nodes[m.cursor])
+			m.reset(m.first())
+		}
+	case "backspace", "left", "h":
+		if len(m.path) > 0 {
+			m.path = m.path[:len(m.path)-1]
+			m.reset(m.first())
+		}
+	case "/":
+		if m.mode == treeMode {
+			m.mode = searchMode
+			m.input = ""
+		}
+	}
+	return m, nil
+}
+
+func (m model) View() string {
+	var sb strings.Builder
+
+	labels := []string{"/"}
+	for _, n := range m.path {
+		labels = append(labels, n.label)
+	}
+	fmt.Fprintf(&sb, "%s  (page %d)\n\n", strings.Join(labels, " "), len(m.pages))
+
+	if m.err != nil {
+		fmt.Fprintf(&sb, "error: %v\n", m.err)
+	}
+
+	switch m.mode {
+	case keysMode:
+		for i, kv := range m.kvs {
+			fmt.Fprintf(&sb, "%s %s = %s\n", marker(i == m.cursor), kvutil.FormatKey(kv.Key), formatValue(kv.Value, 60))
+		}
+		if len(m.kvs) == 0 {
+			sb.WriteString("  (no keys)\n")
+		}
+	default:
+		for i, n := range m.nodes {
+			suffix := "/"
+			if n.leaf {
+				suffix = ""
+			}
+			fmt.Fprintf(&sb, "%s %s%s\n", marker(i == m.cursor), n.label, suffix)
+		}
+		if len(m.nodes) == 0 {
+			sb.WriteString("  (empty)\n")
+		}
+	}
+
+	if m.mode == searchMode {
+		fmt.Fprintf(&sb, "\nsearch: %s_\n", m.input)
+	} else {
+		sb.WriteString("\nenter open · backspace up · tab keys/tree · n/p page · / search · q quit\n")
+	}
+	return sb.String()
+}
+
+func marker(selected bool) string {
+	if selected {
+		return ">"
+	}
+	return " "
+}
+
+func main() {
+	engine := flag.String("engine", "bolt", "storage engine: "+strings.Join(lex.Adapters(), ", "))
+	bucket := flag.String("bucket", "data", "bucket holding the keys (bolt only)")
+	db := flag.String("db", "", "named database holding the keys, rather than the unnamed one (lmdb only)")
+	name := flag.String("name", "lex", "sorted set holding the keys (redis only)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: lexbrowse [-engine name] [-bucket name] [-db name] [-name name] path")
+		os.Exit(2)
+	}
+
+	store, c, err := lex.Open(*engine, flag.Arg(0), lex.OpenOptions{
+		ReadOnly: true,
+		Params:   map[string]string{"bucket": *bucket, "db": *db, "name": *name},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer c.Close()
+
+	m := model{store: store}
+	m.reset(m.first())
+
+	if _, err := tea.NewProgram(m).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	// Importing the adapters registers them with lex.Open.
+	_ "github.com/abdullin/lex-go/badgerkv"
+	_ "github.com/abdullin/lex-go/boltkv"
+	_ "github.com/abdullin/lex-go/leveldbkv"
+	_ "github.com/abdullin/lex-go/lmdbkv"
+	_ "github.com/abdullin/lex-go/pebblekv"
+	_ "github.com/abdullin/lex-go/rediskv"
+)
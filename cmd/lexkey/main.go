@@ -0,0 +1,194 @@
+// Command lexkey encodes and decodes keys, for debugging key layouts.
+//
+// Usage:
+//
+//	lexkey decode [-hex] KEY...
+//	lexkey pack [-hex] TUPLE
+//	lexkey range [-hex] KEY
+//	lexkey strip [-hex] PREFIX KEY...
+//
+// decode prints the tuples encoded by keys, pack prints the key encoding a
+// tuple, range prints the range of keys starting with a key (from the key to
+// its strinc) and the range of tuples extending it, and strip decodes keys
+// after removing a subspace prefix.
+//
+// Keys are written in the printable form of lex.ParseKey, such as
+// \x02app\x00\x15\x05, or in hexadecimal with -hex. A key may also be given
+// as a tuple literal, such as ("app", b"\x01", 5, nil), in which case it is
+// the encoding of the tuple; a printable key starting with ( must be written
+// with \x28. Prefixes are tuple literals or quoted strings, as accepted by
+// subspace.Parse. Keys are printed in the same form as they are read.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+const usage = `usage:
+  lexkey decode [-hex] KEY...
+  lexkey pack [-hex] TUPLE
+  lexkey range [-hex] KEY
+  lexkey strip [-hex] PREFIX KEY...
+`
+
+var commands = map[string]struct {
+	args int // minimum number of arguments
+	run  func(w io.Writer, f format, args []string) error
+}{
+	"decode": {1, decode},
+	"pack":   {1, pack},
+	"range":  {1, keyRange},
+	"strip":  {2, strip},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "lexkey: unknown command %q\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("lexkey "+os.Args[1], flag.ExitOnError)
+	useHex := fs.Bool("hex", false, "read and print keys in hexadecimal")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	fs.Parse(os.Args[2:])
+	if fs.NArg() < cmd.args {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Stdout, format{*useHex}, fs.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "lexkey: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// format reads and prints keys.
+type format struct {
+	hex bool
+}
+
+func (f format) parse(s string) (lex.Key, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "(") {
+		ss, err := subspace.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return lex.Key(ss.Bytes()), nil
+	}
+	if f.hex {
+		b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %v", s, err)
+		}
+		return lex.Key(b), nil
+	}
+	return lex.ParseKey(s)
+}
+
+func (f format) key(k lex.Key) string {
+	if f.hex {
+		return hex.EncodeToString(k)
+	}
+	b, _ := k.MarshalText()
+	return string(b)
+}
+
+func decode(w io.Writer, f format, args []string) error {
+	for _, s := range args {
+		k, err := f.parse(s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, describe(f, k))
+	}
+	return nil
+}
+
+// describe returns the tuple encoded by k, or the printed key if it does not
+// encode a tuple.
+func describe(f format, k []byte) string {
+	t, err := tuple.UnpackLenient(k)
+	if err != nil {
+		return fmt.Sprintf("%s (not a tuple: %v)", f.key(k), err)
+	}
+	return formatTuple(t)
+}
+
+func pack(w io.Writer, f format, args []string) error {
+	k, err := f.parse(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, f.key(k))
+	return nil
+}
+
+func keyRange(w io.Writer, f format, args []string) error {
+	k, err := f.parse(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if end, ok := lex.PrefixEnd(k); ok {
+		fmt.Fprintf(w, "prefix  %s\t%s\n", f.key(k), f.key(end))
+	} else {
+		fmt.Fprintf(w, "prefix  %s\t(no end: the key consists only of 0xFF bytes)\n", f.key(k))
+	}
+	begin := append(append(lex.Key{}, k...), 0x00)
+	end := append(append(lex.Key{}, k...), 0xFF)
+	fmt.Fprintf(w, "tuples  %s\t%s\n", f.key(begin), f.key(end))
+	return nil
+}
+
+func strip(w io.Writer, f format, args []string) error {
+	s, err := subspace.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	for _, a := range args[1:] {
+		k, err := f.parse(a)
+		if err != nil {
+			return err
+		}
+		rest, err := s.Strip(k)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.key(k), err)
+		}
+		fmt.Fprintln(w, describe(f, rest))
+	}
+	return nil
+}
+
+// formatTuple formats a tuple as a tuple literal, such as ("app", b"\x01", 5).
+func formatTuple(t tuple.Tuple) string {
+	parts := make([]string, len(t))
+	for i, el := range t {
+		switch el := el.(type) {
+		case nil:
+			parts[i] = "nil"
+		case []byte:
+			parts[i] = "b" + strconv.Quote(string(el))
+		case string:
+			parts[i] = strconv.Quote(el)
+		case tuple.OpaqueElement:
+			parts[i] = fmt.Sprintf("<%x>", []byte(el))
+		default:
+			parts[i] = fmt.Sprint(el)
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
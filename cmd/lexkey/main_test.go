@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyRange(t *testing.T) {
+	for _, c := range []struct {
+		f    format
+		key  string
+		want string
+	}{
+		{format{}, `\x15\x80`, "prefix  \\x15\\x80\t\\x15\\x81\ntuples  \\x15\\x80\\x00\t\\x15\\x80\\xff\n"},
+		{format{true}, "15fe", "prefix  15fe\t15ff\ntuples  15fe00\t15feff\n"},
+		{format{true}, "15feff", "prefix  15feff\t15ff\ntuples  15feff00\t15feffff\n"},
+		{format{true}, "ffff", "prefix  ffff\t(no end: the key consists only of 0xFF bytes)\ntuples  ffff00\tffffff\n"},
+	} {
+		var w bytes.Buffer
+		if err := keyRange(&w, c.f, []string{c.key}); err != nil {
+			t.Fatalf("range %s: %v", c.key, err)
+		}
+		if w.String() != c.want {
+			t.Errorf("range %s:\n%q\nwant\n%q", c.key, w.String(), c.want)
+		}
+	}
+}
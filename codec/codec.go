@@ -0,0 +1,103 @@
+// Package codec is a registry of value codecs, which encode the values
+// stored under lex keys. Optional packages provide codecs by registering them
+// from an init function, so that importing them (even with a blank import)
+// makes the codecs available by name, for example to tools reading stores
+// with a configured value format.
+//
+// The "json" and "raw" codecs are always registered.
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Codec encodes and decodes values.
+type Codec interface {
+	// Marshal returns the encoding of v.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes b into the value pointed to by v.
+	Unmarshal(b []byte, v interface{}) error
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = map[string]Codec{}
+)
+
+// Register makes a codec available under the given name. Register panics if
+// a codec is already registered under the name.
+func Register(name string, c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := codecs[name]; ok {
+		panic(fmt.Sprintf("codec %q is already registered", name))
+	}
+	codecs[name] = c
+}
+
+// Lookup returns the codec registered under the given name.
+func Lookup(name string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// Names returns the sorted names of the registered codecs.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("json", jsonCodec{})
+	Register("raw", rawCodec{})
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+// rawCodec stores []byte and string values as they are.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("codec raw: cannot marshal %T", v)
+}
+
+func (rawCodec) Unmarshal(b []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *[]byte:
+		*v = append((*v)[:0], b...)
+		return nil
+	case *string:
+		*v = string(b)
+		return nil
+	}
+	return fmt.Errorf("codec raw: cannot unmarshal into %T", v)
+}
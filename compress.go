@@ -0,0 +1,80 @@
+package lex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// errCompressedTruncated is returned by Decompress for truncated input.
+var errCompressedTruncated = errors.New("compressed keys are truncated")
+
+// CompressSorted returns an encoding of the keys which stores every key as
+// the length of the prefix it shares with the previous key, followed by the
+// rest of the key (front coding). Sorted keys with long common prefixes, such
+// as the keys of a subspace, shrink to a fraction of their size, for keeping
+// large sets of keys in memory or sending them between services. Keys which
+// are not sorted are encoded too, but compress less.
+func CompressSorted(keys [][]byte) []byte {
+	var n [binary.MaxVarintLen64]byte
+	b := append([]byte{}, n[:binary.PutUvarint(n[:], uint64(len(keys)))]...)
+
+	var prev []byte
+	for _, k := range keys {
+		shared := 0
+		for shared < len(prev) && shared < len(k) && prev[shared] == k[shared] {
+			shared++
+		}
+		b = append(b, n[:binary.PutUvarint(n[:], uint64(shared))]...)
+		b = append(b, n[:binary.PutUvarint(n[:], uint64(len(k)-shared))]...)
+		b = append(b, k[shared:]...)
+		prev = k
+	}
+	return b
+}
+
+// Decompress returns the keys encoded by CompressSorted, or an error if b is
+// not a valid encoding. The keys do not alias b.
+func Decompress(b []byte) ([][]byte, error) {
+	count, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, errCompressedTruncated
+	}
+	b = b[n:]
+	// Every key takes at least two bytes, which bounds the count of valid
+	// input before allocating for it.
+	if count > uint64(len(b)/2) {
+		return nil, errCompressedTruncated
+	}
+
+	keys := make([][]byte, 0, count)
+	var prev []byte
+	for i := uint64(0); i < count; i++ {
+		shared, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errCompressedTruncated
+		}
+		b = b[n:]
+		rest, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errCompressedTruncated
+		}
+		b = b[n:]
+		if shared > uint64(len(prev)) {
+			return nil, fmt.Errorf("key %d shares %d bytes with a key of %d bytes", i, shared, len(prev))
+		}
+		if rest > uint64(len(b)) {
+			return nil, errCompressedTruncated
+		}
+		k := make([]byte, int(shared)+int(rest))
+		copy(k, prev[:shared])
+		copy(k[shared:], b[:rest])
+		b = b[rest:]
+		keys = append(keys, k)
+		prev = k
+	}
+	if len(b) != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after compressed keys", len(b))
+	}
+	return keys, nil
+}
@@ -0,0 +1,591 @@
+// Package directory provides a port of the FoundationDB directory layer,
+// which manages named, hierarchical keyspaces (directories) on top of any
+// lex.KVStore, so that applications do not need to hardcode tuple prefixes.
+//
+// Each directory is assigned a short prefix by the high-contention allocator
+// (see package hca), and the mapping from paths to prefixes is kept in a
+// separate node subspace, using the same layout as the other FoundationDB
+// bindings. Moving a directory is therefore cheap, as only its entry in the
+// node subspace changes.
+//
+// A directory created with the layer "partition" is a partition: it gets a
+// directory layer of its own, whose metadata and directories are all stored
+// within the prefix of the partition. Directories cannot be moved in or out
+// of a partition, but the partition itself, with everything in it, can be
+// moved or removed as a whole.
+//
+// The directory layer performs several reads and writes per operation.
+// KVStore has no transactions, so a store shared by concurrent writers must
+// be wrapped in a transaction (for example with badgerkv.Txn) for the
+// operations to be atomic.
+package directory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/hca"
+	"github.com/abdullin/lex-go/kvutil"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+const (
+	subdirs int64 = 0
+
+	majorVersion uint32 = 1
+	minorVersion uint32 = 0
+	microVersion uint32 = 0
+)
+
+var (
+	// ErrDirAlreadyExists is returned when trying to create a directory
+	// which already exists.
+	ErrDirAlreadyExists = errors.New("the directory already exists")
+
+	// ErrDirNotExists is returned when opening or listing a directory which
+	// does not exist.
+	ErrDirNotExists = errors.New("the directory does not exist")
+
+	// ErrParentDirDoesNotExist is returned when moving a directory to a path
+	// whose parent does not exist.
+	ErrParentDirDoesNotExist = errors.New("the parent directory does not exist")
+)
+
+// partitionLayer is the layer of directory partitions.
+var partitionLayer = []byte("partition")
+
+// DirectorySubspace is a Subspace whose prefix was allocated by a directory
+// layer for a particular path.
+type DirectorySubspace interface {
+	subspace.Subspace
+
+	// GetPath returns the path with which the directory was opened.
+	GetPath() []string
+
+	// GetLayer returns the layer specified when the directory was created.
+	GetLayer() []byte
+}
+
+type directorySubspace struct {
+	subspace.Subspace
+	path  []string
+	layer []byte
+}
+
+func (d directorySubspace) GetPath() []string {
+	return d.path
+}
+
+func (d directorySubspace) GetLayer() []byte {
+	return d.layer
+}
+
+// directoryPartition is the DirectorySubspace of a partition: a directory
+// whose whole subtree is allocated within its own prefix, so that it can be
+// moved and managed independently. The keys of a partition belong to its
+// subdirectories, and its own prefix holds the metadata of its subtree, so,
+// as in the other FoundationDB bindings, it cannot be used as a key, a range
+// or a subspace itself: every such method panics.
+type directoryPartition struct {
+	directorySubspace
+}
+
+func (p directoryPartition) Sub(el ...tuple.Element) subspace.Subspace {
+	panic("cannot open subspace in the root of a directory partition")
+}
+
+func (p directoryPartition) Pack(t tuple.Tuple) lex.Key {
+	panic("cannot pack keys using the root of a directory partition")
+}
+
+func (p directoryPartition) Unpack(k lex.KeyConvertible) (tuple.Tuple, error) {
+	panic("cannot unpack keys using the root of a directory partition")
+}
+
+func (p directoryPartition) UnpackInto(k lex.KeyConvertible, t *tuple.Tuple) error {
+	panic("cannot unpack keys using the root of a directory partition")
+}
+
+func (p directoryPartition) Strip(k lex.KeyConvertible) ([]byte, error) {
+	panic("cannot strip keys using the root of a directory partition")
+}
+
+func (p directoryPartition) Bytes() []byte {
+	panic("cannot get key for the root of a directory partition")
+}
+
+func (p directoryPartition) LexKey() lex.Key {
+	panic("cannot use the key of the root of a directory partition")
+}
+
+func (p directoryPartition) Contains(k lex.KeyConvertible) bool {
+	panic("cannot check whether a key belongs to the root of a directory partition")
+}
+
+func (p directoryPartition) LexRangeKeys() (lex.KeyConvertible, lex.KeyConvertible) {
+	panic("cannot get range for the root of a directory partition")
+}
+
+func (p directoryPartition) LexRangeKeySelectors() (lex.Selectable, lex.Selectable) {
+	panic("cannot get range for the root of a directory partition")
+}
+
+// Layer manages directories. The node subspace holds the directory metadata
+// and the content subspace holds the prefixes allocated to directories.
+type Layer struct {
+	path      []string
+	nodeSS    subspace.Subspace
+	contentSS subspace.Subspace
+	rootNode  subspace.Subspace
+	allocator *hca.Allocator
+}
+
+// NewLayer returns a directory Layer storing its metadata in nodeSS and
+// allocating directory prefixes within contentSS.
+func NewLayer(nodeSS, contentSS subspace.Subspace) *Layer {
+	rootNode := nodeSS.Sub(nodeSS.Bytes())
+	return &Layer{
+		nodeSS:    nodeSS,
+		contentSS: contentSS,
+		rootNode:  rootNode,
+		allocator: hca.New(rootNode.Sub([]byte("hca"))),
+	}
+}
+
+// Root returns the default directory Layer, which stores its metadata under
+// the 0xFE prefix and allocates prefixes from the whole keyspace, as in the
+// other FoundationDB bindings.
+func Root() *Layer {
+	return NewLayer(subspace.FromBytes([]byte{0xFE}), subspace.AllKeys())
+}
+
+// node is the metadata subspace of a directory, along with its path within
+// the Layer and its layer.
+type node struct {
+	subspace.Subspace
+	prefix []byte
+	path   []string
+	layer  []byte
+}
+
+// inPartition returns true if path continues below the partition n, or also
+// if path leads to the partition itself and includeEmpty is set.
+func (n *node) inPartition(path []string, includeEmpty bool) bool {
+	if n == nil || !bytes.Equal(n.layer, partitionLayer) {
+		return false
+	}
+	return len(n.path) < len(path) || (includeEmpty && len(n.path) == len(path))
+}
+
+// partition returns the Layer managing the directories within the partition
+// n: its metadata is stored under the partition prefix followed by 0xFE, and
+// its prefixes are allocated within the partition prefix.
+func (l *Layer) partition(n *node) *Layer {
+	p := NewLayer(subspace.FromBytes(append(append([]byte{}, n.prefix...), 0xFE)), subspace.FromBytes(n.prefix))
+	p.path = append(append([]string{}, l.path...), n.path...)
+	return p
+}
+
+func (l *Layer) nodeWithPrefix(prefix []byte) subspace.Subspace {
+	return l.nodeSS.Sub(prefix)
+}
+
+func (l *Layer) prefixOfNode(n subspace.Subspace) ([]byte, error) {
+	t, err := l.nodeSS.Unpack(n)
+	if err != nil {
+		return nil, err
+	}
+	return t[0].([]byte), nil
+}
+
+// find walks the path from the root, returning the node of the directory or
+// nil if it does not exist. If the path goes through a partition, find stops
+// there and returns the node of the partition.
+func (l *Layer) find(store lex.KVStore, path []string) (*node, error) {
+	n := &node{Subspace: l.rootNode}
+	for i, name := range path {
+		prefix, err := lex.Get(store, n.Sub(subdirs, name))
+		if err != nil {
+			return nil, err
+		}
+		if prefix == nil {
+			return nil, nil
+		}
+		n = &node{l.nodeWithPrefix(prefix), prefix, path[:i+1], nil}
+		if n.layer, err = l.layerOf(store, n); err != nil {
+			return nil, err
+		}
+		if n.inPartition(path, false) {
+			break
+		}
+	}
+	return n, nil
+}
+
+func (l *Layer) layerOf(store lex.KVStore, n *node) ([]byte, error) {
+	v, err := lex.Get(store, n.Sub([]byte("layer")))
+	if v == nil && err == nil {
+		v = []byte{}
+	}
+	return v, err
+}
+
+func (l *Layer) contentsOf(n *node, path []string, layer []byte) DirectorySubspace {
+	d := directorySubspace{subspace.FromBytes(n.prefix), append(append([]string{}, l.path...), path...), layer}
+	if bytes.Equal(layer, partitionLayer) {
+		return directoryPartition{d}
+	}
+	return d
+}
+
+func (l *Layer) checkVersion(store lex.KVStore, write bool) error {
+	key := l.rootNode.Sub([]byte("version"))
+	v, err := lex.Get(store, key)
+	if err != nil {
+		return err
+	}
+
+	if v == nil {
+		if !write {
+			return nil
+		}
+		b := make([]byte, 12)
+		binary.LittleEndian.PutUint32(b, majorVersion)
+		binary.LittleEndian.PutUint32(b[4:], minorVersion)
+		binary.LittleEndian.PutUint32(b[8:], microVersion)
+		return store.Set(key, b)
+	}
+
+	if len(v) != 12 {
+		return errors.New("malformed directory layer version")
+	}
+	major := binary.LittleEndian.Uint32(v)
+	minor := binary.LittleEndian.Uint32(v[4:])
+	if major > majorVersion || (write && minor > minorVersion) {
+		return fmt.Errorf("cannot use directory layer version %d.%d with version %d.%d", major, minor, majorVersion, minorVersion)
+	}
+	return nil
+}
+
+// CreateOrOpen opens the directory at path, creating it (and any missing
+// parents) if it does not exist. If layer is specified, it is checked
+// against the layer of an existing directory or recorded for a new one.
+func (l *Layer) CreateOrOpen(store lex.KVStore, path []string, layer []byte) (DirectorySubspace, error) {
+	return l.createOrOpen(store, path, layer, true, true)
+}
+
+// Open opens the existing directory at path. If layer is specified, it must
+// match the layer the directory was created with.
+func (l *Layer) Open(store lex.KVStore, path []string, layer []byte) (DirectorySubspace, error) {
+	return l.createOrOpen(store, path, layer, false, true)
+}
+
+// Create creates a directory at path, along with any missing parents. It
+// returns ErrDirAlreadyExists if the directory exists.
+func (l *Layer) Create(store lex.KVStore, path []string, layer []byte) (DirectorySubspace, error) {
+	return l.createOrOpen(store, path, layer, true, false)
+}
+
+func (l *Layer) createOrOpen(store lex.KVStore, path []string, layer []byte, allowCreate, allowOpen bool) (DirectorySubspace, error) {
+	if len(path) == 0 {
+		return nil, errors.New("the root directory cannot be opened")
+	}
+	if err := l.checkVersion(store, false); err != nil {
+		return nil, err
+	}
+
+	existing, err := l.find(store, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.inPartition(path, false) {
+		return l.partition(existing).createOrOpen(store, path[len(existing.path):], layer, allowCreate, allowOpen)
+	}
+
+	if existing != nil {
+		if !allowOpen {
+			return nil, ErrDirAlreadyExists
+		}
+		if len(layer) > 0 && !bytes.Equal(layer, existing.layer) {
+			return nil, errors.New("the directory was created with an incompatible layer")
+		}
+		return l.contentsOf(existing, path, existing.layer), nil
+	}
+
+	if !allowCreate {
+		return nil, ErrDirNotExists
+	}
+	if err := l.checkVersion(store, true); err != nil {
+		return nil, err
+	}
+
+	id, err := l.allocator.Allocate(store)
+	if err != nil {
+		return nil, err
+	}
+	prefix := l.contentSS.Pack(tuple.Tuple{id})
+
+	free, err := l.isPrefixFree(store, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if !free {
+		return nil, errors.New("the directory layer allocated a prefix which is already in use")
+	}
+
+	parent := &node{Subspace: l.rootNode}
+	if len(path) > 1 {
+		p, err := l.createOrOpen(store, path[:len(path)-1], nil, true, true)
+		if err != nil {
+			return nil, err
+		}
+		parent = &node{Subspace: l.nodeWithPrefix(p.Bytes()), prefix: p.Bytes()}
+	}
+
+	n := &node{Subspace: l.nodeWithPrefix(prefix), prefix: prefix}
+	if err := store.Set(parent.Sub(subdirs, path[len(path)-1]), prefix); err != nil {
+		return nil, err
+	}
+	if layer == nil {
+		layer = []byte{}
+	}
+	if err := store.Set(n.Sub([]byte("layer")), layer); err != nil {
+		return nil, err
+	}
+
+	return l.contentsOf(n, path, layer), nil
+}
+
+// nodeContainingKey returns the node whose prefix is a prefix of key, if any.
+func (l *Layer) nodeContainingKey(store lex.KVStore, key []byte) (subspace.Subspace, error) {
+	if bytes.HasPrefix(key, l.nodeSS.Bytes()) {
+		return l.rootNode, nil
+	}
+
+	begin, _ := l.nodeSS.LexRangeKeys()
+	end := append(l.nodeSS.Pack(tuple.Tuple{key}), 0x00)
+	kvs, err := store.GetRange(lex.KeyRange{Begin: begin, End: lex.Key(end)}, lex.RangeOptions{Limit: 1, Reverse: true})
+	if err != nil || len(kvs) == 0 {
+		return nil, err
+	}
+
+	t, err := l.nodeSS.Unpack(kvs[0].Key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := t[0].([]byte)
+	if bytes.HasPrefix(key, prefix) {
+		return l.nodeWithPrefix(prefix), nil
+	}
+	return nil, nil
+}
+
+// isPrefixFree returns true if no directory uses a prefix overlapping
+// prefix, and no keys are stored under it.
+func (l *Layer) isPrefixFree(store lex.KVStore, prefix []byte) (bool, error) {
+	if len(prefix) == 0 {
+		return false, nil
+	}
+
+	n, err := l.nodeContainingKey(store, prefix)
+	if err != nil || n != nil {
+		return false, err
+	}
+
+	end := append(append([]byte{}, prefix...), 0xFF)
+	kvs, err := store.GetRange(lex.KeyRange{Begin: lex.Key(prefix), End: lex.Key(end)}, lex.RangeOptions{Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	if len(kvs) > 0 {
+		return false, nil
+	}
+
+	kvs, err = store.GetRange(lex.KeyRange{
+		Begin: l.nodeSS.Pack(tuple.Tuple{prefix}),
+		End:   l.nodeSS.Pack(tuple.Tuple{end}),
+	}, lex.RangeOptions{Limit: 1})
+	return len(kvs) == 0, err
+}
+
+// Exists returns true if the directory at path exists.
+func (l *Layer) Exists(store lex.KVStore, path []string) (bool, error) {
+	if err := l.checkVersion(store, false); err != nil {
+		return false, err
+	}
+	n, err := l.find(store, path)
+	if err != nil {
+		return false, err
+	}
+	if n.inPartition(path, false) {
+		return l.partition(n).Exists(store, path[len(n.path):])
+	}
+	return n != nil, nil
+}
+
+// List returns the names of the immediate subdirectories of the directory at
+// path (the root directory if path is empty).
+func (l *Layer) List(store lex.KVStore, path []string) ([]string, error) {
+	if err := l.checkVersion(store, false); err != nil {
+		return nil, err
+	}
+	n, err := l.find(store, path)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, ErrDirNotExists
+	}
+	if n.inPartition(path, true) {
+		return l.partition(n).List(store, path[len(n.path):])
+	}
+	return l.subdirNames(store, n.Subspace)
+}
+
+func (l *Layer) subdirNames(store lex.KVStore, n subspace.Subspace) ([]string, error) {
+	sd := n.Sub(subdirs)
+	kvs, err := store.GetRange(sd, lex.RangeOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		t, err := sd.Unpack(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, t[0].(string))
+	}
+	return names, nil
+}
+
+// Move moves the directory at oldPath to newPath. The parent of newPath must
+// exist, and newPath must not. The contents of the directory are not moved;
+// only its entry in the node subspace changes.
+func (l *Layer) Move(store lex.KVStore, oldPath, newPath []string) (DirectorySubspace, error) {
+	if err := l.checkVersion(store, true); err != nil {
+		return nil, err
+	}
+	if len(oldPath) == 0 || len(newPath) == 0 {
+		return nil, errors.New("the root directory cannot be moved")
+	}
+	if len(newPath) >= len(oldPath) && equalPaths(oldPath, newPath[:len(oldPath)]) {
+		return nil, errors.New("the destination directory cannot be a subdirectory of the source directory")
+	}
+
+	old, err := l.find(store, oldPath)
+	if err != nil {
+		return nil, err
+	}
+	if old == nil {
+		return nil, ErrDirNotExists
+	}
+
+	existing, err := l.find(store, newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if old.inPartition(oldPath, false) || existing.inPartition(newPath, false) {
+		if !old.inPartition(oldPath, false) || !existing.inPartition(newPath, false) || !equalPaths(old.path, existing.path) {
+			return nil, errors.New("cannot move between partitions")
+		}
+		return l.partition(old).Move(store, oldPath[len(old.path):], newPath[len(existing.path):])
+	}
+
+	if existing != nil {
+		return nil, ErrDirAlreadyExists
+	}
+
+	parent, err := l.find(store, newPath[:len(newPath)-1])
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, ErrParentDirDoesNotExist
+	}
+
+	if err := store.Set(parent.Sub(subdirs, newPath[len(newPath)-1]), old.prefix); err != nil {
+		return nil, err
+	}
+	if err := l.removeFromParent(store, oldPath); err != nil {
+		return nil, err
+	}
+
+	return l.contentsOf(old, newPath, old.layer), nil
+}
+
+func (l *Layer) removeFromParent(store lex.KVStore, path []string) error {
+	parent, err := l.find(store, path[:len(path)-1])
+	if err != nil || parent == nil {
+		return err
+	}
+	return store.Clear(parent.Sub(subdirs, path[len(path)-1]))
+}
+
+// Remove removes the directory at path, along with all of its
+// subdirectories and all of their contents. It returns false if the
+// directory did not exist.
+func (l *Layer) Remove(store lex.KVStore, path []string) (bool, error) {
+	if err := l.checkVersion(store, true); err != nil {
+		return false, err
+	}
+	if len(path) == 0 {
+		return false, errors.New("the root directory cannot be removed")
+	}
+
+	n, err := l.find(store, path)
+	if err != nil || n == nil {
+		return false, err
+	}
+	if n.inPartition(path, false) {
+		return l.partition(n).Remove(store, path[len(n.path):])
+	}
+
+	if err := l.removeRecursive(store, n.Subspace); err != nil {
+		return false, err
+	}
+	return true, l.removeFromParent(store, path)
+}
+
+func (l *Layer) removeRecursive(store lex.KVStore, n subspace.Subspace) error {
+	sd := n.Sub(subdirs)
+	kvs, err := store.GetRange(sd, lex.RangeOptions{})
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		if err := l.removeRecursive(store, l.nodeWithPrefix(kv.Value)); err != nil {
+			return err
+		}
+	}
+
+	prefix, err := l.prefixOfNode(n)
+	if err != nil {
+		return err
+	}
+	quiet := kvutil.Options{Samples: -1}
+	if _, err := kvutil.ClearSubspace(store, subspace.FromBytes(prefix), quiet); err != nil {
+		return err
+	}
+	_, err = kvutil.ClearSubspace(store, n, quiet)
+	return err
+}
+
+func equalPaths(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
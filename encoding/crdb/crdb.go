@@ -0,0 +1,471 @@
+// Package crdb implements the order-preserving key encoding of CockroachDB
+// (its util/encoding package), so that keys of CockroachDB tables can be
+// inspected and generated alongside tuple keys.
+//
+// The functions follow the conventions of CockroachDB: Encode functions
+// append to a buffer and return it, and Decode functions return the remaining
+// bytes, the decoded value and an error. Every value has an ascending and a
+// descending encoding; the descending one sorts in reverse order of the
+// values.
+package crdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	encodedNull    = 0x00
+	encodedNotNull = 0x01
+
+	floatNaN     = encodedNotNull + 1
+	floatNeg     = floatNaN + 1
+	floatZero    = floatNeg + 1
+	floatPos     = floatZero + 1
+	floatNaNDesc = floatPos + 1
+
+	bytesMarker     = 0x12
+	bytesDescMarker = bytesMarker + 1
+
+	// IntMin and IntMax bound the first byte of encoded integers.
+	IntMin      = 0x80
+	IntMax      = 0xfd
+	intMaxWidth = 8
+	intZero     = IntMin + intMaxWidth
+	intSmall    = IntMax - intZero - intMaxWidth
+
+	encodedNotNullDesc = 0xfe
+	encodedNullDesc    = 0xff
+
+	escape      = 0x00
+	escapedTerm = 0x01
+	escaped00   = 0xff
+)
+
+var errShort = errors.New("insufficient bytes to decode value")
+
+// Type is the type of an encoded value, as determined from its first byte.
+type Type int
+
+// The types of encoded values. The descending encoding of integers cannot be
+// told apart from the ascending one, and is reported as Int.
+const (
+	Unknown Type = iota
+	Null
+	NotNull
+	Int
+	Float
+	Bytes
+	BytesDesc
+)
+
+// PeekType returns the type of the value encoded at the start of b.
+func PeekType(b []byte) Type {
+	if len(b) == 0 {
+		return Unknown
+	}
+	switch m := b[0]; {
+	case m == encodedNull || m == encodedNullDesc:
+		return Null
+	case m == encodedNotNull || m == encodedNotNullDesc:
+		return NotNull
+	case m == bytesMarker:
+		return Bytes
+	case m == bytesDescMarker:
+		return BytesDesc
+	case m >= IntMin && m <= IntMax:
+		return Int
+	case m >= floatNaN && m <= floatNaNDesc:
+		return Float
+	}
+	return Unknown
+}
+
+// EncodeNullAscending appends the marker of a NULL value, which sorts before
+// every other ascending value.
+func EncodeNullAscending(b []byte) []byte {
+	return append(b, encodedNull)
+}
+
+// EncodeNullDescending appends the marker of a NULL value, which sorts after
+// every other descending value.
+func EncodeNullDescending(b []byte) []byte {
+	return append(b, encodedNullDesc)
+}
+
+// EncodeNotNullAscending appends the marker of a value which is not NULL,
+// sorting after NULL values.
+func EncodeNotNullAscending(b []byte) []byte {
+	return append(b, encodedNotNull)
+}
+
+// EncodeNotNullDescending appends the marker of a value which is not NULL,
+// sorting before descending NULL values.
+func EncodeNotNullDescending(b []byte) []byte {
+	return append(b, encodedNotNullDesc)
+}
+
+// DecodeIfNull returns true and the bytes following the value if b starts
+// with a NULL marker, ascending or descending.
+func DecodeIfNull(b []byte) ([]byte, bool) {
+	if PeekType(b) == Null {
+		return b[1:], true
+	}
+	return b, false
+}
+
+// appendBigEndian appends the n low bytes of v.
+func appendBigEndian(b []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+// width returns the number of bytes needed to hold v.
+func width(v uint64) int {
+	n := 1
+	for v > 0xff {
+		v >>= 8
+		n++
+	}
+	return n
+}
+
+// EncodeUvarintAscending appends the encoding of v, which takes a single byte
+// for values up to 109 and one byte more than the width of v otherwise.
+func EncodeUvarintAscending(b []byte, v uint64) []byte {
+	if v <= intSmall {
+		return append(b, intZero+byte(v))
+	}
+	n := width(v)
+	return appendBigEndian(append(b, byte(IntMax-intMaxWidth+n)), v, n)
+}
+
+// DecodeUvarintAscending decodes a value encoded by EncodeUvarintAscending.
+func DecodeUvarintAscending(b []byte) ([]byte, uint64, error) {
+	if len(b) == 0 {
+		return nil, 0, errShort
+	}
+	m := b[0]
+	length := int(m) - intZero
+	b = b[1:]
+	if length < 0 {
+		return nil, 0, fmt.Errorf("invalid uvarint marker %#x", m)
+	}
+	if length <= intSmall {
+		return b, uint64(length), nil
+	}
+	length -= intSmall
+	if length > intMaxWidth {
+		return nil, 0, fmt.Errorf("invalid uvarint length %d", length)
+	}
+	if len(b) < length {
+		return nil, 0, errShort
+	}
+	var v uint64
+	for _, c := range b[:length] {
+		v = v<<8 | uint64(c)
+	}
+	return b[length:], v, nil
+}
+
+// EncodeUvarintDescending appends the descending encoding of v.
+func EncodeUvarintDescending(b []byte, v uint64) []byte {
+	if v == 0 {
+		return append(b, intZero)
+	}
+	n := width(v)
+	return appendBigEndian(append(b, byte(intZero-n)), ^v, n)
+}
+
+// DecodeUvarintDescending decodes a value encoded by EncodeUvarintDescending.
+func DecodeUvarintDescending(b []byte) ([]byte, uint64, error) {
+	if len(b) == 0 {
+		return nil, 0, errShort
+	}
+	length := intZero - int(b[0])
+	b = b[1:]
+	if length < 0 || length > intMaxWidth {
+		return nil, 0, fmt.Errorf("invalid uvarint length %d", length)
+	}
+	if len(b) < length {
+		return nil, 0, errShort
+	}
+	var v uint64
+	for _, c := range b[:length] {
+		v = v<<8 | uint64(^c)
+	}
+	return b[length:], v, nil
+}
+
+// EncodeVarintAscending appends the encoding of v. Non-negative values are
+// encoded as by EncodeUvarintAscending.
+func EncodeVarintAscending(b []byte, v int64) []byte {
+	if v >= 0 {
+		return EncodeUvarintAscending(b, uint64(v))
+	}
+	n := width(uint64(-v))
+	return appendBigEndian(append(b, byte(intZero-n)), uint64(v), n)
+}
+
+// DecodeVarintAscending decodes a value encoded by EncodeVarintAscending.
+func DecodeVarintAscending(b []byte) ([]byte, int64, error) {
+	if len(b) == 0 {
+		return nil, 0, errShort
+	}
+	length := int(b[0]) - intZero
+	if length >= 0 {
+		r, v, err := DecodeUvarintAscending(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		if v > math.MaxInt64 {
+			return nil, 0, fmt.Errorf("varint %d overflows int64", v)
+		}
+		return r, int64(v), nil
+	}
+	length = -length
+	b = b[1:]
+	if length > intMaxWidth {
+		return nil, 0, fmt.Errorf("invalid varint length %d", length)
+	}
+	if len(b) < length {
+		return nil, 0, errShort
+	}
+	// The ones' complement of the bytes builds a positive number, whose
+	// ones' complement is the negative value.
+	var v int64
+	for _, c := range b[:length] {
+		v = v<<8 | int64(^c)
+	}
+	return b[length:], ^v, nil
+}
+
+// EncodeVarintDescending appends the descending encoding of v.
+func EncodeVarintDescending(b []byte, v int64) []byte {
+	return EncodeVarintAscending(b, ^v)
+}
+
+// DecodeVarintDescending decodes a value encoded by EncodeVarintDescending.
+func DecodeVarintDescending(b []byte) ([]byte, int64, error) {
+	r, v, err := DecodeVarintAscending(b)
+	return r, ^v, err
+}
+
+// EncodeUint64Ascending appends the 8 bytes of v in big-endian order.
+func EncodeUint64Ascending(b []byte, v uint64) []byte {
+	return appendBigEndian(b, v, 8)
+}
+
+// DecodeUint64Ascending decodes a value encoded by EncodeUint64Ascending.
+func DecodeUint64Ascending(b []byte) ([]byte, uint64, error) {
+	if len(b) < 8 {
+		return nil, 0, errShort
+	}
+	var v uint64
+	for _, c := range b[:8] {
+		v = v<<8 | uint64(c)
+	}
+	return b[8:], v, nil
+}
+
+// EncodeUint64Descending appends the descending encoding of v.
+func EncodeUint64Descending(b []byte, v uint64) []byte {
+	return EncodeUint64Ascending(b, ^v)
+}
+
+// DecodeUint64Descending decodes a value encoded by EncodeUint64Descending.
+func DecodeUint64Descending(b []byte) ([]byte, uint64, error) {
+	r, v, err := DecodeUint64Ascending(b)
+	return r, ^v, err
+}
+
+// EncodeFloatAscending appends the encoding of f. NaN sorts before every
+// other value, and positive and negative zero are encoded alike.
+func EncodeFloatAscending(b []byte, f float64) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(b, floatNaN)
+	case f == 0:
+		return append(b, floatZero)
+	}
+	u := math.Float64bits(f)
+	if u&(1<<63) != 0 {
+		return EncodeUint64Ascending(append(b, floatNeg), ^u)
+	}
+	return EncodeUint64Ascending(append(b, floatPos), u)
+}
+
+// DecodeFloatAscending decodes a value encoded by EncodeFloatAscending.
+func DecodeFloatAscending(b []byte) ([]byte, float64, error) {
+	if PeekType(b) != Float {
+		return nil, 0, errors.New("did not find a float marker")
+	}
+	switch b[0] {
+	case floatNaN, floatNaNDesc:
+		return b[1:], math.NaN(), nil
+	case floatZero:
+		return b[1:], 0, nil
+	}
+	r, u, err := DecodeUint64Ascending(b[1:])
+	if err != nil {
+		return nil, 0, err
+	}
+	if b[0] == floatNeg {
+		u = ^u
+	}
+	return r, math.Float64frombits(u), nil
+}
+
+// EncodeFloatDescending appends the descending encoding of f. NaN sorts
+// after every other value.
+func EncodeFloatDescending(b []byte, f float64) []byte {
+	if math.IsNaN(f) {
+		return append(b, floatNaNDesc)
+	}
+	return EncodeFloatAscending(b, -f)
+}
+
+// DecodeFloatDescending decodes a value encoded by EncodeFloatDescending.
+func DecodeFloatDescending(b []byte) ([]byte, float64, error) {
+	r, f, err := DecodeFloatAscending(b)
+	if err != nil || math.IsNaN(f) || f == 0 {
+		return r, f, err
+	}
+	return r, -f, nil
+}
+
+// EncodeBytesAscending appends the encoding of data: a marker, the data with
+// every 0x00 byte escaped as 0x00 0xFF, and the terminator 0x00 0x01.
+func EncodeBytesAscending(b []byte, data []byte) []byte {
+	b = append(b, bytesMarker)
+	for {
+		i := bytes.IndexByte(data, escape)
+		if i < 0 {
+			break
+		}
+		b = append(append(b, data[:i]...), escape, escaped00)
+		data = data[i+1:]
+	}
+	return append(append(b, data...), escape, escapedTerm)
+}
+
+// EncodeBytesDescending appends the descending encoding of data, which is
+// the ones' complement of its ascending encoding after a distinct marker.
+func EncodeBytesDescending(b []byte, data []byte) []byte {
+	n := len(b)
+	b = EncodeBytesAscending(b, data)
+	b[n] = bytesDescMarker
+	for i := n + 1; i < len(b); i++ {
+		b[i] = ^b[i]
+	}
+	return b
+}
+
+// EncodeStringAscending appends the encoding of s, which is the encoding of
+// its bytes.
+func EncodeStringAscending(b []byte, s string) []byte {
+	return EncodeBytesAscending(b, []byte(s))
+}
+
+// EncodeStringDescending appends the descending encoding of s.
+func EncodeStringDescending(b []byte, s string) []byte {
+	return EncodeBytesDescending(b, []byte(s))
+}
+
+// DecodeBytesAscending decodes a value encoded by EncodeBytesAscending,
+// returning a new slice.
+func DecodeBytesAscending(b []byte) ([]byte, []byte, error) {
+	return decodeBytes(b, bytesMarker, 0)
+}
+
+// DecodeBytesDescending decodes a value encoded by EncodeBytesDescending,
+// returning a new slice.
+func DecodeBytesDescending(b []byte) ([]byte, []byte, error) {
+	return decodeBytes(b, bytesDescMarker, 0xff)
+}
+
+func decodeBytes(b []byte, marker, mask byte) ([]byte, []byte, error) {
+	if len(b) == 0 || b[0] != marker {
+		return nil, nil, fmt.Errorf("did not find marker %#x", marker)
+	}
+	var data []byte
+	for i := 1; i < len(b); i++ {
+		c := b[i] ^ mask
+		if c != escape {
+			data = append(data, c)
+			continue
+		}
+		if i+1 >= len(b) {
+			return nil, nil, errors.New("malformed escape")
+		}
+		switch b[i+1] ^ mask {
+		case escapedTerm:
+			if data == nil {
+				data = []byte{}
+			}
+			return b[i+2:], data, nil
+		case escaped00:
+			data = append(data, 0x00)
+			i++
+		default:
+			return nil, nil, fmt.Errorf("unknown escape sequence %#x %#x", escape, b[i+1]^mask)
+		}
+	}
+	return nil, nil, errors.New("did not find terminator")
+}
+
+// Format returns a printable representation of a key made of ascending
+// values, such as /53/1/"alice"/NULL, where every value is decoded according
+// to its type as reported by PeekType. Bytes are printed quoted, and
+// descending bytes are marked with a trailing "desc". Format stops at the
+// first value which cannot be decoded, printing the rest in hexadecimal.
+func Format(b []byte) string {
+	var sb strings.Builder
+	for len(b) > 0 {
+		var (
+			r   []byte
+			err error
+		)
+		switch PeekType(b) {
+		case Null:
+			r = b[1:]
+			sb.WriteString("/NULL")
+		case NotNull:
+			r = b[1:]
+			sb.WriteString("/!NULL")
+		case Int:
+			var v int64
+			if r, v, err = DecodeVarintAscending(b); err == nil {
+				fmt.Fprintf(&sb, "/%d", v)
+			}
+		case Float:
+			var f float64
+			if r, f, err = DecodeFloatAscending(b); err == nil {
+				fmt.Fprintf(&sb, "/%g", f)
+			}
+		case Bytes:
+			var d []byte
+			if r, d, err = DecodeBytesAscending(b); err == nil {
+				fmt.Fprintf(&sb, "/%q", d)
+			}
+		case BytesDesc:
+			var d []byte
+			if r, d, err = DecodeBytesDescending(b); err == nil {
+				fmt.Fprintf(&sb, "/%q desc", d)
+			}
+		default:
+			err = errors.New("unknown type")
+		}
+		if err != nil {
+			fmt.Fprintf(&sb, "/0x%x", b)
+			break
+		}
+		b = r
+	}
+	return sb.String()
+}
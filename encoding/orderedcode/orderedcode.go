@@ -0,0 +1,336 @@
+// Package orderedcode implements the encoding of github.com/google/orderedcode,
+// with the same API, so that keys written with that package can be read and
+// extended without being re-encoded.
+//
+// Append encodes a sequence of items such that the lexicographic order of the
+// encodings matches the order of the items: first by the first item, then by
+// the second, and so on. Parse decodes them. Unlike tuples, the encoding is
+// not self-describing: Parse must be given items of the types which were
+// appended.
+//
+// The supported types are string, int64, uint64, float64 (except NaN),
+// Infinity, StringOrInfinity and TrailingString. Wrapping an item with Decr
+// encodes it in decreasing order.
+package orderedcode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrCorrupt is returned by Parse for input which is not a valid encoding of
+// the given items.
+var ErrCorrupt = errors.New("orderedcode: corrupt input")
+
+const (
+	increasing byte = 0x00
+	decreasing byte = 0xff
+)
+
+// Infinity is an item which sorts after every string. It is encoded as two
+// 0xFF bytes, which no string encoding starts with. Like every other value of
+// type struct{}, it can be passed to Append, and Parse accepts it or a pointer
+// to it.
+var Infinity struct{}
+
+// StringOrInfinity is an item holding either a string or Infinity, which
+// sorts after every string. At most one of String and Infinity is set.
+type StringOrInfinity struct {
+	String   string
+	Infinity bool
+}
+
+// TrailingString is a string appended verbatim, without escaping or a
+// terminator. It must be the last item, and Parse consumes the rest of the
+// input into it.
+type TrailingString string
+
+type decr struct {
+	val interface{}
+}
+
+// Decr wraps an item, or a pointer to an item passed to Parse, so that it is
+// encoded in decreasing order: the encoding of the item is inverted bit by
+// bit.
+func Decr(val interface{}) interface{} {
+	return decr{val}
+}
+
+// msb[n] is a byte with the n most significant bits set.
+var msb = [9]byte{0x00, 0x80, 0xc0, 0xe0, 0xf0, 0xf8, 0xfc, 0xfe, 0xff}
+
+// Append appends the encoding of the items to buf and returns it. It returns
+// an error for items of an unsupported type, for NaN, for a StringOrInfinity
+// holding both a string and Infinity, and for a TrailingString which is not
+// the last item.
+func Append(buf []byte, items ...interface{}) ([]byte, error) {
+	for i, item := range items {
+		dir := increasing
+		if d, ok := item.(decr); ok {
+			dir, item = decreasing, d.val
+		}
+		n := len(buf)
+
+		switch x := item.(type) {
+		case struct{}:
+			buf = append(buf, 0xff, 0xff)
+		case string:
+			buf = appendString(buf, x)
+		case StringOrInfinity:
+			switch {
+			case x.Infinity && x.String != "":
+				return nil, errors.New("orderedcode: StringOrInfinity has non-zero String and non-zero Infinity")
+			case x.Infinity:
+				buf = append(buf, 0xff, 0xff)
+			default:
+				buf = appendString(buf, x.String)
+			}
+		case TrailingString:
+			if i != len(items)-1 {
+				return nil, errors.New("orderedcode: TrailingString must be the last item")
+			}
+			buf = append(buf, x...)
+		case int64:
+			buf = appendInt64(buf, x)
+		case uint64:
+			buf = appendUint64(buf, x)
+		case float64:
+			if math.IsNaN(x) {
+				return nil, errors.New("orderedcode: cannot append NaN")
+			}
+			v := int64(math.Float64bits(x))
+			if v < 0 {
+				v = math.MinInt64 - v
+			}
+			buf = appendInt64(buf, v)
+		default:
+			return nil, fmt.Errorf("orderedcode: unsupported item type %T", item)
+		}
+
+		if dir == decreasing {
+			invert(buf[n:])
+		}
+	}
+	return buf, nil
+}
+
+func invert(b []byte) {
+	for i := range b {
+		b[i] = ^b[i]
+	}
+}
+
+// appendString escapes 0x00 as 0x00 0xFF and 0xFF as 0xFF 0x00, and ends the
+// string with 0x00 0x01.
+func appendString(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0x00:
+			b = append(b, 0x00, 0xff)
+		case 0xff:
+			b = append(b, 0xff, 0x00)
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, 0x00, 0x01)
+}
+
+// appendUint64 writes the number of bytes of x, followed by the bytes.
+func appendUint64(b []byte, x uint64) []byte {
+	var a [9]byte
+	i := 8
+	for ; x > 0; x >>= 8 {
+		a[i] = byte(x)
+		i--
+	}
+	a[i] = byte(8 - i)
+	return append(b, a[i:]...)
+}
+
+// appendInt64 writes n bytes whose n most significant bits are set, followed
+// by a clear bit and the 7n-1 bits of x, for the least n which fits x, and
+// inverts the result for negative numbers.
+func appendInt64(b []byte, x int64) []byte {
+	if x >= -64 && x < 64 {
+		return append(b, byte(x)^0x80)
+	}
+	neg := x < 0
+	if neg {
+		x = ^x
+	}
+
+	n := 2
+	for n < 10 && x >= 1<<uint(7*n-1) {
+		n++
+	}
+	var a [10]byte
+	v := uint64(x)
+	for i := 9; i >= 10-n; i-- {
+		a[i] = byte(v)
+		v >>= 8
+	}
+	enc := a[10-n:]
+	if n <= 8 {
+		enc[0] |= msb[n]
+	} else {
+		enc[0] = 0xff
+		enc[1] |= msb[n-8]
+	}
+	if neg {
+		invert(enc)
+	}
+	return append(b, enc...)
+}
+
+// Parse decodes items from the start of encoded into the values pointed to by
+// items, which must be pointers to the types of the appended items, wrapped
+// with Decr for items appended in decreasing order. It returns the input
+// which follows the parsed items.
+func Parse(encoded string, items ...interface{}) (remaining string, err error) {
+	for i, item := range items {
+		dir := increasing
+		if d, ok := item.(decr); ok {
+			dir, item = decreasing, d.val
+		}
+
+		switch p := item.(type) {
+		case struct{}, *struct{}:
+			if len(encoded) < 2 || encoded[0]^dir != 0xff || encoded[1]^dir != 0xff {
+				return "", ErrCorrupt
+			}
+			encoded = encoded[2:]
+		case *string:
+			*p, encoded, err = parseString(encoded, dir)
+		case *StringOrInfinity:
+			if len(encoded) >= 2 && encoded[0]^dir == 0xff && encoded[1]^dir == 0xff {
+				*p = StringOrInfinity{Infinity: true}
+				encoded = encoded[2:]
+				continue
+			}
+			var s string
+			s, encoded, err = parseString(encoded, dir)
+			*p = StringOrInfinity{String: s}
+		case *TrailingString:
+			if i != len(items)-1 {
+				return "", errors.New("orderedcode: TrailingString must be the last item")
+			}
+			b := []byte(encoded)
+			if dir == decreasing {
+				invert(b)
+			}
+			*p, encoded = TrailingString(b), ""
+		case *int64:
+			*p, encoded, err = parseInt64(encoded, dir)
+		case *uint64:
+			*p, encoded, err = parseUint64(encoded, dir)
+		case *float64:
+			var v int64
+			v, encoded, err = parseInt64(encoded, dir)
+			if v < 0 {
+				v = math.MinInt64 - v
+			}
+			*p = math.Float64frombits(uint64(v))
+		default:
+			return "", fmt.Errorf("orderedcode: unsupported item type %T", item)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return encoded, nil
+}
+
+func parseString(s string, dir byte) (string, string, error) {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i] ^ dir
+		if c != 0x00 && c != 0xff {
+			b = append(b, c)
+			continue
+		}
+		if i+1 >= len(s) {
+			return "", "", ErrCorrupt
+		}
+		switch d := s[i+1] ^ dir; {
+		case c == 0x00 && d == 0x01:
+			return string(b), s[i+2:], nil
+		case c == 0x00 && d == 0xff:
+			b = append(b, 0x00)
+		case c == 0xff && d == 0x00:
+			b = append(b, 0xff)
+		default:
+			return "", "", ErrCorrupt
+		}
+		i++
+	}
+	return "", "", ErrCorrupt
+}
+
+func parseUint64(s string, dir byte) (uint64, string, error) {
+	if len(s) == 0 {
+		return 0, "", ErrCorrupt
+	}
+	n := int(s[0] ^ dir)
+	if n > 8 || len(s) < n+1 {
+		return 0, "", ErrCorrupt
+	}
+	var x uint64
+	for i := 1; i <= n; i++ {
+		x = x<<8 | uint64(s[i]^dir)
+	}
+	return x, s[n+1:], nil
+}
+
+func parseInt64(s string, dir byte) (int64, string, error) {
+	if len(s) == 0 {
+		return 0, "", ErrCorrupt
+	}
+	// Negative numbers are inverted, and start with a clear bit.
+	neg := s[0]^dir < 0x80
+	if neg {
+		dir = ^dir
+	}
+
+	c := s[0] ^ dir
+	n := 0
+	for n < 8 && c&0x80 != 0 {
+		c <<= 1
+		n++
+	}
+	if n == 8 {
+		if len(s) < 2 {
+			return 0, "", ErrCorrupt
+		}
+		for c = s[1] ^ dir; n < 11 && c&0x80 != 0; c <<= 1 {
+			n++
+		}
+	}
+	if n == 0 || n > 10 || len(s) < n {
+		return 0, "", ErrCorrupt
+	}
+
+	var a [10]byte
+	for i := 0; i < n; i++ {
+		a[i] = s[i] ^ dir
+	}
+	if n <= 8 {
+		a[0] &^= msb[n]
+	} else {
+		a[0] = 0
+		a[1] &^= msb[n-8]
+	}
+	var v uint64
+	for _, c := range a[:n] {
+		v = v<<8 | uint64(c)
+	}
+	if v > math.MaxInt64 {
+		return 0, "", ErrCorrupt
+	}
+	x := int64(v)
+	if neg {
+		x = ^x
+	}
+	return x, s[n:], nil
+}
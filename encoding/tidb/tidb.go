@@ -0,0 +1,240 @@
+// Package tidb implements the memcomparable key encoding of TiDB (its
+// util/codec package), which is also used by MyRocks for MySQL keys, as a
+// tuple.Codec. Keys encoded by Codec sort like the tuples they encode, and
+// can be read and written by TiDB and TiKV.
+//
+// Every element is written as a flag byte followed by its value: integers as
+// 8 big-endian bytes with the sign bit flipped, floats as 8 bytes whose order
+// matches their values, and byte strings in groups of 8 bytes, each followed
+// by a marker byte telling how many bytes of the group are padding.
+package tidb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// The flags starting the encoding of every element.
+const (
+	NilFlag          byte = 0
+	BytesFlag        byte = 1
+	CompactBytesFlag byte = 2
+	IntFlag          byte = 3
+	UintFlag         byte = 4
+	FloatFlag        byte = 5
+	DecimalFlag      byte = 6
+	DurationFlag     byte = 7
+	VarintFlag       byte = 8
+	UvarintFlag      byte = 9
+	JSONFlag         byte = 10
+	MaxFlag          byte = 250
+)
+
+const (
+	signMask  uint64 = 1 << 63
+	groupSize        = 8
+	marker    byte   = 0xFF
+)
+
+var errShort = errors.New("insufficient bytes to decode value")
+
+// Codec is the memcomparable tuple.Codec. It encodes nil, integers (int64,
+// int and byte as signed integers, uint32 and uint64 as unsigned ones),
+// floating point numbers (float64 and float32), and byte strings ([]byte,
+// string and lex.KeyConvertible, which all decode as []byte).
+//
+// Unpack decodes signed integers as int64, unsigned ones as uint64 and floats
+// as float64. It decodes the compact bytes and varint encodings used by TiDB
+// in values, but not decimals, durations and JSON.
+var Codec tuple.Codec = codec{}
+
+type codec struct{}
+
+func (codec) Pack(t tuple.Tuple) ([]byte, error) {
+	var b []byte
+	for i, el := range t {
+		switch el := el.(type) {
+		case nil:
+			b = append(b, NilFlag)
+		case int64:
+			b = EncodeInt(append(b, IntFlag), el)
+		case int:
+			b = EncodeInt(append(b, IntFlag), int64(el))
+		case byte:
+			b = EncodeInt(append(b, IntFlag), int64(el))
+		case uint32:
+			b = EncodeUint(append(b, UintFlag), uint64(el))
+		case uint64:
+			b = EncodeUint(append(b, UintFlag), el)
+		case float64:
+			b = EncodeFloat(append(b, FloatFlag), el)
+		case float32:
+			b = EncodeFloat(append(b, FloatFlag), float64(el))
+		case []byte:
+			b = EncodeBytes(append(b, BytesFlag), el)
+		case string:
+			b = EncodeBytes(append(b, BytesFlag), []byte(el))
+		case lex.KeyConvertible:
+			b = EncodeBytes(append(b, BytesFlag), el.LexKey())
+		default:
+			return nil, fmt.Errorf("unencodable element at index %d (%v, type %T)", i, el, el)
+		}
+	}
+	return b, nil
+}
+
+func (codec) Unpack(b []byte) (tuple.Tuple, error) {
+	var t tuple.Tuple
+	for len(b) > 0 {
+		var (
+			el  tuple.Element
+			err error
+		)
+		flag := b[0]
+		b = b[1:]
+		switch flag {
+		case NilFlag:
+		case IntFlag:
+			b, el, err = decodeInt(b)
+		case UintFlag:
+			b, el, err = decodeUint(b)
+		case FloatFlag:
+			b, el, err = decodeFloat(b)
+		case BytesFlag:
+			b, el, err = decodeBytes(b)
+		case CompactBytesFlag:
+			b, el, err = decodeCompactBytes(b)
+		case VarintFlag:
+			v, n := binary.Varint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("element %d: invalid varint", len(t))
+			}
+			b, el = b[n:], v
+		case UvarintFlag:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("element %d: invalid uvarint", len(t))
+			}
+			b, el = b[n:], v
+		default:
+			return nil, fmt.Errorf("element %d: unsupported flag %d", len(t), flag)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %v", len(t), err)
+		}
+		t = append(t, el)
+	}
+	return t, nil
+}
+
+// EncodeInt appends the memcomparable encoding of v, without a flag.
+func EncodeInt(b []byte, v int64) []byte {
+	return EncodeUint(b, uint64(v)^signMask)
+}
+
+// EncodeUint appends the memcomparable encoding of v, without a flag.
+func EncodeUint(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// EncodeFloat appends the memcomparable encoding of v, without a flag.
+func EncodeFloat(b []byte, v float64) []byte {
+	u := math.Float64bits(v)
+	if v >= 0 {
+		u |= signMask
+	} else {
+		u = ^u
+	}
+	return EncodeUint(b, u)
+}
+
+// EncodeBytes appends the memcomparable encoding of data, without a flag:
+// groups of 8 bytes, the last one padded with zeros, each followed by 0xFF
+// minus the number of padding bytes in the group. The last group always has
+// padding, so data whose length is a multiple of 8 ends with a group made
+// only of padding.
+func EncodeBytes(b []byte, data []byte) []byte {
+	for i := 0; i <= len(data); i += groupSize {
+		pad := 0
+		if rest := len(data) - i; rest >= groupSize {
+			b = append(b, data[i:i+groupSize]...)
+		} else {
+			pad = groupSize - rest
+			b = append(b, data[i:]...)
+			b = append(b, make([]byte, pad)...)
+		}
+		b = append(b, marker-byte(pad))
+	}
+	return b
+}
+
+func decodeUint(b []byte) ([]byte, tuple.Element, error) {
+	if len(b) < 8 {
+		return nil, nil, errShort
+	}
+	return b[8:], binary.BigEndian.Uint64(b), nil
+}
+
+func decodeInt(b []byte) ([]byte, tuple.Element, error) {
+	if len(b) < 8 {
+		return nil, nil, errShort
+	}
+	return b[8:], int64(binary.BigEndian.Uint64(b) ^ signMask), nil
+}
+
+func decodeFloat(b []byte) ([]byte, tuple.Element, error) {
+	if len(b) < 8 {
+		return nil, nil, errShort
+	}
+	u := binary.BigEndian.Uint64(b)
+	if u&signMask != 0 {
+		u &^= signMask
+	} else {
+		u = ^u
+	}
+	return b[8:], math.Float64frombits(u), nil
+}
+
+func decodeBytes(b []byte) ([]byte, tuple.Element, error) {
+	data := []byte{}
+	for {
+		if len(b) < groupSize+1 {
+			return nil, nil, errShort
+		}
+		group, m := b[:groupSize], b[groupSize]
+		b = b[groupSize+1:]
+		pad := int(marker - m)
+		if pad > groupSize {
+			return nil, nil, fmt.Errorf("invalid marker byte %#x", m)
+		}
+		data = append(data, group[:groupSize-pad]...)
+		if pad == 0 {
+			continue
+		}
+		for _, c := range group[groupSize-pad:] {
+			if c != 0 {
+				return nil, nil, fmt.Errorf("invalid padding byte %#x", c)
+			}
+		}
+		return b, data, nil
+	}
+}
+
+func decodeCompactBytes(b []byte) ([]byte, tuple.Element, error) {
+	n, l := binary.Varint(b)
+	if l <= 0 || n < 0 {
+		return nil, nil, errors.New("invalid compact bytes length")
+	}
+	b = b[l:]
+	if int64(len(b)) < n {
+		return nil, nil, errShort
+	}
+	return b[n:], append([]byte{}, b[:n]...), nil
+}
@@ -0,0 +1,108 @@
+package timestamp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Feed encodes times as two tuple elements, (bucket, offset), which sort the
+// most recent times first: bucket numbers the period of time holding the
+// time, inverted bit by bit, and offset is the number of units from the time
+// to the last unit of the period. Feeds read newest first with a forward scan
+// on stores lacking efficient reverse scans, and the keys of a period share a
+// prefix, from which they can be read or cleared at once. Offsets are small
+// integers, which take fewer bytes in keys than descending timestamps.
+type Feed struct {
+	e      Encoding
+	bucket int64
+}
+
+// Feed returns the Feed encoding times in the unit of e, in periods of the
+// given duration. The order of e is ignored: a Feed sorts the most recent
+// times first. Feed will panic if the duration is not a positive multiple of
+// the unit of e.
+func (e Encoding) Feed(bucket time.Duration) Feed {
+	if bucket <= 0 || bucket%e.unit != 0 {
+		panic(fmt.Sprintf("timestamp: invalid feed bucket %v for unit %v", bucket, e.unit))
+	}
+	return Feed{Encoding{e.unit, false}, int64(bucket / e.unit)}
+}
+
+// Bucket returns the duration of the periods of the feed.
+func (f Feed) Bucket() time.Duration {
+	return time.Duration(f.bucket) * f.e.unit
+}
+
+// split returns the number of the period holding the time and the offset of
+// the time from the last unit of the period.
+func (f Feed) split(t time.Time) (int64, int64) {
+	n := f.e.count(t)
+	b := n / f.bucket
+	if n%f.bucket < 0 {
+		b--
+	}
+	return b, (b+1)*f.bucket - 1 - n
+}
+
+// BucketStart returns the start of the period holding the time, in UTC.
+func (f Feed) BucketStart(t time.Time) time.Time {
+	b, _ := f.split(t)
+	return f.e.time(b * f.bucket)
+}
+
+// Tuple returns the elements encoding the time, (bucket, offset).
+func (f Feed) Tuple(t time.Time) tuple.Tuple {
+	b, off := f.split(t)
+	return tuple.Tuple{^b, off}
+}
+
+// FromTuple decodes a time from the first two elements of a tuple, encoded by
+// Tuple, in UTC.
+func (f Feed) FromTuple(t tuple.Tuple) (time.Time, error) {
+	b, err := t.GetInt(0)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp: bucket: %v", err)
+	}
+	off, err := t.GetInt(1)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp: offset: %v", err)
+	}
+	if off < 0 || off >= f.bucket {
+		return time.Time{}, fmt.Errorf("timestamp: offset %d out of range [0, %d)", off, f.bucket)
+	}
+	return f.e.time((^b+1)*f.bucket - 1 - off), nil
+}
+
+// Range returns the range of the keys in s of the times from from (inclusive)
+// to to (exclusive), whose tuples start with the elements returned by Tuple.
+// The range is read newest first by a forward scan.
+func (f Feed) Range(s subspace.Subspace, from, to time.Time) lex.KeyRange {
+	_, begin := s.Sub(f.Tuple(to)...).LexRangeKeys()
+	_, end := s.Sub(f.Tuple(from)...).LexRangeKeys()
+	return lex.KeyRange{Begin: begin, End: end}
+}
+
+// Ranges is like Range, but returns one range per period, newest first, such
+// as to read the periods of a feed in parallel or to stop reading a feed at
+// the end of a period.
+func (f Feed) Ranges(s subspace.Subspace, from, to time.Time) []lex.KeyRange {
+	if !from.Before(to) {
+		return nil
+	}
+	var rs []lex.KeyRange
+	for end := to; ; {
+		start := f.BucketStart(end)
+		if end.Equal(start) {
+			start = start.Add(-f.Bucket())
+		}
+		if !start.After(from) {
+			return append(rs, f.Range(s, from, end))
+		}
+		rs = append(rs, f.Range(s, start, end))
+		end = start
+	}
+}
@@ -0,0 +1,127 @@
+// Package timestamp encodes times so that their encodings sort by time, for
+// time-series keys. Every Encoding counts a unit of time (seconds,
+// milliseconds or nanoseconds) since the Unix epoch, in increasing or
+// decreasing order, and has two forms: a fixed-width fragment of 8 bytes for
+// raw keys, and a tuple element. A Feed encodes times as two tuple elements
+// grouping them in periods, for feeds read newest first.
+package timestamp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Size is the length of the raw encoding of a time.
+const Size = 8
+
+// Encoding is an encoding of times in a unit and an order.
+type Encoding struct {
+	unit time.Duration
+	desc bool
+}
+
+// The encodings of times. The descending encodings sort the most recent
+// times first, which suits keys of feeds read newest first.
+var (
+	Seconds = Encoding{time.Second, false}
+	Millis  = Encoding{time.Millisecond, false}
+	Nanos   = Encoding{time.Nanosecond, false}
+
+	SecondsDesc = Encoding{time.Second, true}
+	MillisDesc  = Encoding{time.Millisecond, true}
+	NanosDesc   = Encoding{time.Nanosecond, true}
+)
+
+// Unit returns the unit of time counted by the encoding.
+func (e Encoding) Unit() time.Duration {
+	return e.unit
+}
+
+// Descending returns true if the encoding sorts the most recent times first.
+func (e Encoding) Descending() bool {
+	return e.desc
+}
+
+// count returns the number of units since the Unix epoch, rounded down.
+// Counting nanoseconds is limited to the years 1678 to 2262.
+func (e Encoding) count(t time.Time) int64 {
+	switch e.unit {
+	case time.Second:
+		return t.Unix()
+	case time.Millisecond:
+		return t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+	}
+	return t.UnixNano()
+}
+
+func (e Encoding) time(n int64) time.Time {
+	switch e.unit {
+	case time.Second:
+		return time.Unix(n, 0).UTC()
+	case time.Millisecond:
+		s, ms := n/1000, n%1000
+		if ms < 0 {
+			s, ms = s-1, ms+1000
+		}
+		return time.Unix(s, ms*int64(time.Millisecond)).UTC()
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// Int returns the number of units since the Unix epoch, rounded down, as
+// encoded in tuples: in decreasing encodings, it is inverted bit by bit.
+func (e Encoding) Int(t time.Time) int64 {
+	n := e.count(t)
+	if e.desc {
+		n = ^n
+	}
+	return n
+}
+
+// Element returns the tuple element encoding the time, an int64 (see Int).
+func (e Encoding) Element(t time.Time) tuple.Element {
+	return e.Int(t)
+}
+
+// FromElement decodes a time from a tuple element returned by Element, in
+// UTC.
+func (e Encoding) FromElement(el tuple.Element) (time.Time, error) {
+	n, ok := el.(int64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("timestamp: expected an int64 element, got %T", el)
+	}
+	if e.desc {
+		n = ^n
+	}
+	return e.time(n), nil
+}
+
+// Append appends the raw encoding of the time: the 8 big-endian bytes of Int
+// with the sign bit flipped, which sort like Int.
+func (e Encoding) Append(b []byte, t time.Time) []byte {
+	var a [Size]byte
+	binary.BigEndian.PutUint64(a[:], uint64(e.Int(t))^(1<<63))
+	return append(b, a[:]...)
+}
+
+// Bytes returns the raw encoding of the time.
+func (e Encoding) Bytes(t time.Time) []byte {
+	return e.Append(nil, t)
+}
+
+// Decode decodes the time at the start of b, encoded by Append, returning it
+// in UTC and the bytes which follow it.
+func (e Encoding) Decode(b []byte) (time.Time, []byte, error) {
+	if len(b) < Size {
+		return time.Time{}, nil, errors.New("timestamp: insufficient bytes to decode time")
+	}
+	n := int64(binary.BigEndian.Uint64(b) ^ (1 << 63))
+	if e.desc {
+		n = ^n
+	}
+	return e.time(n), b[Size:], nil
+}
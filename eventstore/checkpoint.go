@@ -0,0 +1,119 @@
+package eventstore
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Position is the position of a consumer in a log: a log position, or a
+// composite position such as (commit version, index in batch). Positions
+// compare like their encodings, which are stored as the values of
+// checkpoints.
+type Position tuple.Tuple
+
+// Encode returns the encoding of the position, the packed tuple.
+func (p Position) Encode() []byte {
+	return tuple.Tuple(p).Pack()
+}
+
+// DecodePosition decodes a position encoded by Encode.
+func DecodePosition(b []byte) (Position, error) {
+	t, err := tuple.Unpack(b)
+	if err != nil {
+		return nil, fmt.Errorf("eventstore: invalid position: %v", err)
+	}
+	return Position(t), nil
+}
+
+// Compare returns an integer comparing two positions: 0 if p == o, -1 if
+// p < o and +1 if p > o. The empty position precedes every other position.
+func (p Position) Compare(o Position) int {
+	return tuple.Compare(tuple.Tuple(p), tuple.Tuple(o))
+}
+
+// Checkpoints builds the keys of the checkpoints of consumers, such as
+// projection workers: the key of a consumer is (consumer) in the subspace,
+// and its value is the encoding of the position up to which the consumer
+// has processed the log.
+type Checkpoints struct {
+	subspace.Subspace
+}
+
+// NewCheckpoints returns the Checkpoints stored in the subspace s.
+func NewCheckpoints(s subspace.Subspace) Checkpoints {
+	return Checkpoints{s}
+}
+
+// Key returns the key of the checkpoint of a consumer.
+func (c Checkpoints) Key(consumer string) lex.Key {
+	return c.Pack(tuple.Tuple{consumer})
+}
+
+// ParseKey decodes the key of the checkpoint of a consumer.
+func (c Checkpoints) ParseKey(key lex.KeyConvertible) (string, error) {
+	t, err := c.Unpack(key)
+	if err != nil {
+		return "", err
+	}
+	if len(t) != 1 {
+		return "", fmt.Errorf("eventstore: expected (consumer), got %d elements", len(t))
+	}
+	consumer, ok := t[0].(string)
+	if !ok {
+		return "", fmt.Errorf("eventstore: expected a string consumer, got %T", t[0])
+	}
+	return consumer, nil
+}
+
+// Load returns the checkpoint of a consumer, and false if it has none.
+func (c Checkpoints) Load(store lex.KVStore, consumer string) (Position, bool, error) {
+	v, err := lex.Get(store, c.Key(consumer))
+	if err != nil || v == nil {
+		return nil, false, err
+	}
+	p, err := DecodePosition(v)
+	if err != nil {
+		return nil, false, err
+	}
+	return p, true, nil
+}
+
+// Save sets the checkpoint of a consumer.
+func (c Checkpoints) Save(store lex.KVStore, consumer string, p Position) error {
+	return store.Set(c.Key(consumer), p.Encode())
+}
+
+// Advance sets the checkpoint of a consumer to p if p follows its current
+// checkpoint (or if it has none), and returns true if it did. As KVStore has
+// no transactions, Advance only guards against moving a checkpoint backwards
+// if a single worker updates it.
+func (c Checkpoints) Advance(store lex.KVStore, consumer string, p Position) (bool, error) {
+	cur, ok, err := c.Load(store, consumer)
+	if err != nil {
+		return false, err
+	}
+	if ok && p.Compare(cur) <= 0 {
+		return false, nil
+	}
+	return true, c.Save(store, consumer, p)
+}
+
+// Min returns the smallest checkpoint of the given consumers, up to which
+// every one of them has processed the log (for instance, to know which
+// events can be archived), and false if one of them has no checkpoint.
+func (c Checkpoints) Min(store lex.KVStore, consumers ...string) (Position, bool, error) {
+	var min Position
+	for i, consumer := range consumers {
+		p, ok, err := c.Load(store, consumer)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		if i == 0 || p.Compare(min) < 0 {
+			min = p
+		}
+	}
+	return min, len(consumers) > 0, nil
+}
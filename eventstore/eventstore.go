@@ -0,0 +1,144 @@
+// Package eventstore provides the key scheme of event sourcing on an ordered
+// key-value store: the events of every stream, keyed by stream and version so
+// that a stream reads in order from any version, and a global log keyed by
+// position so that projections can follow every event in commit order.
+//
+// The package only builds and decodes keys and ranges, and reads and writes
+// the checkpoints of consumers; events, and how the log refers to them, are
+// left to the application.
+package eventstore
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Keys builds the keys of an event store: (stream, version) in Streams,
+// (position) in Log and the checkpoints of the consumers of the log in
+// Checkpoints. Streams are identified by any tuple element, such as a string
+// or a UUID; versions and positions are non-negative integers.
+type Keys struct {
+	Streams     subspace.Subspace
+	Log         subspace.Subspace
+	Checkpoints Checkpoints
+}
+
+// New returns the Keys of an event store in the subspace s, whose streams are
+// stored under s.Sub(0), whose log is stored under s.Sub(1) and whose
+// checkpoints are stored under s.Sub(2).
+func New(s subspace.Subspace) Keys {
+	return Keys{Streams: s.Sub(0), Log: s.Sub(1), Checkpoints: NewCheckpoints(s.Sub(2))}
+}
+
+// Event returns the key of a version of a stream.
+func (k Keys) Event(stream tuple.Element, version int64) lex.Key {
+	return k.Streams.Pack(tuple.Tuple{stream, version})
+}
+
+// ParseEvent decodes the key of a version of a stream.
+func (k Keys) ParseEvent(key lex.KeyConvertible) (stream tuple.Element, version int64, err error) {
+	t, err := k.Streams.Unpack(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(t) != 2 {
+		return nil, 0, fmt.Errorf("eventstore: expected (stream, version), got %d elements", len(t))
+	}
+	version, ok := t[1].(int64)
+	if !ok {
+		return nil, 0, fmt.Errorf("eventstore: expected an int64 version, got %T", t[1])
+	}
+	return t[0], version, nil
+}
+
+// Stream returns the range of every version of a stream.
+func (k Keys) Stream(stream tuple.Element) lex.KeyRange {
+	b, e := k.Streams.Sub(stream).LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: e}
+}
+
+// StreamRange returns the range of the versions of a stream from fromVersion
+// included on.
+func (k Keys) StreamRange(stream tuple.Element, fromVersion int64) lex.KeyRange {
+	_, e := k.Streams.Sub(stream).LexRangeKeys()
+	return lex.KeyRange{Begin: k.Event(stream, fromVersion), End: e}
+}
+
+// StreamBetween returns the range of the versions of a stream from
+// fromVersion included to toVersion excluded.
+func (k Keys) StreamBetween(stream tuple.Element, fromVersion, toVersion int64) lex.KeyRange {
+	return lex.KeyRange{Begin: k.Event(stream, fromVersion), End: k.Event(stream, toVersion)}
+}
+
+// Position returns the key of a position in the log.
+func (k Keys) Position(position int64) lex.Key {
+	return k.Log.Pack(tuple.Tuple{position})
+}
+
+// ParsePosition decodes the key of a position in the log.
+func (k Keys) ParsePosition(key lex.KeyConvertible) (int64, error) {
+	t, err := k.Log.Unpack(key)
+	if err != nil {
+		return 0, err
+	}
+	if len(t) != 1 {
+		return 0, fmt.Errorf("eventstore: expected (position), got %d elements", len(t))
+	}
+	position, ok := t[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("eventstore: expected an int64 position, got %T", t[0])
+	}
+	return position, nil
+}
+
+// LogRange returns the range of the positions of the log from fromPosition
+// included on.
+func (k Keys) LogRange(fromPosition int64) lex.KeyRange {
+	_, e := k.Log.LexRangeKeys()
+	return lex.KeyRange{Begin: k.Position(fromPosition), End: e}
+}
+
+// LogBetween returns the range of the positions of the log from fromPosition
+// included to toPosition excluded.
+func (k Keys) LogBetween(fromPosition, toPosition int64) lex.KeyRange {
+	return lex.KeyRange{Begin: k.Position(fromPosition), End: k.Position(toPosition)}
+}
+
+// Head returns the last version of a stream, and false if the stream holds no
+// events.
+func (k Keys) Head(store lex.KVStore, stream tuple.Element) (int64, bool, error) {
+	r := k.Stream(stream)
+	last, err := store.GetKey(lex.LastLessThan(r.End))
+	if err != nil {
+		return 0, false, err
+	}
+	if !k.Streams.Sub(stream).Contains(last) {
+		return 0, false, nil
+	}
+	_, version, err := k.ParseEvent(last)
+	if err != nil {
+		return 0, false, err
+	}
+	return version, true, nil
+}
+
+// LastPosition returns the last position of the log, and false if the log is
+// empty.
+func (k Keys) LastPosition(store lex.KVStore) (int64, bool, error) {
+	_, e := k.Log.LexRangeKeys()
+	last, err := store.GetKey(lex.LastLessThan(e))
+	if err != nil {
+		return 0, false, err
+	}
+	if !k.Log.Contains(last) {
+		return 0, false, nil
+	}
+	position, err := k.ParsePosition(last)
+	if err != nil {
+		return 0, false, err
+	}
+	return position, true, nil
+}
@@ -0,0 +1,335 @@
+// Package extsort sorts streams of key-value pairs which may not fit in
+// memory. Pairs are buffered up to a memory limit, sorted and spilled to
+// temporary run files, which are then merged in key order. The sorted output
+// is suitable for bulk imports into ordered backends, which are usually much
+// faster when keys arrive in order.
+//
+// Sorted streams are written in a simple framed format: every pair is encoded
+// as the uvarint length of the key, the key, the uvarint length of the value
+// and the value. The same format is used for the run files.
+package extsort
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/abdullin/lex-go"
+)
+
+// DefaultMemLimit is the number of bytes buffered before a run is spilled to
+// disk when Options.MemLimit is not set.
+const DefaultMemLimit = 64 << 20
+
+// maxChunkSize is the length above which a key or value is rejected, both by
+// Write and by a Reader, rather than allocating a buffer for it, as a corrupt
+// length would make a Reader do.
+const maxChunkSize = 1 << 28
+
+// Options control the behavior of a Sorter. The default zero-value of Options
+// spills runs to the default temporary directory with the default memory
+// limit.
+type Options struct {
+	// Dir is the directory in which run files are created. An empty Dir
+	// indicates the default directory for temporary files.
+	Dir string
+
+	// MemLimit is the number of bytes of keys and values buffered in memory
+	// before they are sorted and spilled to a run file.
+	MemLimit int
+}
+
+func (o Options) memLimit() int {
+	if o.MemLimit > 0 {
+		return o.MemLimit
+	}
+	return DefaultMemLimit
+}
+
+// Sorter sorts the key-value pairs added to it. Pairs with equal keys are
+// kept, in the order in which they were added.
+type Sorter struct {
+	o    Options
+	buf  []lex.KeyValue
+	size int
+	runs []*os.File
+	done bool
+}
+
+// New returns an empty Sorter.
+func New(o Options) *Sorter {
+	return &Sorter{o: o}
+}
+
+// Add adds a key-value pair to the sorter. The key and value are copied, so
+// the caller may reuse them.
+func (s *Sorter) Add(key lex.KeyConvertible, value []byte) error {
+	if s.done {
+		return errors.New("extsort: sorter already sorted")
+	}
+
+	k := key.LexKey()
+	kv := lex.KeyValue{Key: append(lex.Key{}, k...), Value: append([]byte{}, value...)}
+	s.buf = append(s.buf, kv)
+	s.size += len(kv.Key) + len(kv.Value)
+
+	if s.size >= s.o.memLimit() {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill sorts the buffered pairs and writes them to a new run file.
+func (s *Sorter) spill() error {
+	f, err := os.CreateTemp(s.o.Dir, "extsort-*.run")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f)
+
+	s.sortBuffer()
+	w := bufio.NewWriter(f)
+	for _, kv := range s.buf {
+		if err := Write(w, kv); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	s.buf, s.size = s.buf[:0], 0
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+func (s *Sorter) sortBuffer() {
+	sort.SliceStable(s.buf, func(i, j int) bool {
+		return bytes.Compare(s.buf[i].Key, s.buf[j].Key) < 0
+	})
+}
+
+// Sort merges every pair added to the sorter and calls emit with each of them
+// in key order. A Sorter may only be sorted once; the run files are removed
+// once Sort returns.
+func (s *Sorter) Sort(emit func(lex.KeyValue) error) error {
+	if s.done {
+		return errors.New("extsort: sorter already sorted")
+	}
+	s.done = true
+	defer s.Close()
+
+	s.sortBuffer()
+	if len(s.runs) == 0 {
+		for _, kv := range s.buf {
+			if err := emit(kv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The in-memory buffer is merged as the last run, so that pairs with equal
+	// keys keep the order in which they were added.
+	m := &merger{}
+	for i, f := range s.runs {
+		if err := m.push(i, NewReader(bufio.NewReader(f))); err != nil {
+			return err
+		}
+	}
+	if err := m.push(len(s.runs), &sliceSource{s.buf}); err != nil {
+		return err
+	}
+
+	for m.Len() > 0 {
+		c := m.cursors[0]
+		if err := emit(c.kv); err != nil {
+			return err
+		}
+		kv, err := c.src.Next()
+		switch {
+		case err == io.EOF:
+			heap.Pop(m)
+		case err != nil:
+			return err
+		default:
+			c.kv = kv
+			heap.Fix(m, 0)
+		}
+	}
+	return nil
+}
+
+// WriteTo sorts the pairs added to the sorter and writes them to w in the
+// framed format.
+func (s *Sorter) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	err := s.Sort(func(kv lex.KeyValue) error {
+		return Write(bw, kv)
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	return cw.n, err
+}
+
+// Close removes the run files of the sorter. It is only needed when a Sorter
+// is abandoned without being sorted.
+func (s *Sorter) Close() error {
+	var first error
+	for _, f := range s.runs {
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+		if err := os.Remove(f.Name()); err != nil && first == nil {
+			first = err
+		}
+	}
+	s.runs, s.buf = nil, nil
+	s.done = true
+	return first
+}
+
+// Write writes a single key-value pair to w in the framed format.
+func Write(w io.Writer, kv lex.KeyValue) error {
+	if len(kv.Key) > maxChunkSize || len(kv.Value) > maxChunkSize {
+		return fmt.Errorf("extsort: key or value longer than %d bytes", maxChunkSize)
+	}
+	var n [binary.MaxVarintLen64]byte
+	if _, err := w.Write(n[:binary.PutUvarint(n[:], uint64(len(kv.Key)))]); err != nil {
+		return err
+	}
+	if _, err := w.Write(kv.Key); err != nil {
+		return err
+	}
+	if _, err := w.Write(n[:binary.PutUvarint(n[:], uint64(len(kv.Value)))]); err != nil {
+		return err
+	}
+	_, err := w.Write(kv.Value)
+	return err
+}
+
+// Reader reads key-value pairs written in the framed format.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader reading pairs from r.
+func NewReader(r io.Reader) *Reader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Reader{br}
+}
+
+// Next returns the next key-value pair, or io.EOF once every pair has been
+// read.
+func (r *Reader) Next() (lex.KeyValue, error) {
+	k, err := r.chunk()
+	if err != nil {
+		return lex.KeyValue{}, err
+	}
+	v, err := r.chunk()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return lex.KeyValue{Key: k, Value: v}, err
+}
+
+func (r *Reader) chunk() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxChunkSize {
+		return nil, fmt.Errorf("extsort: chunk of %d bytes is longer than %d bytes", n, maxChunkSize)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// source is a sorted stream of key-value pairs merged by Sort.
+type source interface {
+	Next() (lex.KeyValue, error)
+}
+
+type sliceSource struct {
+	kvs []lex.KeyValue
+}
+
+func (s *sliceSource) Next() (lex.KeyValue, error) {
+	if len(s.kvs) == 0 {
+		return lex.KeyValue{}, io.EOF
+	}
+	kv := s.kvs[0]
+	s.kvs = s.kvs[1:]
+	return kv, nil
+}
+
+type cursor struct {
+	run int
+	kv  lex.KeyValue
+	src source
+}
+
+// merger is a heap of cursors ordered by their current key, then by run.
+type merger struct {
+	cursors []*cursor
+}
+
+func (m *merger) push(run int, src source) error {
+	kv, err := src.Next()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	heap.Push(m, &cursor{run, kv, src})
+	return nil
+}
+
+func (m *merger) Len() int { return len(m.cursors) }
+
+func (m *merger) Less(i, j int) bool {
+	a, b := m.cursors[i], m.cursors[j]
+	if c := bytes.Compare(a.kv.Key, b.kv.Key); c != 0 {
+		return c < 0
+	}
+	return a.run < b.run
+}
+
+func (m *merger) Swap(i, j int) { m.cursors[i], m.cursors[j] = m.cursors[j], m.cursors[i] }
+
+func (m *merger) Push(x interface{}) { m.cursors = append(m.cursors, x.(*cursor)) }
+
+func (m *merger) Pop() interface{} {
+	c := m.cursors[len(m.cursors)-1]
+	m.cursors = m.cursors[:len(m.cursors)-1]
+	return c
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,162 @@
+// Package graph provides the key scheme of directed graphs with labelled
+// edges on an ordered key-value store, for property graphs: every edge is
+// stored twice, as an out-edge (from, label, to) and as an in-edge (to,
+// label, from), so that the neighbours of a node are read in either
+// direction with a single range read, all at once or for a label.
+//
+// Nodes and labels are tuple elements. The value of an out-edge holds the
+// properties of the edge, if any; in-edges are empty.
+package graph
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Edge is an edge of a graph, decoded from its key.
+type Edge struct {
+	From  tuple.Element
+	Label tuple.Element
+	To    tuple.Element
+}
+
+// Graph builds the keys of a graph: (from, label, to) in Out and (to, label,
+// from) in In.
+type Graph struct {
+	Out subspace.Subspace
+	In  subspace.Subspace
+}
+
+// New returns the Graph in the subspace s, whose out-edges are stored under
+// s.Sub(0) and whose in-edges are stored under s.Sub(1).
+func New(s subspace.Subspace) Graph {
+	return Graph{Out: s.Sub(0), In: s.Sub(1)}
+}
+
+// OutKey returns the key of the out-edge of an edge.
+func (g Graph) OutKey(e Edge) lex.Key {
+	return g.Out.Pack(tuple.Tuple{e.From, e.Label, e.To})
+}
+
+// InKey returns the key of the in-edge of an edge.
+func (g Graph) InKey(e Edge) lex.Key {
+	return g.In.Pack(tuple.Tuple{e.To, e.Label, e.From})
+}
+
+func parse(s subspace.Subspace, k lex.KeyConvertible) (tuple.Tuple, error) {
+	t, err := s.Unpack(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(t) != 3 {
+		return nil, fmt.Errorf("graph: expected (node, label, node), got %d elements", len(t))
+	}
+	return t, nil
+}
+
+// ParseOut decodes the key of an out-edge.
+func (g Graph) ParseOut(k lex.KeyConvertible) (Edge, error) {
+	t, err := parse(g.Out, k)
+	if err != nil {
+		return Edge{}, err
+	}
+	return Edge{From: t[0], Label: t[1], To: t[2]}, nil
+}
+
+// ParseIn decodes the key of an in-edge.
+func (g Graph) ParseIn(k lex.KeyConvertible) (Edge, error) {
+	t, err := parse(g.In, k)
+	if err != nil {
+		return Edge{}, err
+	}
+	return Edge{From: t[2], Label: t[1], To: t[0]}, nil
+}
+
+func prefixRange(s subspace.Subspace, el ...tuple.Element) lex.KeyRange {
+	b, e := s.Sub(el...).LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: e}
+}
+
+// OutRange returns the range of the out-edges of a node.
+func (g Graph) OutRange(from tuple.Element) lex.KeyRange {
+	return prefixRange(g.Out, from)
+}
+
+// OutLabelRange returns the range of the out-edges of a node with a label.
+func (g Graph) OutLabelRange(from, label tuple.Element) lex.KeyRange {
+	return prefixRange(g.Out, from, label)
+}
+
+// InRange returns the range of the in-edges of a node.
+func (g Graph) InRange(to tuple.Element) lex.KeyRange {
+	return prefixRange(g.In, to)
+}
+
+// InLabelRange returns the range of the in-edges of a node with a label.
+func (g Graph) InLabelRange(to, label tuple.Element) lex.KeyRange {
+	return prefixRange(g.In, to, label)
+}
+
+// Add adds an edge with properties, replacing the properties of the edge if
+// it is present already.
+func (g Graph) Add(store lex.KVStore, e Edge, properties []byte) error {
+	if err := store.Set(g.OutKey(e), properties); err != nil {
+		return err
+	}
+	return store.Set(g.InKey(e), nil)
+}
+
+// Remove removes an edge. Removing a missing edge is not an error.
+func (g Graph) Remove(store lex.KVStore, e Edge) error {
+	if err := store.Clear(g.OutKey(e)); err != nil {
+		return err
+	}
+	return store.Clear(g.InKey(e))
+}
+
+// Properties returns the properties of an edge, or nil if the graph does not
+// hold the edge (see lex.Get).
+func (g Graph) Properties(store lex.KVStore, e Edge) ([]byte, error) {
+	return lex.Get(store, g.OutKey(e))
+}
+
+// Neighbors returns the edges of a node in a range returned by OutRange,
+// OutLabelRange, InRange or InLabelRange, reading at most limit edges if
+// limit is positive.
+func (g Graph) Neighbors(store lex.KVStore, r lex.KeyRange, limit int) ([]Edge, error) {
+	kvs, err := store.GetRange(r, lex.RangeOptions{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	edges := make([]Edge, len(kvs))
+	for i, kv := range kvs {
+		if g.Out.Contains(kv.Key) {
+			edges[i], err = g.ParseOut(kv.Key)
+		} else {
+			edges[i], err = g.ParseIn(kv.Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return edges, nil
+}
+
+// RemoveNode removes every edge from and to a node.
+func (g Graph) RemoveNode(store lex.KVStore, node tuple.Element) error {
+	for _, r := range []lex.KeyRange{g.OutRange(node), g.InRange(node)} {
+		edges, err := g.Neighbors(store, r, 0)
+		if err != nil {
+			return err
+		}
+		for _, e := range edges {
+			if err := g.Remove(store, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package lex
+
+import "hash/fnv"
+
+// Hash64 returns the 64-bit FNV-1a hash of the key. The hash only depends on
+// the bytes of the key, so it is stable across processes and releases, and
+// may be used to shard keys or stored in place of the keys.
+func (k Key) Hash64() uint64 {
+	h := fnv.New64a()
+	h.Write(k)
+	return h.Sum64()
+}
@@ -0,0 +1,150 @@
+// Package hca implements the high-contention allocator (HCA) of the
+// FoundationDB directory layer as a standalone component, so that
+// applications can allocate compact, unique integers (typically used as key
+// prefixes) without coordinating through a single counter.
+//
+// Candidates are drawn at random from a window of integers, which advances
+// once half of it has been allocated. Concurrent allocations therefore
+// rarely touch the same keys, while the allocated integers stay small and
+// encode to short tuple prefixes.
+//
+// An allocation performs several reads and writes. KVStore has no
+// transactions, so a store shared by concurrent allocators must be wrapped
+// in a transaction (for example with badgerkv.Txn) for allocations to be
+// unique.
+package hca
+
+import (
+	"encoding/binary"
+	"math/rand"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/kvutil"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Allocator allocates unique integers, keeping its state in a subspace.
+type Allocator struct {
+	counters, recent subspace.Subspace
+}
+
+// New returns an Allocator keeping its state in the subspace s, using the
+// same layout as the allocators of the other FoundationDB bindings.
+func New(s subspace.Subspace) *Allocator {
+	return &Allocator{s.Sub(int64(0)), s.Sub(int64(1))}
+}
+
+func windowSize(start int64) int64 {
+	switch {
+	case start < 255:
+		return 64
+	case start < 65535:
+		return 1024
+	}
+	return 8192
+}
+
+// Allocate returns a non-negative integer which has never been returned by an
+// Allocator using the same subspace.
+func (a *Allocator) Allocate(store lex.KVStore) (int64, error) {
+	for {
+		start, window, err := a.advance(store)
+		if err != nil {
+			return 0, err
+		}
+
+		for {
+			candidate := start + rand.Int63n(window)
+			key := a.recent.Pack(tuple.Tuple{candidate})
+
+			v, err := lex.Get(store, key)
+			if err != nil {
+				return 0, err
+			}
+
+			latest, err := a.latest(store)
+			if err != nil {
+				return 0, err
+			}
+			if latest != start {
+				// Another allocation advanced the window; start over.
+				break
+			}
+
+			if v == nil {
+				if err := store.Set(key, []byte{}); err != nil {
+					return 0, err
+				}
+				return candidate, nil
+			}
+		}
+	}
+}
+
+// latest returns the start of the current window.
+func (a *Allocator) latest(store lex.KVStore) (int64, error) {
+	kvs, err := store.GetRange(a.counters, lex.RangeOptions{Limit: 1, Reverse: true})
+	if err != nil || len(kvs) == 0 {
+		return 0, err
+	}
+	t, err := a.counters.Unpack(kvs[0].Key)
+	if err != nil {
+		return 0, err
+	}
+	return t[0].(int64), nil
+}
+
+// advance counts an allocation in the current window, moving to a new window
+// if the current one is at least half full, and returns the start and size
+// of the window to allocate from.
+func (a *Allocator) advance(store lex.KVStore) (int64, int64, error) {
+	start, err := a.latest(store)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for {
+		key := a.counters.Pack(tuple.Tuple{start})
+		v, err := lex.Get(store, key)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		var count int64
+		if len(v) == 8 {
+			count = int64(binary.LittleEndian.Uint64(v))
+		}
+		count++
+
+		window := windowSize(start)
+		if count*2 < window {
+			b := make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, uint64(count))
+			if err := store.Set(key, b); err != nil {
+				return 0, 0, err
+			}
+			return start, window, nil
+		}
+
+		// The window is half full: clear it and move past it.
+		if err := clearBefore(store, a.counters, start+1); err != nil {
+			return 0, 0, err
+		}
+		if err := clearBefore(store, a.recent, start+window); err != nil {
+			return 0, 0, err
+		}
+		start += window
+		if err := store.Set(a.counters.Pack(tuple.Tuple{start}), make([]byte, 8)); err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// clearBefore removes the keys of s whose first element is less than end.
+func clearBefore(store lex.KVStore, s subspace.Subspace, end int64) error {
+	begin, _ := s.LexRangeKeys()
+	r := lex.KeyRange{Begin: begin, End: s.Pack(tuple.Tuple{end})}
+	_, err := kvutil.ClearRange(store, r, kvutil.Options{Samples: -1})
+	return err
+}
@@ -0,0 +1,118 @@
+// Package index builds the keys of secondary indexes: an index entry is the
+// key (values, pk) in the subspace of the index, where values are the indexed
+// values of a record and pk its primary key, so that the records with given
+// values are found by reading a range of the index and extracting their
+// primary keys from the entry keys.
+//
+// Index entries carry no value; the package only builds and decodes keys and
+// ranges, leaving reads and writes to the application.
+package index
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Index builds the keys of a secondary index indexing Columns values of
+// records, stored in Subspace.
+type Index struct {
+	Subspace subspace.Subspace
+	Columns  int
+}
+
+// New returns an Index of the given number of columns, stored in the
+// subspace s.
+func New(s subspace.Subspace, columns int) Index {
+	return Index{Subspace: s, Columns: columns}
+}
+
+// Entry returns the key of the index entry of a record. Entry will panic if
+// values does not hold a value per column of the index.
+func (x Index) Entry(values, pk tuple.Tuple) lex.Key {
+	if len(values) != x.Columns {
+		panic(fmt.Sprintf("index: expected %d values, got %d", x.Columns, len(values)))
+	}
+	t := make(tuple.Tuple, 0, len(values)+len(pk))
+	return x.Subspace.Pack(append(append(t, values...), pk...))
+}
+
+// Parse decodes the key of an index entry into the indexed values and the
+// primary key of the record.
+func (x Index) Parse(key lex.KeyConvertible) (values, pk tuple.Tuple, err error) {
+	t, err := x.Subspace.Unpack(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(t) < x.Columns {
+		return nil, nil, fmt.Errorf("index: expected at least %d elements, got %d", x.Columns, len(t))
+	}
+	return t[:x.Columns:x.Columns], t[x.Columns:], nil
+}
+
+// PrimaryKey decodes the primary key of the record of an index entry.
+func (x Index) PrimaryKey(key lex.KeyConvertible) (tuple.Tuple, error) {
+	_, pk, err := x.Parse(key)
+	return pk, err
+}
+
+// Equal returns the range of the entries whose leading values equal the
+// given values: every entry of the index for no values, the entries of the
+// records with the given values for a value per column, and a prefix lookup
+// on the leading columns in between. Equal will panic if more values than
+// columns are given.
+func (x Index) Equal(values ...tuple.Element) lex.KeyRange {
+	if len(values) > x.Columns {
+		panic(fmt.Sprintf("index: expected at most %d values, got %d", x.Columns, len(values)))
+	}
+	b, e := x.Subspace.Sub(values...).LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: e}
+}
+
+// StartsWith returns the range of the entries whose leading values equal the
+// given values, and whose next value is a string starting with prefix.
+// StartsWith will panic unless fewer values than columns are given.
+func (x Index) StartsWith(values tuple.Tuple, prefix string) lex.KeyRange {
+	if len(values) >= x.Columns {
+		panic(fmt.Sprintf("index: expected at most %d values, got %d", x.Columns-1, len(values)))
+	}
+	// The encoding of a string starting with prefix starts with the encoding
+	// of prefix without its terminator.
+	p := tuple.Tuple{prefix}.Pack()
+	// It holds the string typecode, so it does not consist only of 0xFF bytes
+	// and always has an end.
+	begin := append(append([]byte{}, x.Subspace.Sub(values...).Bytes()...), p[:len(p)-1]...)
+	end, _ := lex.PrefixEnd(begin)
+	return lex.KeyRange{Begin: lex.Key(begin), End: end}
+}
+
+// Between returns the range of the entries whose leading values equal the
+// given values, and whose next value is from from included to to excluded.
+// Between will panic unless fewer values than columns are given.
+func (x Index) Between(values tuple.Tuple, from, to tuple.Element) lex.KeyRange {
+	if len(values) >= x.Columns {
+		panic(fmt.Sprintf("index: expected at most %d values, got %d", x.Columns-1, len(values)))
+	}
+	s := x.Subspace.Sub(values...)
+	return lex.KeyRange{Begin: s.Pack(tuple.Tuple{from}), End: s.Pack(tuple.Tuple{to})}
+}
+
+// Update returns the entry to clear and the entry to set when the indexed
+// values of a record change from old to new, and false if they are equal and
+// the index is unchanged. A nil old is a record being inserted, whose clear
+// entry is nil, and a nil new is a record being deleted, whose set entry is
+// nil.
+func (x Index) Update(pk, old, new tuple.Tuple) (clear, set lex.Key, changed bool) {
+	if old != nil && new != nil && old.Equal(new) {
+		return nil, nil, false
+	}
+	if old != nil {
+		clear = x.Entry(old, pk)
+	}
+	if new != nil {
+		set = x.Entry(new, pk)
+	}
+	return clear, set, true
+}
@@ -0,0 +1,278 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Order is the direction in which the values of a column sort.
+type Order int
+
+// The orders of a column: ascending or descending.
+const (
+	Asc Order = iota
+	Desc
+)
+
+func (o Order) String() string {
+	if o == Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// Nulls is the position of the null (nil) values of a column, regardless of
+// its order.
+type Nulls int
+
+// The positions of null values: before or after every other value.
+const (
+	NullsFirst Nulls = iota
+	NullsLast
+)
+
+func (n Nulls) String() string {
+	if n == NullsLast {
+		return "NULLS LAST"
+	}
+	return "NULLS FIRST"
+}
+
+// Column is a column of a composite index: the kind of its values, their
+// order and the position of its null values. Any column may hold null
+// values, encoded as nil elements. The zero values of Order and Nulls sort
+// like tuples, in ascending order with nulls first.
+type Column struct {
+	Name  string
+	Kind  tuple.Kind
+	Order Order
+	Nulls Nulls
+}
+
+func (c Column) String() string {
+	return fmt.Sprintf("%s %s %s %s", c.Name, c.Kind, c.Order, c.Nulls)
+}
+
+// Spec describes a composite index, such as an SQL index, as a sequence of
+// columns. Compile turns it into the Codec encoding and decoding its values.
+type Spec struct {
+	Columns []Column
+}
+
+// String returns the columns of the spec, such as
+// (name string ASC NULLS FIRST, age int DESC NULLS LAST).
+func (s Spec) String() string {
+	parts := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		parts[i] = c.String()
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// Compile returns the Codec of the spec, or an error if a column is unnamed
+// or named twice, or has an invalid kind, order or position of nulls.
+func (s Spec) Compile() (*Codec, error) {
+	c := &Codec{spec: s, schemas: make([]tuple.Schema, len(s.Columns))}
+	names := map[string]bool{}
+	for i, col := range s.Columns {
+		switch {
+		case col.Name == "":
+			return nil, fmt.Errorf("index: column %d has no name", i)
+		case names[col.Name]:
+			return nil, fmt.Errorf("index: column %s is declared twice", col.Name)
+		case col.Kind == tuple.KindNil:
+			return nil, fmt.Errorf("index: column %s has kind nil", col.Name)
+		case col.Order != Asc && col.Order != Desc:
+			return nil, fmt.Errorf("index: column %s has invalid order %d", col.Name, int(col.Order))
+		case col.Nulls != NullsFirst && col.Nulls != NullsLast:
+			return nil, fmt.Errorf("index: column %s has invalid nulls %d", col.Name, int(col.Nulls))
+		}
+		names[col.Name] = true
+		c.schemas[i] = tuple.Schema{Fields: []tuple.Field{{Name: col.Name, Kind: col.Kind}}}
+	}
+	return c, nil
+}
+
+// MustCompile is like Compile, but panics if the spec is invalid.
+func (s Spec) MustCompile() *Codec {
+	c, err := s.Compile()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Null markers encode null values: nullFirst sorts before, and nullLast
+// after, the encoding of any value in either order.
+const (
+	nullFirst = 0x00
+	nullLast  = 0xFF
+)
+
+// Codec encodes the values of a composite index into bytes which sort in the
+// order of its Spec, and decodes them back.
+//
+// The value of an ascending column is encoded as a tuple element, followed
+// by 0x00 for byte strings and strings: as a 0x00 byte is escaped as 0x00
+// 0xFF, the encoding of a string such as "a" is a prefix of the encoding of
+// "a\x00", and would not sort before it once followed by a null marker or by
+// a descending column. The value of a descending column
+// is encoded as the inverted bytes of the encoding of an ascending column. A
+// null value is encoded as 0x00 if nulls come first and as 0xFF if they come
+// last. Elements of types registered with typecode 0xFF cannot be encoded.
+type Codec struct {
+	spec    Spec
+	schemas []tuple.Schema
+}
+
+// Spec returns the spec the codec was compiled from.
+func (c *Codec) Spec() Spec {
+	return c.spec
+}
+
+// Append appends the encoding of the values of the leading columns of the
+// index to b, and returns the result. It returns an error if there are more
+// values than columns, or if a value does not match the kind of its column.
+func (c *Codec) Append(b []byte, values tuple.Tuple) ([]byte, error) {
+	if len(values) > len(c.spec.Columns) {
+		return nil, fmt.Errorf("index: expected at most %d values, got %d", len(c.spec.Columns), len(values))
+	}
+	for i, v := range values {
+		col := c.spec.Columns[i]
+		if v == nil {
+			if col.Nulls == NullsLast {
+				b = append(b, nullLast)
+			} else {
+				b = append(b, nullFirst)
+			}
+			continue
+		}
+
+		e, err := tuple.Tuple{v}.PackChecked()
+		if err != nil {
+			return nil, fmt.Errorf("index: column %s: %v", col.Name, err)
+		}
+		if err := c.schemas[i].ValidateKey(e); err != nil {
+			return nil, fmt.Errorf("index: %v", err)
+		}
+		if e[0] == nullLast {
+			return nil, fmt.Errorf("index: column %s: cannot encode typecode %02x", col.Name, e[0])
+		}
+
+		n := len(b)
+		b = append(b, e...)
+		if e[0] == 0x01 || e[0] == 0x02 {
+			b = append(b, 0x00)
+		}
+		if col.Order == Desc {
+			invert(b[n:])
+		}
+	}
+	return b, nil
+}
+
+// Encode returns the encoding of the values of every column of the index.
+func (c *Codec) Encode(values tuple.Tuple) ([]byte, error) {
+	if len(values) != len(c.spec.Columns) {
+		return nil, fmt.Errorf("index: expected %d values, got %d", len(c.spec.Columns), len(values))
+	}
+	return c.Append(nil, values)
+}
+
+// Decode decodes the values of every column of the index from the start of
+// b, and returns them followed by the remaining bytes of b.
+func (c *Codec) Decode(b []byte) (tuple.Tuple, []byte, error) {
+	values := make(tuple.Tuple, len(c.spec.Columns))
+	for i, col := range c.spec.Columns {
+		if len(b) == 0 {
+			return nil, nil, fmt.Errorf("index: column %s: encoding is truncated", col.Name)
+		}
+		switch {
+		case b[0] == nullFirst && col.Nulls == NullsFirst, b[0] == nullLast && col.Nulls == NullsLast:
+			b = b[1:]
+			continue
+		case b[0] == nullFirst || b[0] == nullLast:
+			return nil, nil, fmt.Errorf("index: column %s: unexpected null marker %02x", col.Name, b[0])
+		}
+
+		e := b
+		if col.Order == Desc {
+			e = append([]byte{}, b...)
+			invert(e)
+		}
+		t, err := tuple.UnpackWithSuffix(e, 1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("index: column %s: %v", col.Name, err)
+		}
+		n := len(e) - len(t[1].(tuple.RawSuffix))
+		if err := c.schemas[i].ValidateKey(e[:n]); err != nil {
+			return nil, nil, fmt.Errorf("index: %v", err)
+		}
+		if e[0] == 0x01 || e[0] == 0x02 {
+			if n == len(e) || e[n] != 0x00 {
+				return nil, nil, fmt.Errorf("index: column %s: missing terminator after %s", col.Name, col.Kind)
+			}
+			n++
+		}
+		values[i], b = t[0], b[n:]
+	}
+	return values, b, nil
+}
+
+// Entry returns the key of the index entry of a record in the subspace s:
+// the encoding of its values followed by its packed primary key.
+func (c *Codec) Entry(s subspace.Subspace, values, pk tuple.Tuple) (lex.Key, error) {
+	if len(values) != len(c.spec.Columns) {
+		return nil, fmt.Errorf("index: expected %d values, got %d", len(c.spec.Columns), len(values))
+	}
+	b, err := c.Append(append([]byte{}, s.Bytes()...), values)
+	if err != nil {
+		return nil, err
+	}
+	return lex.Key(append(b, pk.Pack()...)), nil
+}
+
+// ParseEntry decodes the key of an index entry in the subspace s into the
+// values and the primary key of the record.
+func (c *Codec) ParseEntry(s subspace.Subspace, key lex.KeyConvertible) (values, pk tuple.Tuple, err error) {
+	b, err := s.Strip(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	values, rest, err := c.Decode(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	pk, err = tuple.Unpack(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, pk, nil
+}
+
+// Range returns the range of the entries in the subspace s whose leading
+// values equal the given values (every entry for no values).
+func (c *Codec) Range(s subspace.Subspace, values ...tuple.Element) (lex.KeyRange, error) {
+	b, err := c.Append(append([]byte{}, s.Bytes()...), values)
+	if err != nil {
+		return lex.KeyRange{}, err
+	}
+	if len(b) == 0 {
+		return lex.AppKeys(), nil
+	}
+	end, ok := lex.PrefixEnd(b)
+	if !ok {
+		return lex.KeyRange{}, fmt.Errorf("index: range of %x is unbounded", b)
+	}
+	return lex.KeyRange{Begin: lex.Key(b), End: end}, nil
+}
+
+func invert(b []byte) {
+	for i := range b {
+		b[i] = ^b[i]
+	}
+}
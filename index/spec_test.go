@@ -0,0 +1,31 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+func TestRangeHighBytes(t *testing.T) {
+	// A descending column encodes small values as bytes above 0x80, none of
+	// which is 0xFF.
+	c := Spec{Columns: []Column{{Name: "n", Kind: tuple.KindInt, Order: Desc}}}.MustCompile()
+	r, err := c.Range(subspace.FromBytes(nil), int64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, e := r.LexRangeKeys()
+	begin, end := b.LexKey(), e.LexKey()
+	if lex.Compare(begin, end) >= 0 {
+		t.Fatalf("empty range [%x, %x)", begin, end)
+	}
+	k, err := c.Append(nil, tuple.Tuple{int64(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lex.Compare(lex.Key(k), begin) < 0 || lex.Compare(lex.Key(k), end) >= 0 {
+		t.Fatalf("key %x is not in [%x, %x)", k, begin, end)
+	}
+}
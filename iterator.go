@@ -0,0 +1,177 @@
+package lex
+
+// StreamingMode controls how an Iterator returned by Iterate batches the
+// range reads of a store which does not iterate natively. The default
+// StreamingModeIterator suits most scans, which may stop early.
+type StreamingMode int
+
+const (
+	// StreamingModeIterator reads batches of growing size, starting small
+	// for scans which stop after a few keys and growing for long scans.
+	StreamingModeIterator StreamingMode = iota
+
+	// StreamingModeWantAll reads the whole range (up to the limit) at once,
+	// for scans which are known to consume every key.
+	StreamingModeWantAll
+
+	// StreamingModeExact reads exactly the number of keys of the limit at
+	// once. The limit must be set.
+	StreamingModeExact
+
+	// StreamingModeSmall, StreamingModeMedium and StreamingModeLarge read
+	// batches of a fixed size.
+	StreamingModeSmall
+	StreamingModeMedium
+	StreamingModeLarge
+)
+
+// Batch sizes of the streaming modes.
+const (
+	smallBatch  = 16
+	mediumBatch = 256
+	largeBatch  = 4096
+)
+
+// batchSize returns the size of the nth batch read in the mode, or 0 to read
+// every remaining key.
+func (m StreamingMode) batchSize(n int) int {
+	switch m {
+	case StreamingModeWantAll, StreamingModeExact:
+		return 0
+	case StreamingModeSmall:
+		return smallBatch
+	case StreamingModeMedium:
+		return mediumBatch
+	case StreamingModeLarge:
+		return largeBatch
+	}
+	size := smallBatch
+	for ; n > 0 && size < largeBatch; n-- {
+		size *= 2
+	}
+	return size
+}
+
+// Iterator reads the key-value pairs of a range one at a time. Next must be
+// called before reading the first pair, and Close once done with the
+// iterator, which may hold resources of the store (such as a snapshot).
+//
+//	it := lex.Iterate(store, r, lex.RangeOptions{})
+//	defer it.Close()
+//	for it.Next() {
+//		process(it.Key(), it.Value())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator interface {
+	// Next advances the iterator to the next pair, and returns false once the
+	// range (or the limit) is exhausted or an error occurred.
+	Next() bool
+
+	// Key and Value return the current pair. They may be retained by the
+	// caller.
+	Key() Key
+	Value() []byte
+
+	// Err returns the error which stopped the iterator, if any.
+	Err() error
+
+	// Close releases the resources of the iterator, and returns Err.
+	Close() error
+}
+
+// IterableStore is implemented by stores which iterate over ranges natively,
+// such as engines with cursors. NewIterator follows the semantics of
+// GetRange; the Mode of the options may be ignored.
+type IterableStore interface {
+	KVStore
+	NewIterator(r Range, options RangeOptions) Iterator
+}
+
+// Iterate returns an Iterator over the pairs of the range in the store, ordered
+// and limited as described by options. If the store implements IterableStore,
+// its own iterator is returned; otherwise the range is read with GetRange in
+// batches whose size depends on the Mode of the options.
+//
+// Batched iterators do not read a consistent snapshot of the store: keys set
+// or cleared during the iteration may or may not be returned.
+func Iterate(store KVStore, r Range, options RangeOptions) Iterator {
+	if is, ok := store.(IterableStore); ok {
+		return is.NewIterator(r, options)
+	}
+	begin, end := r.LexRangeKeySelectors()
+	return &batchIterator{store: store, begin: begin, end: end, o: options, left: options.Limit}
+}
+
+type batchIterator struct {
+	store      KVStore
+	begin, end Selectable
+	o          RangeOptions
+
+	batch []KeyValue
+	n     int  // number of batches read
+	left  int  // number of pairs left to the limit, if any
+	done  bool // true once the last batch was read
+	cur   KeyValue
+	err   error
+}
+
+func (it *batchIterator) Next() bool {
+	if len(it.batch) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.read()
+		if len(it.batch) == 0 {
+			return false
+		}
+	}
+	it.cur, it.batch = it.batch[0], it.batch[1:]
+	return true
+}
+
+// read reads the next batch, and moves the bounds of the range past it.
+func (it *batchIterator) read() {
+	size := it.o.Mode.batchSize(it.n)
+	if it.o.Limit > 0 && (size == 0 || it.left < size) {
+		size = it.left
+	}
+	it.n++
+
+	kvs, err := it.store.GetRange(SelectorRange{it.begin, it.end}, RangeOptions{Limit: size, Reverse: it.o.Reverse})
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.batch = kvs
+	it.left -= len(kvs)
+	if size == 0 || len(kvs) < size || (it.o.Limit > 0 && it.left == 0) {
+		it.done = true
+		return
+	}
+
+	last := kvs[len(kvs)-1].Key
+	if it.o.Reverse {
+		it.end = FirstGreaterOrEqual(last)
+	} else {
+		it.begin = FirstGreaterThan(last)
+	}
+}
+
+func (it *batchIterator) Key() Key {
+	return it.cur.Key
+}
+
+func (it *batchIterator) Value() []byte {
+	return it.cur.Value
+}
+
+func (it *batchIterator) Err() error {
+	return it.err
+}
+
+func (it *batchIterator) Close() error {
+	it.batch, it.done = nil, true
+	return it.err
+}
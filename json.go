@@ -0,0 +1,101 @@
+package lex
+
+import "encoding/json"
+
+// Keys are represented in JSON by their printable form (see Key.MarshalText),
+// and a nil KeyConvertible or Selectable by null. Interface fields decode as
+// Key and KeySelector.
+
+type jsonSelector struct {
+	Key     *Key `json:"key"`
+	OrEqual bool `json:"orEqual"`
+	Offset  int  `json:"offset"`
+}
+
+type jsonKeyRange struct {
+	Begin *Key `json:"begin"`
+	End   *Key `json:"end"`
+}
+
+type jsonSelectorRange struct {
+	Begin *KeySelector `json:"begin"`
+	End   *KeySelector `json:"end"`
+}
+
+func keyOf(k KeyConvertible) *Key {
+	if k == nil {
+		return nil
+	}
+	r := k.LexKey()
+	return &r
+}
+
+func convertibleOf(k *Key) KeyConvertible {
+	if k == nil {
+		return nil
+	}
+	return *k
+}
+
+func selectorOf(s Selectable) *KeySelector {
+	if s == nil {
+		return nil
+	}
+	r := s.LexKeySelector()
+	return &r
+}
+
+func selectableOf(s *KeySelector) Selectable {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// MarshalJSON implements json.Marshaler, writing the key selector as an
+// object such as {"key": "\\x02a\\x00", "orEqual": false, "offset": 1}.
+func (ks KeySelector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonSelector{keyOf(ks.Key), ks.OrEqual, ks.Offset})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (ks *KeySelector) UnmarshalJSON(b []byte) error {
+	var j jsonSelector
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*ks = KeySelector{convertibleOf(j.Key), j.OrEqual, j.Offset}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing the range as an object with
+// the keys begin and end.
+func (kr KeyRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonKeyRange{keyOf(kr.Begin), keyOf(kr.End)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (kr *KeyRange) UnmarshalJSON(b []byte) error {
+	var j jsonKeyRange
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*kr = KeyRange{convertibleOf(j.Begin), convertibleOf(j.End)}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing the range as an object with
+// the key selectors begin and end.
+func (sr SelectorRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonSelectorRange{selectorOf(sr.Begin), selectorOf(sr.End)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (sr *SelectorRange) UnmarshalJSON(b []byte) error {
+	var j jsonSelectorRange
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*sr = SelectorRange{selectableOf(j.Begin), selectableOf(j.End)}
+	return nil
+}
@@ -51,3 +51,45 @@ func FirstGreaterThan(key KeyConvertible) KeySelector {
 func FirstGreaterOrEqual(key KeyConvertible) KeySelector {
 	return KeySelector{key, false, 1}
 }
+
+// Normalize returns the selector as one of the four canonical selectors of its
+// key, constructed by LastLessThan, LastLessOrEqual, FirstGreaterThan and
+// FirstGreaterOrEqual, and the residual number of keys to move from the key
+// the canonical selector resolves to: forward if positive and backward if
+// negative. Selectors with a positive offset are forward and normalize to
+// FirstGreaterThan or FirstGreaterOrEqual; the others are backward and
+// normalize to LastLessThan or LastLessOrEqual. A nil key normalizes to
+// MinKey.
+//
+// Stores resolve a selector by seeking its canonical selector, then moving
+// the residual number of keys, stopping before the first key or past the
+// last one.
+func (ks KeySelector) Normalize() (KeySelector, int) {
+	var k KeyConvertible = MinKey
+	if ks.Key != nil {
+		k = ks.Key.LexKey()
+	}
+	if ks.Offset > 0 {
+		return KeySelector{k, ks.OrEqual, 1}, ks.Offset - 1
+	}
+	return KeySelector{k, ks.OrEqual, 0}, ks.Offset
+}
+
+// Forward returns true if the selector has a positive offset, and resolves to
+// a key after its key or to its key.
+func (ks KeySelector) Forward() bool {
+	return ks.Offset > 0
+}
+
+// BeforeAll returns true if the selector resolves before the first key of any
+// store, to MinKey: a backward selector of MinKey, which no key precedes.
+func (ks KeySelector) BeforeAll() bool {
+	return !ks.Forward() && IsMinKey(ks.Key)
+}
+
+// AfterAll returns true if the selector resolves past the last key of any
+// store of application keys, to MaxAppKey: a forward selector of a key which
+// is not an application key, which no application key follows.
+func (ks KeySelector) AfterAll() bool {
+	return ks.Forward() && !IsAppKey(ks.Key)
+}
@@ -0,0 +1,304 @@
+// Package keystats analyzes the shape of a key space: it consumes a stream of
+// keys and reports how they are distributed among prefixes and subspaces, how
+// many tuple elements they hold and how large they are, for capacity planning
+// and for spotting layout mistakes such as a prefix holding most keys.
+package keystats
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/kvutil"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Defaults of the Options.
+const (
+	DefaultDepth       = 2
+	DefaultTop         = 10
+	DefaultMaxPrefixes = 10000
+)
+
+// Options configure an Analyzer.
+type Options struct {
+	// Depth is the number of leading tuple elements of the prefixes of the
+	// histogram. Keys with fewer elements (or which are not well-formed
+	// tuples) count as prefixes of their own. It defaults to DefaultDepth.
+	Depth int
+
+	// Top is the number of hot prefixes reported. It defaults to
+	// DefaultTop.
+	Top int
+
+	// MaxPrefixes bounds the number of distinct prefixes tracked, and so the
+	// memory used by an Analyzer. Keys of further prefixes are only counted
+	// in Report.OtherKeys. It defaults to DefaultMaxPrefixes.
+	MaxPrefixes int
+
+	// Subspaces names the subspaces whose keys are reported separately. A
+	// key counts towards the most specific subspace containing it.
+	Subspaces map[string]subspace.Subspace
+}
+
+func (o Options) depth() int {
+	if o.Depth > 0 {
+		return o.Depth
+	}
+	return DefaultDepth
+}
+
+func (o Options) top() int {
+	if o.Top > 0 {
+		return o.Top
+	}
+	return DefaultTop
+}
+
+func (o Options) maxPrefixes() int {
+	if o.MaxPrefixes > 0 {
+		return o.MaxPrefixes
+	}
+	return DefaultMaxPrefixes
+}
+
+// PrefixStats describes the keys starting with a prefix.
+type PrefixStats struct {
+	Prefix lex.Key `json:"prefix"`
+	Keys   int     `json:"keys"`
+	Bytes  int     `json:"bytes"`
+
+	// Share is the fraction of all keys starting with the prefix.
+	Share float64 `json:"share"`
+}
+
+// SubspaceStats describes the keys of a named subspace.
+type SubspaceStats struct {
+	Name       string  `json:"name"`
+	Prefix     lex.Key `json:"prefix"`
+	Keys       int     `json:"keys"`
+	Bytes      int     `json:"bytes"`
+	AvgKeySize float64 `json:"avg_key_size"`
+}
+
+// DepthStats counts the keys holding a number of tuple elements. A Depth of
+// -1 counts the keys which are not well-formed tuples.
+type DepthStats struct {
+	Depth int `json:"depth"`
+	Keys  int `json:"keys"`
+}
+
+// Report is the result of an analysis. It is designed to be serialized with
+// encoding/json.
+type Report struct {
+	Keys       int     `json:"keys"`
+	Bytes      int     `json:"bytes"`
+	MinKeySize int     `json:"min_key_size"`
+	MaxKeySize int     `json:"max_key_size"`
+	AvgKeySize float64 `json:"avg_key_size"`
+
+	// Depths is the distribution of the number of tuple elements of keys,
+	// ordered by depth.
+	Depths []DepthStats `json:"depths"`
+
+	// Prefixes is the histogram of prefixes, ordered by prefix, and Hot
+	// lists the prefixes holding the most keys first.
+	Prefixes []PrefixStats `json:"prefixes"`
+	Hot      []PrefixStats `json:"hot"`
+
+	// OtherKeys counts the keys whose prefixes were not tracked, once
+	// Options.MaxPrefixes prefixes were.
+	OtherKeys int `json:"other_keys"`
+
+	// Subspaces describes the named subspaces, ordered by prefix. Unmatched
+	// counts the keys in none of them.
+	Subspaces []SubspaceStats `json:"subspaces"`
+	Unmatched int             `json:"unmatched"`
+}
+
+// Analyzer accumulates the statistics of a stream of keys. An Analyzer is not
+// safe for concurrent use.
+type Analyzer struct {
+	o Options
+	r Report
+
+	depths    map[int]int
+	prefixes  map[string]*PrefixStats
+	subspaces []*SubspaceStats
+}
+
+// New returns an Analyzer.
+func New(o Options) *Analyzer {
+	a := &Analyzer{
+		o:        o,
+		depths:   map[int]int{},
+		prefixes: map[string]*PrefixStats{},
+	}
+	for name, s := range o.Subspaces {
+		a.subspaces = append(a.subspaces, &SubspaceStats{Name: name, Prefix: lex.Key(s.Bytes())})
+	}
+	sort.Slice(a.subspaces, func(i, j int) bool {
+		return bytes.Compare(a.subspaces[i].Prefix, a.subspaces[j].Prefix) < 0
+	})
+	return a
+}
+
+// Add records a key.
+func (a *Analyzer) Add(k lex.KeyConvertible) {
+	key := k.LexKey()
+	n := len(key)
+	if a.r.Keys == 0 || n < a.r.MinKeySize {
+		a.r.MinKeySize = n
+	}
+	if n > a.r.MaxKeySize {
+		a.r.MaxKeySize = n
+	}
+	a.r.Keys++
+	a.r.Bytes += n
+
+	prefix := key
+	spans, err := tuple.Spans(key)
+	if err != nil {
+		a.depths[-1]++
+	} else {
+		a.depths[len(spans)]++
+		if d := a.o.depth(); len(spans) >= d {
+			prefix = key[:spans[d-1].End]
+		}
+	}
+
+	if p, ok := a.prefixes[string(prefix)]; ok {
+		p.Keys++
+		p.Bytes += n
+	} else if len(a.prefixes) < a.o.maxPrefixes() {
+		a.prefixes[string(prefix)] = &PrefixStats{Prefix: append(lex.Key{}, prefix...), Keys: 1, Bytes: n}
+	} else {
+		a.r.OtherKeys++
+	}
+
+	if s := a.subspace(key); s != nil {
+		s.Keys++
+		s.Bytes += n
+	} else {
+		a.r.Unmatched++
+	}
+}
+
+// subspace returns the most specific named subspace containing the key.
+func (a *Analyzer) subspace(k lex.Key) *SubspaceStats {
+	var found *SubspaceStats
+	for _, s := range a.subspaces {
+		if bytes.HasPrefix(k, s.Prefix) && (found == nil || len(s.Prefix) > len(found.Prefix)) {
+			found = s
+		}
+	}
+	return found
+}
+
+// Report returns the statistics of the keys recorded so far.
+func (a *Analyzer) Report() *Report {
+	r := a.r
+	if r.Keys > 0 {
+		r.AvgKeySize = float64(r.Bytes) / float64(r.Keys)
+	}
+
+	r.Depths = make([]DepthStats, 0, len(a.depths))
+	for d, n := range a.depths {
+		r.Depths = append(r.Depths, DepthStats{d, n})
+	}
+	sort.Slice(r.Depths, func(i, j int) bool { return r.Depths[i].Depth < r.Depths[j].Depth })
+
+	r.Prefixes = make([]PrefixStats, 0, len(a.prefixes))
+	for _, p := range a.prefixes {
+		ps := *p
+		ps.Share = float64(ps.Keys) / float64(r.Keys)
+		r.Prefixes = append(r.Prefixes, ps)
+	}
+	sort.Slice(r.Prefixes, func(i, j int) bool { return bytes.Compare(r.Prefixes[i].Prefix, r.Prefixes[j].Prefix) < 0 })
+
+	r.Hot = append([]PrefixStats{}, r.Prefixes...)
+	sort.SliceStable(r.Hot, func(i, j int) bool { return r.Hot[i].Keys > r.Hot[j].Keys })
+	if len(r.Hot) > a.o.top() {
+		r.Hot = r.Hot[:a.o.top()]
+	}
+
+	r.Subspaces = make([]SubspaceStats, len(a.subspaces))
+	for i, s := range a.subspaces {
+		r.Subspaces[i] = *s
+		if s.Keys > 0 {
+			r.Subspaces[i].AvgKeySize = float64(s.Bytes) / float64(s.Keys)
+		}
+	}
+	return &r
+}
+
+// batchSize is the number of keys read at once by Analyze.
+const batchSize = 1000
+
+// Analyze reads every key in the range and returns its statistics.
+func Analyze(store lex.KVStore, er lex.ExactRange, o Options) (*Report, error) {
+	a := New(o)
+	b, e := er.LexRangeKeys()
+	r := lex.SelectorRange{Begin: lex.FirstGreaterOrEqual(b), End: lex.FirstGreaterOrEqual(e)}
+	for {
+		kvs, err := store.GetRange(r, lex.RangeOptions{Limit: batchSize})
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range kvs {
+			a.Add(kv.Key)
+		}
+		if len(kvs) < batchSize {
+			return a.Report(), nil
+		}
+		r.Begin = lex.FirstGreaterThan(kvs[len(kvs)-1].Key)
+	}
+}
+
+// WriteTo writes a human-readable summary of the report to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d keys, %d bytes, key size %d to %d (%.1f on average)\n", r.Keys, r.Bytes, r.MinKeySize, r.MaxKeySize, r.AvgKeySize)
+
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nDEPTH\tKEYS")
+	for _, d := range r.Depths {
+		depth := fmt.Sprint(d.Depth)
+		if d.Depth < 0 {
+			depth = "not a tuple"
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", depth, d.Keys)
+	}
+
+	fmt.Fprintln(tw, "\nHOT PREFIX\tKEYS\tSHARE\tBYTES")
+	for _, p := range r.Hot {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%d\n", kvutil.FormatKey(p.Prefix), p.Keys, 100*p.Share, p.Bytes)
+	}
+	if r.OtherKeys > 0 {
+		fmt.Fprintf(tw, "(untracked)\t%d\t\t\n", r.OtherKeys)
+	}
+
+	if len(r.Subspaces) > 0 {
+		fmt.Fprintln(tw, "\nSUBSPACE\tKEYS\tBYTES\tAVG KEY SIZE")
+		for _, s := range r.Subspaces {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\n", s.Name, s.Keys, s.Bytes, s.AvgKeySize)
+		}
+		fmt.Fprintf(tw, "(unmatched)\t%d\t\t\n", r.Unmatched)
+	}
+	tw.Flush()
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// String returns a human-readable summary of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+	r.WriteTo(&sb)
+	return sb.String()
+}
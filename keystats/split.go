@@ -0,0 +1,106 @@
+package keystats
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Splits returns up to n-1 split keys dividing the keyspace of a sample of
+// keys into n shards holding about as many keys each, such as to run a
+// backfill in parallel or to pre-split a target store. The sample should be
+// drawn uniformly from the keys, and hold many more keys than n; it is not
+// modified. The split keys are sorted, and each of them is a key of the
+// sample, which begins a shard. Fewer split keys are returned if the sample
+// holds fewer than n distinct keys.
+func Splits(sample []lex.Key, n int) []lex.Key {
+	keys := append([]lex.Key{}, sample...)
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+	var ws []weighted
+	for _, k := range keys {
+		if len(ws) > 0 && bytes.Equal(ws[len(ws)-1].key, k) {
+			ws[len(ws)-1].weight++
+			continue
+		}
+		ws = append(ws, weighted{k, 1})
+	}
+	return split(ws, n)
+}
+
+// SplitsByPrefix is like Splits, but balances the keys counted by a histogram
+// of prefixes, such as Report.Prefixes: the split keys are prefixes of the
+// histogram. The keys of a prefix all fall in the same shard, so that a
+// prefix holding more than a shard worth of keys yields a larger shard;
+// analyze the keys with a greater Options.Depth to split it.
+func SplitsByPrefix(prefixes []PrefixStats, n int) []lex.Key {
+	ws := make([]weighted, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p.Keys > 0 {
+			ws = append(ws, weighted{p.Prefix, p.Keys})
+		}
+	}
+	sort.Slice(ws, func(i, j int) bool { return bytes.Compare(ws[i].key, ws[j].key) < 0 })
+	return split(ws, n)
+}
+
+// Splits returns up to n-1 split keys balancing the keys counted by the
+// histogram of prefixes of the report (see SplitsByPrefix).
+func (r *Report) Splits(n int) []lex.Key {
+	return SplitsByPrefix(r.Prefixes, n)
+}
+
+// Shards returns the ranges into which the split keys divide the range er:
+// the first shard begins with er and the last one ends with it. Split keys
+// outside of er, or equal to the previous one, are ignored.
+func Shards(er lex.ExactRange, splits []lex.Key) []lex.KeyRange {
+	b, e := er.LexRangeKeys()
+	begin, end := b.LexKey(), e.LexKey()
+
+	var shards []lex.KeyRange
+	for _, k := range splits {
+		if bytes.Compare(k, begin) <= 0 || bytes.Compare(k, end) >= 0 {
+			continue
+		}
+		shards = append(shards, lex.KeyRange{Begin: begin, End: k})
+		begin = k
+	}
+	return append(shards, lex.KeyRange{Begin: begin, End: end})
+}
+
+// weighted is a key standing for weight keys of the keyspace.
+type weighted struct {
+	key    lex.Key
+	weight int
+}
+
+// split returns the keys of ws, sorted by key, which begin n shards of about
+// the same total weight: a shard ends before the key whose beginning is the
+// nearest to the end of the shard.
+func split(ws []weighted, n int) []lex.Key {
+	total := 0
+	for _, w := range ws {
+		total += w.weight
+	}
+
+	var splits []lex.Key
+	seen, shard := 0, 1
+	for _, w := range ws {
+		if shard >= n {
+			break
+		}
+		// The shard ends before the key if the weight before the key is
+		// nearer to shard*total/n than the weight up to the key; a heavy
+		// key may end several shards at once.
+		next := seen + w.weight
+		if seen > 0 && 2*shard*total <= n*(seen+next) {
+			splits = append(splits, w.key)
+			for shard < n && 2*shard*total <= n*(seen+next) {
+				shard++
+			}
+		}
+		seen = next
+	}
+	return splits
+}
@@ -0,0 +1,219 @@
+package kvutil
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// DefaultProbes is the number of random descents performed by
+// EstimatePrefixes when EstimateOptions.Probes is not set.
+const DefaultProbes = 32
+
+// DefaultExactLimit is the number of prefixes EstimatePrefixes counts exactly
+// before sampling when EstimateOptions.ExactLimit is not set.
+const DefaultExactLimit = 1000
+
+// EstimateOptions control the sampling performed by EstimatePrefixes.
+type EstimateOptions struct {
+	// Probes is the number of random descents from which the number of
+	// prefixes is extrapolated.
+	Probes int
+
+	// ExactLimit is the number of prefixes counted one by one before
+	// resorting to sampling. Subspaces holding fewer prefixes are counted
+	// exactly.
+	ExactLimit int
+
+	// Rand is the source of the random descents. A nil Rand uses the default
+	// source of math/rand.
+	Rand *rand.Rand
+}
+
+func (o EstimateOptions) probes() int {
+	if o.Probes > 1 {
+		return o.Probes
+	}
+	return DefaultProbes
+}
+
+func (o EstimateOptions) exactLimit() int {
+	if o.ExactLimit > 0 {
+		return o.ExactLimit
+	}
+	return DefaultExactLimit
+}
+
+func (o EstimateOptions) intn(n int) int {
+	if o.Rand != nil {
+		return o.Rand.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+// Estimate is the estimated number of distinct prefixes in a subspace.
+type Estimate struct {
+	Depth int     `json:"depth"`
+	Count float64 `json:"count"`
+
+	// Low and High bound the 95% confidence interval of Count. They are
+	// equal to Count when Exact is set.
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+
+	// Exact indicates that every prefix was read, and Count is exact.
+	Exact bool `json:"exact"`
+
+	// Reads is the number of key selectors resolved to compute the estimate.
+	Reads int `json:"reads"`
+}
+
+// EstimatePrefixes estimates the number of distinct prefixes of depth tuple
+// elements among the keys in the subspace, without reading every key. Keys
+// with fewer than depth elements (or which are not well-formed tuples) count
+// as prefixes of their own.
+//
+// Subspaces with few prefixes are counted exactly. Otherwise, the keys are
+// seen as a trie of bytes whose leaves are the prefixes, and every probe
+// descends it along a random path, multiplying the number of children met at
+// each step (Knuth's estimator of the size of a tree). Every probe is an
+// unbiased estimate of the number of prefixes; the estimate is their mean.
+// Reading the children of a node takes one read per child, so a probe costs
+// at most 256 reads per byte of the prefixes, and usually far less.
+func EstimatePrefixes(store lex.KVStore, s subspace.Subspace, depth int, o EstimateOptions) (Estimate, error) {
+	e := Estimate{Depth: depth}
+	p := prober{store: store, s: s, depth: depth, reads: &e.Reads}
+
+	n, err := p.count(o.exactLimit())
+	if err != nil {
+		return e, err
+	}
+	if n < o.exactLimit() {
+		e.Count, e.Low, e.High, e.Exact = float64(n), float64(n), float64(n), true
+		return e, nil
+	}
+
+	var sum, sq float64
+	for i := 0; i < o.probes(); i++ {
+		x, err := p.descend(o)
+		if err != nil {
+			return e, err
+		}
+		sum += x
+		sq += x * x
+	}
+
+	k := float64(o.probes())
+	mean := sum / k
+	se := math.Sqrt(math.Max(sq/k-mean*mean, 0) / (k - 1))
+
+	e.Count = mean
+	e.Low = math.Max(mean-1.96*se, float64(n))
+	e.High = math.Max(mean+1.96*se, e.Low)
+	return e, nil
+}
+
+// prober reads distinct prefixes of a subspace.
+type prober struct {
+	store lex.KVStore
+	s     subspace.Subspace
+	depth int
+	reads *int
+}
+
+func (p prober) get(sel lex.Selectable) (lex.Key, error) {
+	*p.reads++
+	return p.store.GetKey(sel)
+}
+
+// prefix returns the prefix of depth elements of a key in the subspace.
+func (p prober) prefix(k lex.Key) lex.Key {
+	spans, err := tuple.Spans(k[len(p.s.Bytes()):])
+	if err != nil || len(spans) < p.depth || p.depth == 0 {
+		return k
+	}
+	return k[:len(p.s.Bytes())+spans[p.depth-1].End]
+}
+
+// count reads up to limit distinct prefixes, returning how many it read.
+func (p prober) count(limit int) (int, error) {
+	b, e := p.s.LexRangeKeys()
+	from, end := b.LexKey(), e.LexKey()
+
+	for n := 0; n < limit; n++ {
+		k, err := p.get(lex.FirstGreaterOrEqual(from))
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Compare(k, end) >= 0 {
+			return n, nil
+		}
+		from = append(append(lex.Key{}, p.prefix(k)...), 0xFF)
+	}
+	return limit, nil
+}
+
+// descend follows a random path from the root of the trie of the keys down
+// to a prefix, and returns the product of the number of children of the
+// nodes on the path.
+func (p prober) descend(o EstimateOptions) (float64, error) {
+	b, e := p.s.LexRangeKeys()
+	node, from, to := p.s.Bytes(), b.LexKey(), e.LexKey()
+	est := 1.0
+
+	for {
+		first, err := p.get(lex.FirstGreaterOrEqual(from))
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Compare(first, to) >= 0 {
+			return 0, nil
+		}
+		last, err := p.get(lex.LastLessThan(to))
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(p.prefix(first), p.prefix(last)) {
+			return est, nil
+		}
+
+		// Children are the distinct bytes following the node, or -1 for a
+		// key equal to the node itself (which is a prefix of its own).
+		var children []int
+		for k := first; bytes.Compare(k, to) < 0; {
+			if len(k) == len(node) {
+				children = append(children, -1)
+				k, err = p.get(lex.FirstGreaterThan(k))
+			} else {
+				c := k[len(node)]
+				children = append(children, int(c))
+				if c == 0xFF {
+					break
+				}
+				k, err = p.get(lex.FirstGreaterOrEqual(lex.Key(append(append([]byte{}, node...), c+1))))
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		est *= float64(len(children))
+		c := children[o.intn(len(children))]
+		if c < 0 {
+			return est, nil
+		}
+		node = append(append([]byte{}, node...), byte(c))
+		from, to = node, strinc(node)
+	}
+}
+
+// strinc returns the first key greater than every key starting with b, which
+// must not consist only of 0xFF bytes.
+func strinc(b []byte) lex.Key {
+	r, _ := lex.PrefixEnd(b)
+	return r
+}
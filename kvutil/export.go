@@ -0,0 +1,40 @@
+package kvutil
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/extsort"
+)
+
+// Export writes every key-value pair in the range to w, in ascending order
+// and in the framed format of extsort, so that the pairs can be read back
+// with an extsort.Reader and loaded into another store with Load. An export
+// cancelled through the context of the options can be resumed after the last
+// key reported to its Progress, by exporting the rest of the range to
+// another file.
+//
+// In dry-run mode, the pairs are read and counted, but nothing is written to
+// w.
+func Export(store lex.KVStore, r lex.ExactRange, w io.Writer, o Options) (*Report, error) {
+	rep := newReport("export", o)
+	rep.startRange(r)
+
+	bw := bufio.NewWriter(w)
+	err := scan(store, r, rep, o, func(kvs []lex.KeyValue) error {
+		for _, kv := range kvs {
+			rep.add(kv, o.samples())
+			if o.DryRun {
+				continue
+			}
+			if err := extsort.Write(bw, kv); err != nil {
+				return err
+			}
+		}
+		// Flush every batch, so that the pairs reported to the Progress
+		// were written.
+		return bw.Flush()
+	})
+	return rep, err
+}
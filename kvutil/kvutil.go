@@ -0,0 +1,260 @@
+// Package kvutil provides bulk utilities, such as clearing, copying, exporting
+// and loading ranges, backfilling indexes and sweeping expired keys, which
+// operate on any lex.KVStore.
+//
+// Every mutating utility accepts Options and returns a Report describing what
+// was (or, in dry-run mode, would have been) touched, so that operators can
+// review destructive operations before executing them. Long-running
+// utilities can be monitored with a Progress and stopped by cancelling a
+// context.
+package kvutil
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// DefaultBatchSize is the number of keys read per range read when
+// Options.BatchSize is not set.
+const DefaultBatchSize = 1000
+
+// DefaultSamples is the number of sample keys recorded in a Report when
+// Options.Samples is not set.
+const DefaultSamples = 10
+
+// Options control the behavior of the bulk utilities. The default zero-value
+// of Options performs the operation with default batching.
+type Options struct {
+	// DryRun indicates that the utility should only read the affected keys
+	// and produce a Report, without writing anything.
+	DryRun bool
+
+	// BatchSize is the number of keys read per range read.
+	BatchSize int
+
+	// Samples is the number of decoded sample keys recorded in the Report. A
+	// negative value disables sampling.
+	Samples int
+
+	// Context, if set, stops the utility between two batches once it is
+	// done. The utility then returns the error of the context, along with
+	// the Report of the keys processed so far.
+	Context context.Context
+
+	// Progress, if set, receives the status of the utility after every
+	// batch.
+	Progress Progress
+}
+
+func (o Options) batchSize() int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+func (o Options) samples() int {
+	switch {
+	case o.Samples < 0:
+		return 0
+	case o.Samples == 0:
+		return DefaultSamples
+	}
+	return o.Samples
+}
+
+// RangeReport describes a single range touched by a utility.
+type RangeReport struct {
+	Begin lex.Key `json:"begin"`
+	End   lex.Key `json:"end"`
+	Keys  int     `json:"keys"`
+	Bytes int     `json:"bytes"`
+}
+
+// Report describes the keys touched by a bulk utility. Keys and Bytes count
+// the key-value pairs read; as the store may change while a utility runs (or
+// between a dry run and the real one), they are estimates of what a later run
+// would touch.
+type Report struct {
+	Operation string        `json:"operation"`
+	DryRun    bool          `json:"dry_run"`
+	Ranges    []RangeReport `json:"ranges"`
+	Keys      int           `json:"keys"`
+	Bytes     int           `json:"bytes"`
+	Samples   []string      `json:"samples"`
+}
+
+func newReport(op string, o Options) *Report {
+	return &Report{Operation: op, DryRun: o.DryRun}
+}
+
+// add records a key-value pair read from the range most recently started with
+// startRange.
+func (r *Report) add(kv lex.KeyValue, samples int) {
+	n := len(kv.Key) + len(kv.Value)
+	rr := &r.Ranges[len(r.Ranges)-1]
+	rr.Keys++
+	rr.Bytes += n
+	r.Keys++
+	r.Bytes += n
+	if len(r.Samples) < samples {
+		r.Samples = append(r.Samples, FormatKey(kv.Key))
+	}
+}
+
+func (r *Report) startRange(er lex.ExactRange) {
+	b, e := er.LexRangeKeys()
+	r.Ranges = append(r.Ranges, RangeReport{Begin: b.LexKey(), End: e.LexKey()})
+}
+
+// String returns a human-readable summary of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+	mode := ""
+	if r.DryRun {
+		mode = " (dry run)"
+	}
+	fmt.Fprintf(&sb, "%s%s: %d keys, %d bytes in %d ranges\n", r.Operation, mode, r.Keys, r.Bytes, len(r.Ranges))
+	for _, rr := range r.Ranges {
+		fmt.Fprintf(&sb, "  [%s, %s): %d keys, %d bytes\n", FormatKey(rr.Begin), FormatKey(rr.End), rr.Keys, rr.Bytes)
+	}
+	for _, s := range r.Samples {
+		fmt.Fprintf(&sb, "  sample %s\n", s)
+	}
+	return sb.String()
+}
+
+// FormatKey returns a printable representation of a key: the decoded tuple if
+// the key is a well-formed tuple, and the quoted bytes otherwise.
+func FormatKey(k lex.Key) string {
+	t, err := tuple.Unpack(k)
+	if err != nil || len(t) == 0 {
+		return strconv.Quote(string(k))
+	}
+
+	parts := make([]string, len(t))
+	for i, el := range t {
+		parts[i] = formatElement(el)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func formatElement(el tuple.Element) string {
+	switch el := el.(type) {
+	case []byte:
+		return "b" + strconv.Quote(string(el))
+	case string:
+		return strconv.Quote(el)
+	}
+	return fmt.Sprint(el)
+}
+
+// scan reads every key-value pair in the range in batches, calling fn with
+// each batch and then reporting progress, until the context of the options
+// is cancelled.
+func scan(store lex.KVStore, er lex.ExactRange, rep *Report, o Options, fn func([]lex.KeyValue) error) error {
+	b, e := er.LexRangeKeys()
+	r := lex.SelectorRange{Begin: lex.FirstGreaterOrEqual(b), End: lex.FirstGreaterOrEqual(e)}
+	t := o.track(rep.Operation, b.LexKey(), e.LexKey())
+	batch := o.batchSize()
+
+	// Completion is estimated between the first and the last key actually in
+	// the range, which are much closer than the bounds of the range.
+	if o.Progress != nil {
+		end, err := store.GetKey(lex.LastLessThan(e))
+		if err != nil {
+			return err
+		}
+		t.end = append(end, 0x00)
+	}
+
+	for {
+		if err := t.err(); err != nil {
+			return err
+		}
+		kvs, err := store.GetRange(r, lex.RangeOptions{Limit: batch})
+		if err != nil {
+			return err
+		}
+		if len(kvs) == 0 {
+			return nil
+		}
+		if t.end != nil && rep.Keys == 0 {
+			t.begin = kvs[0].Key
+		}
+		if err := fn(kvs); err != nil {
+			return err
+		}
+		last := kvs[len(kvs)-1].Key
+		t.update(rep, last)
+		if len(kvs) < batch {
+			return nil
+		}
+		r.Begin = lex.FirstGreaterThan(last)
+	}
+}
+
+// ClearRange removes every key in the range from the store.
+func ClearRange(store lex.KVStore, r lex.ExactRange, o Options) (*Report, error) {
+	rep := newReport("clear", o)
+	rep.startRange(r)
+
+	err := scan(store, r, rep, o, func(kvs []lex.KeyValue) error {
+		for _, kv := range kvs {
+			rep.add(kv, o.samples())
+			if o.DryRun {
+				continue
+			}
+			if err := store.Clear(kv.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return rep, err
+}
+
+// ClearSubspace removes every key starting with the prefix of the subspace
+// from the store, including the prefix itself (see prefixRange).
+func ClearSubspace(store lex.KVStore, s subspace.Subspace, o Options) (*Report, error) {
+	return ClearRange(store, prefixRange(s), o)
+}
+
+// prefixRange returns the range of every key starting with the prefix of the
+// subspace, including the prefix itself and keys continuing it with 0xFF
+// bytes, which the range of the subspace leaves out. The range of a prefix
+// without an end, such as the empty one, ends at lex.MaxAppKey.
+func prefixRange(s subspace.Subspace) lex.KeyRange {
+	end, ok := lex.PrefixEnd(s.Bytes())
+	if !ok {
+		end = lex.MaxAppKey
+	}
+	return lex.KeyRange{Begin: s, End: end}
+}
+
+// CopyRange copies every key-value pair in the range from src to dst,
+// overwriting existing values in dst. src and dst may be the same store.
+func CopyRange(src, dst lex.KVStore, r lex.ExactRange, o Options) (*Report, error) {
+	rep := newReport("copy", o)
+	rep.startRange(r)
+
+	err := scan(src, r, rep, o, func(kvs []lex.KeyValue) error {
+		for _, kv := range kvs {
+			rep.add(kv, o.samples())
+			if o.DryRun {
+				continue
+			}
+			if err := dst.Set(kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return rep, err
+}
@@ -0,0 +1,34 @@
+package kvutil
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+	"github.com/abdullin/lex-go/subspace"
+)
+
+func TestClearSubspaceHighBytes(t *testing.T) {
+	s := lextest.NewMemStore()
+	sp := subspace.Sub("a")
+	for _, suffix := range [][]byte{nil, {0x00}, {0xFE}, {0xFF}, {0xFF, 0xFF, 0x01}} {
+		s.Set(append(append(lex.Key{}, sp.Bytes()...), suffix...), []byte("v"))
+	}
+	outside := subspace.Sub("b").Pack(nil)
+	s.Set(outside, []byte("v"))
+
+	rep, err := ClearSubspace(s, sp, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Keys != 5 {
+		t.Errorf("ClearSubspace cleared %d keys, want 5", rep.Keys)
+	}
+	kvs, err := s.GetRange(lex.KeyRange{Begin: lex.MinKey, End: lex.MaxAppKey}, lex.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || string(kvs[0].Key) != string(outside) {
+		t.Errorf("ClearSubspace left %v, want only %q", kvs, outside)
+	}
+}
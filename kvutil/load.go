@@ -0,0 +1,124 @@
+package kvutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Source is a stream of key-value pairs, such as an extsort.Reader. Next
+// returns io.EOF once every pair has been read.
+type Source interface {
+	Next() (lex.KeyValue, error)
+}
+
+// BulkLoader is implemented by stores offering a faster way than Set to load
+// key-value pairs in order. LoadBatch is called with batches of pairs sorted
+// by strictly ascending keys, every batch following the previous one; the
+// pairs of a batch must be stored once LoadBatch returns.
+type BulkLoader interface {
+	LoadBatch(kvs []lex.KeyValue) error
+}
+
+// LoadOptions control the behavior of Load.
+type LoadOptions struct {
+	Options
+
+	// After resumes an interrupted load: pairs whose keys are less than or
+	// equal to After are skipped. A nil After loads every pair. The last key
+	// reported to the Progress of the options once a batch is stored may be
+	// saved and passed as After to resume the load later.
+	After lex.Key
+}
+
+// Load stores the key-value pairs read from src, which must be sorted by
+// strictly ascending keys, into dst. If dst implements BulkLoader, pairs are
+// loaded in batches with LoadBatch; otherwise they are stored with Set.
+//
+// Load returns an error as soon as a key is out of order, after storing the
+// pairs preceding it.
+func Load(dst lex.KVStore, src Source, o LoadOptions) (*Report, error) {
+	rep := newReport("load", o.Options)
+	rep.Ranges = append(rep.Ranges, RangeReport{})
+
+	loader, ok := dst.(BulkLoader)
+	if !ok {
+		loader = setLoader{dst}
+	}
+
+	batch := make([]lex.KeyValue, 0, o.batchSize())
+	var last lex.Key
+
+	// The range of the source is unknown, so the completion is not
+	// estimated.
+	t := o.track(rep.Operation, nil, nil)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := t.err(); err != nil {
+			return err
+		}
+		if !o.DryRun {
+			if err := loader.LoadBatch(batch); err != nil {
+				return err
+			}
+		}
+		t.update(rep, last)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		kv, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rep, err
+		}
+
+		if rep.Keys == 0 && o.After != nil && bytes.Compare(kv.Key, o.After) <= 0 {
+			continue
+		}
+		if rep.Keys > 0 && bytes.Compare(kv.Key, last) <= 0 {
+			if err := flush(); err != nil {
+				return rep, err
+			}
+			return rep, fmt.Errorf("kvutil: input is not sorted at key %s", FormatKey(kv.Key))
+		}
+
+		if rep.Keys == 0 {
+			rep.Ranges[0].Begin = kv.Key
+		}
+		rep.add(kv, o.samples())
+		rep.Ranges[0].End = append(append(lex.Key{}, kv.Key...), 0x00)
+
+		batch = append(batch, kv)
+		last = kv.Key
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return rep, err
+			}
+		}
+	}
+
+	return rep, flush()
+}
+
+// setLoader loads batches into a store without a bulk loading fast path.
+type setLoader struct {
+	store lex.KVStore
+}
+
+func (l setLoader) LoadBatch(kvs []lex.KeyValue) error {
+	for _, kv := range kvs {
+		if err := l.store.Set(kv.Key, kv.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package kvutil
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+)
+
+// sliceSource is a Source reading pairs from a slice.
+type sliceSource []lex.KeyValue
+
+func (s *sliceSource) Next() (lex.KeyValue, error) {
+	if len(*s) == 0 {
+		return lex.KeyValue{}, io.EOF
+	}
+	kv := (*s)[0]
+	*s = (*s)[1:]
+	return kv, nil
+}
+
+// batchLoader is a BulkLoader recording the sizes of the batches it loads.
+type batchLoader struct {
+	*lextest.MemStore
+	batches []int
+}
+
+func (l *batchLoader) LoadBatch(kvs []lex.KeyValue) error {
+	l.batches = append(l.batches, len(kvs))
+	for _, kv := range kvs {
+		if err := l.Set(kv.Key, kv.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pairs(n int) []lex.KeyValue {
+	kvs := make([]lex.KeyValue, n)
+	for i := range kvs {
+		kvs[i] = lex.KeyValue{Key: lex.Key(fmt.Sprintf("k%03d", i)), Value: []byte(fmt.Sprint(i))}
+	}
+	return kvs
+}
+
+func all(t *testing.T, s lex.KVStore) []lex.KeyValue {
+	t.Helper()
+	kvs, err := s.GetRange(lex.KeyRange{Begin: lex.MinKey, End: lex.MaxAppKey}, lex.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kvs
+}
+
+func TestLoad(t *testing.T) {
+	want := pairs(25)
+
+	// A store without a fast path is loaded with Set.
+	s := lextest.NewMemStore()
+	src := sliceSource(pairs(25))
+	rep, err := Load(s, &src, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Keys != 25 {
+		t.Errorf("Load reported %d keys, want 25", rep.Keys)
+	}
+	if got := all(t, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+
+	// A BulkLoader is loaded in batches.
+	l := &batchLoader{MemStore: lextest.NewMemStore()}
+	src = sliceSource(pairs(25))
+	if _, err := Load(l, &src, LoadOptions{Options: Options{BatchSize: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(l.batches, []int{10, 10, 5}) {
+		t.Errorf("loaded batches of %v, want [10 10 5]", l.batches)
+	}
+	if got := all(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+}
+
+func TestLoadResume(t *testing.T) {
+	s := lextest.NewMemStore()
+	src := sliceSource(pairs(25))
+	rep, err := Load(s, &src, LoadOptions{After: lex.Key("k019")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Keys != 5 {
+		t.Errorf("Load reported %d keys, want 5", rep.Keys)
+	}
+	if got, want := all(t, s), pairs(25)[20:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+}
+
+func TestLoadUnsorted(t *testing.T) {
+	s := lextest.NewMemStore()
+	kvs := pairs(5)
+	kvs[3], kvs[4] = kvs[4], kvs[3]
+	src := sliceSource(kvs)
+	if _, err := Load(s, &src, LoadOptions{}); err == nil {
+		t.Fatal("Load accepted unsorted input")
+	}
+	if got, want := all(t, s), kvs[:4]; !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v before the error, want %v", got, want)
+	}
+}
+
+func TestLoadDryRun(t *testing.T) {
+	s := lextest.NewMemStore()
+	src := sliceSource(pairs(5))
+	rep, err := Load(s, &src, LoadOptions{Options: Options{DryRun: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Keys != 5 || len(all(t, s)) != 0 {
+		t.Errorf("dry run reported %d keys and loaded %d, want 5 and 0", rep.Keys, len(all(t, s)))
+	}
+}
@@ -0,0 +1,83 @@
+package kvutil
+
+import (
+	"errors"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/rewrite"
+	"github.com/abdullin/lex-go/subspace"
+)
+
+// MigrateOptions control the behavior of Migrate.
+type MigrateOptions struct {
+	Options
+
+	// Transformer, if set, rewrites the tuple following the prefix of every
+	// migrated key (see rewrite.Transformer.Rewrite).
+	Transformer *rewrite.Transformer
+
+	// Move indicates that every source key is cleared once copied.
+	Move bool
+
+	// After resumes an interrupted migration: source keys less than or equal
+	// to After are skipped. The last key reported to the Progress of the
+	// options may be saved and passed as After to resume the migration later.
+	After lex.Key
+}
+
+// Migrate copies every key starting with the prefix of the subspace from
+// (including the prefix itself) into the subspace to, replacing the prefix of
+// from with the prefix of to, and optionally rewriting the rest of the key.
+// The subspaces must not overlap.
+func Migrate(store lex.KVStore, from, to subspace.Subspace, o MigrateOptions) (*Report, error) {
+	op := "migrate"
+	if o.Move {
+		op = "move"
+	}
+	rep := newReport(op, o.Options)
+
+	if from.Contains(to) || to.Contains(from) {
+		return rep, errors.New("kvutil: source and destination subspaces overlap")
+	}
+
+	r := prefixRange(from)
+	if o.After != nil && lex.Compare(o.After, from) >= 0 {
+		r.Begin = lex.Key(append(append([]byte{}, o.After...), 0x00))
+	}
+	rep.startRange(r)
+
+	err := scan(store, r, rep, o.Options, func(kvs []lex.KeyValue) error {
+		for _, kv := range kvs {
+			k, err := migrate(kv.Key, from, to, o.Transformer)
+			if err != nil {
+				return err
+			}
+			rep.add(kv, o.samples())
+			if o.DryRun {
+				continue
+			}
+			if err := store.Set(k, kv.Value); err != nil {
+				return err
+			}
+			if !o.Move {
+				continue
+			}
+			if err := store.Clear(kv.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return rep, err
+}
+
+func migrate(k lex.Key, from, to subspace.Subspace, t *rewrite.Transformer) (lex.Key, error) {
+	if t != nil {
+		return t.Rewrite(k, from, to)
+	}
+	b, err := from.Strip(k)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(lex.Key{}, to.Bytes()...), b...), nil
+}
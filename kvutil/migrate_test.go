@@ -0,0 +1,40 @@
+package kvutil
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+	"github.com/abdullin/lex-go/subspace"
+)
+
+func TestMigrateHighBytes(t *testing.T) {
+	s := lextest.NewMemStore()
+	from, to := subspace.Sub("a"), subspace.Sub("b")
+	suffixes := [][]byte{nil, {0x00}, {0xFE}, {0xFF}, {0xFF, 0xFF, 0x01}}
+	for _, suffix := range suffixes {
+		s.Set(append(append(lex.Key{}, from.Bytes()...), suffix...), []byte("v"))
+	}
+
+	rep, err := Migrate(s, from, to, MigrateOptions{Move: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Keys != len(suffixes) {
+		t.Errorf("Migrate moved %d keys, want %d", rep.Keys, len(suffixes))
+	}
+	moved, err := s.GetRange(prefixRange(to), lex.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != len(suffixes) {
+		t.Errorf("Migrate copied %v, want %d keys", moved, len(suffixes))
+	}
+	left, err := s.GetRange(prefixRange(from), lex.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("Migrate left %v in the source subspace", left)
+	}
+}
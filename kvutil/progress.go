@@ -0,0 +1,117 @@
+package kvutil
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Status describes the progress of a running utility.
+type Status struct {
+	Operation string `json:"operation"`
+
+	// Keys and Bytes count the key-value pairs processed so far.
+	Keys  int `json:"keys"`
+	Bytes int `json:"bytes"`
+
+	// Key is the last key processed. As keys are processed in order, a
+	// utility can be resumed after it.
+	Key lex.Key `json:"key"`
+
+	// Percent estimates the completion of the utility from the position of
+	// Key within the range being processed. It is negative when unknown.
+	Percent float64 `json:"percent"`
+
+	// Elapsed is the time since the utility started, and ETA the estimated
+	// time until it completes (or zero when unknown).
+	Elapsed time.Duration `json:"elapsed"`
+	ETA     time.Duration `json:"eta"`
+}
+
+// Progress receives the status of a running utility after every batch of
+// keys.
+type Progress interface {
+	Update(s Status)
+}
+
+// ProgressFunc is a function implementing Progress.
+type ProgressFunc func(s Status)
+
+// Update implements Progress.
+func (f ProgressFunc) Update(s Status) {
+	f(s)
+}
+
+// tracker checks for cancellation and reports the progress of a utility.
+type tracker struct {
+	ctx        context.Context
+	progress   Progress
+	op         string
+	begin, end lex.Key
+	start      time.Time
+}
+
+func (o Options) track(op string, begin, end lex.Key) *tracker {
+	ctx := o.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &tracker{ctx, o.Progress, op, begin, end, time.Now()}
+}
+
+// err returns an error if the utility has been cancelled.
+func (t *tracker) err() error {
+	return t.ctx.Err()
+}
+
+// update reports the progress of the utility once the pairs counted in the
+// report have been processed, up to the key k.
+func (t *tracker) update(rep *Report, k lex.Key) {
+	if t.progress == nil {
+		return
+	}
+
+	s := Status{
+		Operation: t.op,
+		Keys:      rep.Keys,
+		Bytes:     rep.Bytes,
+		Key:       k,
+		Percent:   -1,
+		Elapsed:   time.Since(t.start),
+	}
+	if t.end != nil {
+		s.Percent = 100 * fraction(t.begin, t.end, k)
+		if s.Percent > 0 {
+			s.ETA = time.Duration(float64(s.Elapsed) * (100 - s.Percent) / s.Percent)
+		}
+	}
+	t.progress.Update(s)
+}
+
+// fraction returns the position of k within [begin, end), interpolating the
+// first bytes following the common prefix of begin and end.
+func fraction(begin, end, k lex.Key) float64 {
+	i := 0
+	for i < len(begin) && i < len(end) && begin[i] == end[i] {
+		i++
+	}
+
+	b, e, x := position(begin, i), position(end, i), position(k, i)
+	if e <= b || x <= b {
+		return 0
+	}
+	if x >= e {
+		return 1
+	}
+	return float64(x-b) / float64(e-b)
+}
+
+func position(k lex.Key, i int) uint64 {
+	var b [8]byte
+	if i < len(k) {
+		copy(b[:], k[i:])
+	}
+	return binary.BigEndian.Uint64(b[:])
+}
@@ -0,0 +1,97 @@
+package kvutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Redaction is the way a Printer hides an element.
+type Redaction int
+
+// The redactions of elements: Keep prints an element like FormatKey, Mask
+// replaces it with "***", and Hash replaces it with "#" followed by the first
+// 8 bytes of a salted hash of the element in hexadecimal, so that log lines
+// about the same element can be correlated without revealing it.
+const (
+	Keep Redaction = iota
+	Mask
+	Hash
+)
+
+// Printer formats keys like FormatKey, redacting the elements at given
+// positions of their tuples, such as emails and user IDs, so that keys can be
+// logged without leaking personal data. The zero-value of Printer, and a nil
+// *Printer, redact nothing.
+type Printer struct {
+	// Positions maps positions in the tuples of keys to their redactions. A
+	// negative position counts from the end of the tuple: -1 is its last
+	// element.
+	Positions map[int]Redaction
+
+	// Salt is the key of the hash of elements (an HMAC-SHA256). It should be
+	// kept secret, so that the hashes of guessable elements cannot be
+	// reversed.
+	Salt []byte
+}
+
+// NewPrinter returns a Printer masking the elements at the given positions.
+func NewPrinter(positions ...int) *Printer {
+	p := &Printer{Positions: map[int]Redaction{}}
+	for _, i := range positions {
+		p.Positions[i] = Mask
+	}
+	return p
+}
+
+// FormatKey returns a printable representation of a key with the elements
+// at the positions of the printer redacted. A key which is not a well-formed
+// tuple is masked entirely, unless the printer redacts nothing.
+func (p *Printer) FormatKey(k lex.Key) string {
+	if p == nil || len(p.Positions) == 0 {
+		return FormatKey(k)
+	}
+	t, err := tuple.Unpack(k)
+	if err != nil || len(t) == 0 {
+		return fmt.Sprintf("<%d bytes redacted>", len(k))
+	}
+
+	parts := make([]string, len(t))
+	for i, el := range t {
+		r, ok := p.Positions[i]
+		if !ok {
+			r = p.Positions[i-len(t)]
+		}
+		switch r {
+		case Mask:
+			parts[i] = "***"
+		case Hash:
+			h := hmac.New(sha256.New, p.Salt)
+			h.Write(tuple.Tuple{el}.Pack())
+			parts[i] = "#" + hex.EncodeToString(h.Sum(nil)[:8])
+		default:
+			parts[i] = formatElement(el)
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// Key returns a fmt.Stringer formatting the key with FormatKey when it is
+// printed, for passing keys to loggers which may discard them.
+func (p *Printer) Key(k lex.KeyConvertible) fmt.Stringer {
+	return printedKey{p, k}
+}
+
+type printedKey struct {
+	p *Printer
+	k lex.KeyConvertible
+}
+
+func (k printedKey) String() string {
+	return k.p.FormatKey(k.k.LexKey())
+}
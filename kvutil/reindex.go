@@ -0,0 +1,40 @@
+package kvutil
+
+import (
+	"github.com/abdullin/lex-go"
+)
+
+// EntriesFunc returns the index entries of a record.
+type EntriesFunc func(record lex.KeyValue) ([]lex.KeyValue, error)
+
+// Reindex backfills or rebuilds an index: it reads every record in the range
+// and stores the index entries which entries returns for each of them,
+// overwriting existing entries. Stale entries are not removed; clear the
+// subspace of the index first to rebuild it from scratch.
+//
+// The Report counts the records read. In dry-run mode, entries is still
+// called, so that invalid records are reported, but nothing is written.
+func Reindex(store lex.KVStore, records lex.ExactRange, entries EntriesFunc, o Options) (*Report, error) {
+	rep := newReport("reindex", o)
+	rep.startRange(records)
+
+	err := scan(store, records, rep, o, func(kvs []lex.KeyValue) error {
+		for _, kv := range kvs {
+			es, err := entries(kv)
+			if err != nil {
+				return err
+			}
+			rep.add(kv, o.samples())
+			if o.DryRun {
+				continue
+			}
+			for _, e := range es {
+				if err := store.Set(e.Key, e.Value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return rep, err
+}
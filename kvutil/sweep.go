@@ -0,0 +1,58 @@
+package kvutil
+
+import (
+	"time"
+
+	"github.com/abdullin/lex-go"
+)
+
+// ExpiryIndex is an index of primary keys by expiration time, such as the
+// index of an expiration layer, which Sweep garbage-collects.
+type ExpiryIndex interface {
+	// Expired returns the range of the entries of the keys expired at the
+	// time now.
+	Expired(now time.Time) lex.KeyRange
+
+	// ParseEntry returns the expiration time and the primary key of an
+	// entry.
+	ParseEntry(k lex.KeyConvertible) (time.Time, lex.Key, error)
+
+	// Remove removes a primary key from the index.
+	Remove(store lex.KVStore, key lex.KeyConvertible) error
+}
+
+// Sweep garbage-collects the records of the primary keys expired at the time
+// now in an expiration index: it calls fn with every expired key, earliest
+// first, such as to delete its record, then removes the key from the index.
+// It stops at the first error of fn, leaving its key in the index. Sweep
+// reads the expired keys in batches until none is left, reporting its
+// progress after every batch.
+//
+// The Report counts the entries of the index read. In dry-run mode, fn is not
+// called and the index is left unchanged.
+func Sweep(store lex.KVStore, x ExpiryIndex, now time.Time, fn func(key lex.Key) error, o Options) (*Report, error) {
+	rep := newReport("sweep", o)
+	r := x.Expired(now)
+	rep.startRange(r)
+
+	err := scan(store, r, rep, o, func(kvs []lex.KeyValue) error {
+		for _, kv := range kvs {
+			_, key, err := x.ParseEntry(kv.Key)
+			if err != nil {
+				return err
+			}
+			rep.add(kv, o.samples())
+			if o.DryRun {
+				continue
+			}
+			if err := fn(key); err != nil {
+				return err
+			}
+			if err := x.Remove(store, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return rep, err
+}
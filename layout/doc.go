@@ -0,0 +1,145 @@
+package layout
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Doc describes the families of a layout, for generating documentation of
+// the keyspace which stays in sync with the code. It is designed to be
+// serialized with encoding/json, and written as Markdown by WriteMarkdown.
+type Doc struct {
+	Families []FamilyDoc `json:"families"`
+}
+
+// FamilyDoc describes a family: its subspace, the range of its keys (with the
+// prefix and bounds in hexadecimal) and its schema, or the schemas of its
+// versions if it is versioned, preceded by the schema of the elements leading
+// the version, if any.
+type FamilyDoc struct {
+	Name     string      `json:"name"`
+	Subspace string      `json:"subspace"`
+	Prefix   string      `json:"prefix"`
+	Begin    string      `json:"begin"`
+	End      string      `json:"end"`
+	Schema   *SchemaDoc  `json:"schema,omitempty"`
+	Leading  *SchemaDoc  `json:"leading,omitempty"`
+	Versions []SchemaDoc `json:"versions,omitempty"`
+}
+
+// SchemaDoc describes a schema. For a version of a versioned family whose keys
+// start with the version, Begin and End bound the keys of that version.
+type SchemaDoc struct {
+	Version  int64      `json:"version,omitempty"`
+	Begin    string     `json:"begin,omitempty"`
+	End      string     `json:"end,omitempty"`
+	Shape    string     `json:"shape"`
+	Fields   []FieldDoc `json:"fields"`
+	Variadic bool       `json:"variadic,omitempty"`
+}
+
+// FieldDoc describes a field of a schema.
+type FieldDoc struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+func describeSchema(s tuple.Schema) SchemaDoc {
+	d := SchemaDoc{Shape: s.String(), Fields: make([]FieldDoc, len(s.Fields)), Variadic: s.Variadic}
+	for i, f := range s.Fields {
+		d.Fields[i] = FieldDoc{f.Name, f.Kind.String(), f.Optional}
+	}
+	return d
+}
+
+// Describe returns the description of the families of the layout, ordered by
+// prefix.
+func (l *Layout) Describe() Doc {
+	d := Doc{Families: []FamilyDoc{}}
+	for _, f := range l.Families() {
+		b, e := f.Subspace.LexRangeKeys()
+		fd := FamilyDoc{
+			Name:     f.Name,
+			Subspace: f.Subspace.String(),
+			Prefix:   hex.EncodeToString(f.Subspace.Bytes()),
+			Begin:    hex.EncodeToString(b.LexKey()),
+			End:      hex.EncodeToString(e.LexKey()),
+		}
+		if f.Versions == nil {
+			s := describeSchema(f.Schema)
+			fd.Schema = &s
+		} else {
+			if f.Versions.Position() > 0 {
+				s := describeSchema(f.Versions.Leading())
+				fd.Leading = &s
+			}
+			for _, v := range f.Versions.List() {
+				schema, _ := f.Versions.Schema(v)
+				s := describeSchema(schema)
+				s.Version = v
+				if f.Versions.Position() == 0 {
+					b, e := f.Subspace.Sub(v).LexRangeKeys()
+					s.Begin, s.End = hex.EncodeToString(b.LexKey()), hex.EncodeToString(e.LexKey())
+				}
+				fd.Versions = append(fd.Versions, s)
+			}
+		}
+		d.Families = append(d.Families, fd)
+	}
+	return d
+}
+
+// WriteMarkdown writes the description as a Markdown document, with a section
+// per family holding a table of its fields and their positions in the tuples
+// (following the leading elements and the version in versioned families).
+func (d Doc) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# Key layout\n")
+	for _, f := range d.Families {
+		fmt.Fprintf(&sb, "\n## %s\n\n", f.Name)
+		fmt.Fprintf(&sb, "- Subspace: `%s`\n", f.Subspace)
+		fmt.Fprintf(&sb, "- Prefix: `%s`\n", f.Prefix)
+		fmt.Fprintf(&sb, "- Range: `%s` to `%s`\n", f.Begin, f.End)
+		if f.Schema != nil {
+			writeSchemaMarkdown(&sb, *f.Schema, 0)
+		}
+		first := 1
+		if f.Leading != nil {
+			sb.WriteString("\n### Leading fields\n\n")
+			writeSchemaMarkdown(&sb, *f.Leading, 0)
+			first += len(f.Leading.Fields)
+		}
+		for _, s := range f.Versions {
+			fmt.Fprintf(&sb, "\n### Version %d\n\n", s.Version)
+			if s.Begin != "" {
+				fmt.Fprintf(&sb, "- Range: `%s` to `%s`\n", s.Begin, s.End)
+			}
+			writeSchemaMarkdown(&sb, s, first)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeSchemaMarkdown(sb *strings.Builder, s SchemaDoc, first int) {
+	fmt.Fprintf(sb, "- Shape: `%s`\n", s.Shape)
+	if len(s.Fields) == 0 {
+		return
+	}
+	sb.WriteString("\n| # | Field | Kind | Presence |\n|---|---|---|---|\n")
+	for i, f := range s.Fields {
+		presence := "required"
+		switch {
+		case s.Variadic && i == len(s.Fields)-1:
+			presence = "repeated"
+		case f.Optional:
+			presence = "optional"
+		}
+		fmt.Fprintf(sb, "| %d | %s | %s | %s |\n", first+i, f.Name, f.Kind, presence)
+	}
+}
@@ -0,0 +1,283 @@
+// Package layout declares the key families of an application in one place.
+// Every family has a name, a subspace and the schema of the tuples stored
+// under it; a Layout checks that the subspaces do not overlap, validates keys
+// against their declared schema, gives typed access to their elements by field
+// name, and dumps the whole keyspace for documentation and reviews.
+package layout
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Family is a key family: the keys under a subspace, whose tuples all have
+// the same schema.
+type Family struct {
+	Name     string
+	Subspace subspace.Subspace
+	Schema   tuple.Schema
+
+	// Versions, if set, makes the family versioned: its tuples hold the
+	// version of their format, first or after the leading elements of
+	// Versions, which selects their schema among Versions, and Schema is
+	// unused.
+	Versions *tuple.Versions
+}
+
+// Check returns an error if the tuple does not match the schema of the
+// family.
+func (f *Family) Check(t tuple.Tuple) error {
+	_, _, err := f.check(t)
+	return err
+}
+
+// check returns the version and schema of the tuple.
+func (f *Family) check(t tuple.Tuple) (int64, tuple.Schema, error) {
+	if f.Versions == nil {
+		if err := f.Schema.Validate(t); err != nil {
+			return 0, f.Schema, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		return 0, f.Schema, nil
+	}
+	v, err := f.Versions.Validate(t)
+	if err != nil {
+		return v, tuple.Schema{}, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	s, _ := f.Versions.Schema(v)
+	return v, s, nil
+}
+
+// Pack returns the key of the family holding the given elements, or an error
+// if they do not match the schema of the family or if the key is longer than
+// lex.MaxKeySize. The latest version of a versioned family is inserted at its
+// position among the elements.
+func (f *Family) Pack(el ...tuple.Element) (lex.Key, error) {
+	t := tuple.Tuple(el)
+	if f.Versions != nil {
+		t = f.Versions.Tag(el...)
+	}
+	return f.pack(t)
+}
+
+// PackVersion is like Pack, but inserts the given version rather than the
+// latest one, such as to keep writing keys in the format of the previous
+// version during a migration. It returns an error if the family is not
+// versioned.
+func (f *Family) PackVersion(version int64, el ...tuple.Element) (lex.Key, error) {
+	if f.Versions == nil {
+		return nil, fmt.Errorf("%s: family is not versioned", f.Name)
+	}
+	return f.pack(f.Versions.TagVersion(version, el...))
+}
+
+// PackDual returns the keys of an entry in two formats of a versioned family,
+// for the dual writes of a migration from one version to another: while both
+// keys are written, readers find the entry whichever format they expect, and
+// once every entry was rewritten the keys of the old format can be cleared.
+// It returns an error, and no key, if either key cannot be packed.
+func (f *Family) PackDual(from int64, fromEl []tuple.Element, to int64, toEl []tuple.Element) (lex.Key, lex.Key, error) {
+	oldKey, err := f.PackVersion(from, fromEl...)
+	if err != nil {
+		return nil, nil, err
+	}
+	newKey, err := f.PackVersion(to, toEl...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldKey, newKey, nil
+}
+
+func (f *Family) pack(t tuple.Tuple) (lex.Key, error) {
+	if err := f.Check(t); err != nil {
+		return nil, err
+	}
+	return subspace.PackChecked(f.Subspace, t)
+}
+
+// Unpack decodes a key of the family into a Row, returning an error if the
+// key is not in the subspace of the family or does not match its schema.
+func (f *Family) Unpack(k lex.KeyConvertible) (Row, error) {
+	t, err := f.Subspace.Unpack(k)
+	if err != nil {
+		return Row{}, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	v, s, err := f.check(t)
+	if err != nil {
+		return Row{}, err
+	}
+	return Row{f, t, v, s}, nil
+}
+
+// Row is a key of a family, decoded and checked against its schema.
+type Row struct {
+	Family *Family
+	Tuple  tuple.Tuple
+
+	// Version is the version of the format of the key, if its family is
+	// versioned.
+	Version int64
+
+	schema tuple.Schema
+}
+
+// index returns the index in the tuple of the named field.
+func (r Row) index(name string) int {
+	if v := r.Family.Versions; v != nil {
+		if i := v.Leading().Index(name); i >= 0 {
+			return i
+		}
+	}
+	i := r.schema.Index(name)
+	if i < 0 {
+		panic(fmt.Sprintf("%s: no field %s", r.Family.Name, name))
+	}
+	if v := r.Family.Versions; v != nil {
+		i += v.Position() + 1
+	}
+	return i
+}
+
+// Get returns the element of the named field, or nil if the field is
+// optional and missing. Get panics if the schema of the row has no such
+// field.
+func (r Row) Get(name string) tuple.Element {
+	i := r.index(name)
+	if i >= len(r.Tuple) {
+		return nil
+	}
+	return r.Tuple[i]
+}
+
+// Rest returns the elements of the named variadic field, which are none if
+// optional fields before it are missing.
+func (r Row) Rest(name string) tuple.Tuple {
+	i := r.index(name)
+	if i >= len(r.Tuple) {
+		return tuple.Tuple{}
+	}
+	return r.Tuple[i:]
+}
+
+// String returns the element of the named string field, or "" if the field
+// is optional and missing.
+func (r Row) String(name string) string {
+	s, _ := r.Get(name).(string)
+	return s
+}
+
+// Bytes returns the element of the named bytes field, or nil if the field is
+// optional and missing.
+func (r Row) Bytes(name string) []byte {
+	b, _ := r.Get(name).([]byte)
+	return b
+}
+
+// Int returns the element of the named int field, or 0 if the field is
+// optional and missing.
+func (r Row) Int(name string) int64 {
+	i, _ := r.Get(name).(int64)
+	return i
+}
+
+// Money returns the element of the named money field, or the zero Money if
+// the field is optional and missing.
+func (r Row) Money(name string) tuple.Money {
+	m, _ := r.Get(name).(tuple.Money)
+	return m
+}
+
+// Layout is a set of key families with disjoint subspaces.
+type Layout struct {
+	reg      *subspace.Registry
+	families map[string]*Family
+}
+
+// New returns an empty Layout.
+func New() *Layout {
+	return &Layout{subspace.NewRegistry(), map[string]*Family{}}
+}
+
+// Declare adds a family to the layout. It returns an error if the name is
+// taken, or if the subspace overlaps with the subspace of another family.
+func (l *Layout) Declare(name string, s subspace.Subspace, schema tuple.Schema) (*Family, error) {
+	return l.declare(&Family{Name: name, Subspace: s, Schema: schema})
+}
+
+// DeclareVersioned adds a versioned family to the layout, whose keys hold the
+// version of their format at the position set by v. Versions may be
+// registered with v after the family is declared.
+func (l *Layout) DeclareVersioned(name string, s subspace.Subspace, v *tuple.Versions) (*Family, error) {
+	return l.declare(&Family{Name: name, Subspace: s, Versions: v})
+}
+
+func (l *Layout) declare(f *Family) (*Family, error) {
+	if err := l.reg.Register(f.Name, f.Subspace); err != nil {
+		return nil, err
+	}
+	l.families[f.Name] = f
+	return f, nil
+}
+
+// MustDeclare is like Declare but panics if the family cannot be declared.
+func (l *Layout) MustDeclare(name string, s subspace.Subspace, schema tuple.Schema) *Family {
+	f, err := l.Declare(name, s, schema)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Family returns the family declared under the given name.
+func (l *Layout) Family(name string) (*Family, bool) {
+	f, ok := l.families[name]
+	return f, ok
+}
+
+// Families returns the families of the layout, ordered by prefix.
+func (l *Layout) Families() []*Family {
+	var fs []*Family
+	for _, name := range l.reg.Names() {
+		fs = append(fs, l.families[name])
+	}
+	return fs
+}
+
+// Match decodes a key with the family whose subspace contains it, returning
+// an error if no family contains the key or if it does not match the schema
+// of its family.
+func (l *Layout) Match(k lex.KeyConvertible) (Row, error) {
+	for _, f := range l.families {
+		if f.Subspace.Contains(k) {
+			return f.Unpack(k)
+		}
+	}
+	return Row{}, fmt.Errorf("key %q is not in any family", []byte(k.LexKey()))
+}
+
+// Dump writes a table of the families of the layout, ordered by prefix, with
+// their subspace and schema, listing every version of versioned families
+// (following their leading schema, if any).
+func (l *Layout) Dump(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FAMILY\tSUBSPACE\tSCHEMA")
+	for _, f := range l.Families() {
+		if f.Versions == nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, f.Subspace, f.Schema)
+			continue
+		}
+		for _, v := range f.Versions.List() {
+			s, _ := f.Versions.Schema(v)
+			if f.Versions.Position() > 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%s v%d %s\n", f.Name, f.Subspace, f.Versions.Leading(), v, s)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\tv%d %s\n", f.Name, f.Subspace, v, s)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,42 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+func TestRowMissingOptional(t *testing.T) {
+	l := New()
+	f := l.MustDeclare("events", subspace.Sub("e"), tuple.Schema{
+		Fields: []tuple.Field{
+			{Name: "id", Kind: tuple.KindInt},
+			{Name: "name", Kind: tuple.KindString, Optional: true},
+			{Name: "data", Kind: tuple.KindBytes, Optional: true},
+			{Name: "tags", Kind: tuple.KindString},
+		},
+		Variadic: true,
+	})
+
+	k, err := f.Pack(int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := f.Unpack(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Int("id"); got != 1 {
+		t.Errorf("Int(id) = %d, want 1", got)
+	}
+	if got := r.String("name"); got != "" {
+		t.Errorf("String(name) = %q, want empty", got)
+	}
+	if got := r.Bytes("data"); got != nil {
+		t.Errorf("Bytes(data) = %q, want nil", got)
+	}
+	if got := r.Rest("tags"); len(got) != 0 {
+		t.Errorf("Rest(tags) = %v, want none", got)
+	}
+}
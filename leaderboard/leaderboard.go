@@ -0,0 +1,193 @@
+// Package leaderboard provides the key scheme of ranked sets, such as the
+// leaderboards of games: members are keyed by descending score, so the top
+// of the board is the beginning of a range, and an index from members to
+// their scores lets a member be found, moved and removed.
+//
+// Members are identified by any tuple element, such as a string or a UUID,
+// and scores are integers. Members of equal scores rank in the order of
+// their packed identifiers. Set reads the previous score of a member before
+// writing the new one, so concurrent writers must update a board in
+// transactions.
+package leaderboard
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Entry is a member of a board and its score.
+type Entry struct {
+	Member tuple.Element
+	Score  int64
+}
+
+// Board builds the keys of a leaderboard: (score, member) in Scores, with
+// scores inverted bit by bit so that the highest scores sort first, and
+// (member) in Members, holding the packed score of the member.
+type Board struct {
+	Scores  subspace.Subspace
+	Members subspace.Subspace
+}
+
+// New returns the Board in the subspace s, whose ranking is stored under
+// s.Sub(0) and whose index of members is stored under s.Sub(1).
+func New(s subspace.Subspace) Board {
+	return Board{Scores: s.Sub(0), Members: s.Sub(1)}
+}
+
+// Key returns the ranking key of a member with a score.
+func (b Board) Key(score int64, member tuple.Element) lex.Key {
+	return b.Scores.Pack(tuple.Tuple{^score, member})
+}
+
+// ParseKey decodes a ranking key.
+func (b Board) ParseKey(key lex.KeyConvertible) (Entry, error) {
+	t, err := b.Scores.Unpack(key)
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(t) != 2 {
+		return Entry{}, fmt.Errorf("leaderboard: expected (score, member), got %d elements", len(t))
+	}
+	score, err := t.GetInt(0)
+	if err != nil {
+		return Entry{}, fmt.Errorf("leaderboard: %v", err)
+	}
+	return Entry{t[1], ^score}, nil
+}
+
+// MemberKey returns the key holding the score of a member.
+func (b Board) MemberKey(member tuple.Element) lex.Key {
+	return b.Members.Pack(tuple.Tuple{member})
+}
+
+// Above returns the range of the entries ranked above a member with a score.
+func (b Board) Above(score int64, member tuple.Element) lex.KeyRange {
+	begin, _ := b.Scores.LexRangeKeys()
+	return lex.KeyRange{Begin: begin, End: b.Key(score, member)}
+}
+
+// From returns the range of the entry of a member with a score and of the
+// entries ranked below it.
+func (b Board) From(score int64, member tuple.Element) lex.KeyRange {
+	_, end := b.Scores.LexRangeKeys()
+	return lex.KeyRange{Begin: b.Key(score, member), End: end}
+}
+
+// Score returns the score of a member, and false if the member is not on the
+// board.
+func (b Board) Score(store lex.KVStore, member tuple.Element) (int64, bool, error) {
+	v, err := lex.Get(store, b.MemberKey(member))
+	if err != nil || v == nil {
+		return 0, false, err
+	}
+	t, err := tuple.Unpack(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("leaderboard: invalid score of %v: %v", member, err)
+	}
+	score, err := t.GetInt(0)
+	if err != nil {
+		return 0, false, fmt.Errorf("leaderboard: invalid score of %v: %v", member, err)
+	}
+	return score, true, nil
+}
+
+// Set sets the score of a member, adding the member to the board if needed.
+func (b Board) Set(store lex.KVStore, member tuple.Element, score int64) error {
+	old, ok, err := b.Score(store, member)
+	if err != nil {
+		return err
+	}
+	if ok && old == score {
+		return nil
+	}
+	if ok {
+		if err := store.Clear(b.Key(old, member)); err != nil {
+			return err
+		}
+	}
+	if err := store.Set(b.Key(score, member), nil); err != nil {
+		return err
+	}
+	return store.Set(b.MemberKey(member), tuple.Tuple{score}.Pack())
+}
+
+// Remove removes a member from the board. Removing a missing member is not
+// an error.
+func (b Board) Remove(store lex.KVStore, member tuple.Element) error {
+	old, ok, err := b.Score(store, member)
+	if err != nil || !ok {
+		return err
+	}
+	if err := store.Clear(b.Key(old, member)); err != nil {
+		return err
+	}
+	return store.Clear(b.MemberKey(member))
+}
+
+func (b Board) entries(kvs []lex.KeyValue) ([]Entry, error) {
+	es := make([]Entry, len(kvs))
+	for i, kv := range kvs {
+		e, err := b.ParseKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		es[i] = e
+	}
+	return es, nil
+}
+
+// Top returns the n highest ranked entries, or every entry if n is not
+// positive.
+func (b Board) Top(store lex.KVStore, n int) ([]Entry, error) {
+	kvs, err := store.GetRange(b.Scores, lex.RangeOptions{Limit: n})
+	if err != nil {
+		return nil, err
+	}
+	return b.entries(kvs)
+}
+
+// Rank returns the rank of a member, 0 for the top of the board, and false if
+// the member is not on the board. Ranking reads every entry above the
+// member, which costs as much as its rank.
+func (b Board) Rank(store lex.KVStore, member tuple.Element) (int, bool, error) {
+	score, ok, err := b.Score(store, member)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	kvs, err := store.GetRange(b.Above(score, member), lex.RangeOptions{})
+	if err != nil {
+		return 0, false, err
+	}
+	return len(kvs), true, nil
+}
+
+// Around returns the entry of a member with the n entries ranked above it and
+// the n entries ranked below it, in rank order, or nil if the member is not
+// on the board.
+func (b Board) Around(store lex.KVStore, member tuple.Element, n int) ([]Entry, error) {
+	score, ok, err := b.Score(store, member)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var above []lex.KeyValue
+	if n > 0 {
+		above, err = store.GetRange(b.Above(score, member), lex.RangeOptions{Limit: n, Reverse: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+	below, err := store.GetRange(b.From(score, member), lex.RangeOptions{Limit: n + 1})
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make([]lex.KeyValue, 0, len(above)+len(below))
+	for i := len(above) - 1; i >= 0; i-- {
+		kvs = append(kvs, above[i])
+	}
+	return b.entries(append(kvs, below...))
+}
@@ -0,0 +1,239 @@
+// Package leveldbkv adapts goleveldb (https://github.com/syndtr/goleveldb) to
+// the lex.KVStore interface, so that existing LevelDB users can consume tuple
+// and subspace keys without translating range semantics themselves.
+//
+// Store implements lex.IterableStore, iterating over ranges with goleveldb
+// iterators.
+package leveldbkv
+
+import (
+	"bytes"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Backend is the subset of the goleveldb API used by Store. It is implemented
+// by both *leveldb.DB and *leveldb.Transaction.
+type Backend interface {
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+	Put(key, value []byte, wo *opt.WriteOptions) error
+	Delete(key []byte, wo *opt.WriteOptions) error
+}
+
+// Store implements lex.KVStore on top of a goleveldb database or transaction.
+type Store struct {
+	b  Backend
+	ro *opt.ReadOptions
+	wo *opt.WriteOptions
+}
+
+// New returns a Store backed by b, reading and writing with the given options
+// (either of which may be nil).
+func New(b Backend, ro *opt.ReadOptions, wo *opt.WriteOptions) *Store {
+	return &Store{b, ro, wo}
+}
+
+// Range returns the goleveldb range covering exactly the keys of er, ending
+// at lex.MaxAppKey at the latest, so that no range reaches the keys starting
+// with 0xFF.
+func Range(er lex.ExactRange) *util.Range {
+	b, e := er.LexRangeKeys()
+	end := e.LexKey()
+	if bytes.Compare(end, lex.MaxAppKey) > 0 {
+		end = lex.MaxAppKey
+	}
+	return &util.Range{Start: b.LexKey(), Limit: end}
+}
+
+// slice returns the goleveldb range covering exactly the keys of r, resolving
+// key selectors only when r is not an lex.ExactRange.
+func (s *Store) slice(r lex.Range) (*util.Range, error) {
+	if er, ok := r.(lex.ExactRange); ok {
+		return Range(er), nil
+	}
+	bsel, esel := r.LexRangeKeySelectors()
+	begin, err := s.GetKey(bsel)
+	if err != nil {
+		return nil, err
+	}
+	end, err := s.GetKey(esel)
+	if err != nil {
+		return nil, err
+	}
+	return &util.Range{Start: begin, Limit: end}, nil
+}
+
+// GetRange implements lex.KVStore. Exact ranges are translated directly into
+// a util.Range bounding the iterator; other ranges are first resolved to keys
+// with GetKey.
+func (s *Store) GetRange(r lex.Range, options lex.RangeOptions) ([]lex.KeyValue, error) {
+	slice, err := s.slice(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Compare(slice.Start, slice.Limit) >= 0 {
+		return nil, nil
+	}
+
+	it := s.b.NewIterator(slice, s.ro)
+	defer it.Release()
+
+	var kvs []lex.KeyValue
+
+	valid := it.First()
+	if options.Reverse {
+		valid = it.Last()
+	}
+
+	for ; valid; valid = step(it, options.Reverse) {
+		kvs = append(kvs, lex.KeyValue{Key: lex.Key(clone(it.Key())), Value: clone(it.Value())})
+
+		if options.Limit > 0 && len(kvs) == options.Limit {
+			break
+		}
+	}
+
+	return kvs, it.Error()
+}
+
+func step(it iterator.Iterator, reverse bool) bool {
+	if reverse {
+		return it.Prev()
+	}
+	return it.Next()
+}
+
+// NewIterator implements lex.IterableStore. The iterator holds a goleveldb
+// iterator until it is closed.
+func (s *Store) NewIterator(r lex.Range, options lex.RangeOptions) lex.Iterator {
+	it := &rangeIterator{reverse: options.Reverse, limit: options.Limit}
+	slice, err := s.slice(r)
+	if err != nil || bytes.Compare(slice.Start, slice.Limit) >= 0 {
+		it.err = err
+		return it
+	}
+	it.it = s.b.NewIterator(slice, s.ro)
+	return it
+}
+
+type rangeIterator struct {
+	it       iterator.Iterator
+	reverse  bool
+	started  bool
+	limit, n int
+	key      lex.Key
+	value    []byte
+	err      error
+}
+
+func (i *rangeIterator) Next() bool {
+	if i.it == nil || i.err != nil || (i.limit > 0 && i.n == i.limit) {
+		return false
+	}
+
+	var valid bool
+	switch {
+	case i.started:
+		valid = step(i.it, i.reverse)
+	case i.reverse:
+		valid = i.it.Last()
+	default:
+		valid = i.it.First()
+	}
+	i.started = true
+	if !valid {
+		i.err = i.it.Error()
+		return false
+	}
+
+	i.key, i.value = lex.Key(clone(i.it.Key())), clone(i.it.Value())
+	i.n++
+	return true
+}
+
+func (i *rangeIterator) Key() lex.Key {
+	return i.key
+}
+
+func (i *rangeIterator) Value() []byte {
+	return i.value
+}
+
+func (i *rangeIterator) Err() error {
+	return i.err
+}
+
+func (i *rangeIterator) Close() error {
+	if i.it != nil {
+		i.it.Release()
+		i.it = nil
+	}
+	return i.err
+}
+
+// GetKey implements lex.KVStore.
+func (s *Store) GetKey(sel lex.Selectable) (lex.Key, error) {
+	ks, n := sel.LexKeySelector().Normalize()
+	switch {
+	case ks.BeforeAll():
+		return lex.MinKey, nil
+	case ks.AfterAll():
+		return lex.MaxAppKey, nil
+	}
+	k := []byte(ks.Key.LexKey())
+
+	// The first key greater than k is the first key greater than or equal to
+	// k+0x00, and the last key less than or equal to k is the last key less
+	// than k+0x00.
+	if ks.OrEqual {
+		k = append(clone(k), 0x00)
+	}
+
+	it := s.b.NewIterator(nil, s.ro)
+	defer it.Release()
+
+	if ks.Forward() {
+		valid := it.Seek(k)
+		for i := 0; i < n && valid; i++ {
+			valid = it.Next()
+		}
+		if !valid {
+			return lex.MaxAppKey, it.Error()
+		}
+		return lex.Key(clone(it.Key())), nil
+	}
+
+	valid := it.Seek(k)
+	if valid {
+		valid = it.Prev()
+	} else {
+		valid = it.Last()
+	}
+	for i := 0; i < -n && valid; i++ {
+		valid = it.Prev()
+	}
+	if !valid {
+		return lex.MinKey, it.Error()
+	}
+	return lex.Key(clone(it.Key())), nil
+}
+
+// Set implements lex.KVStore.
+func (s *Store) Set(key lex.KeyConvertible, value []byte) error {
+	return s.b.Put(key.LexKey(), value, s.wo)
+}
+
+// Clear implements lex.KVStore.
+func (s *Store) Clear(key lex.KeyConvertible) error {
+	return s.b.Delete(key.LexKey(), s.wo)
+}
+
+func clone(b []byte) []byte {
+	r := make([]byte, len(b))
+	copy(r, b)
+	return r
+}
@@ -0,0 +1,22 @@
+package leveldbkv
+
+import (
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+)
+
+func TestConformance(t *testing.T) {
+	lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		db, err := leveldb.Open(storage.NewMemStorage(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return New(db, nil, nil)
+	})
+}
@@ -0,0 +1,21 @@
+package leveldbkv
+
+import (
+	"io"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	"github.com/abdullin/lex-go"
+)
+
+// The "leveldb" adapter opens the LevelDB database in the given directory.
+func init() {
+	lex.RegisterAdapter("leveldb", func(path string, o lex.OpenOptions) (lex.KVStore, io.Closer, error) {
+		db, err := leveldb.OpenFile(path, &opt.Options{ReadOnly: o.ReadOnly})
+		if err != nil {
+			return nil, nil, err
+		}
+		return New(db, nil, nil), db, nil
+	})
+}
@@ -0,0 +1,311 @@
+// Package lexfuzz provides fuzz targets, corpus seeds and invariant checks
+// for the tuple codec, so that layers can fuzz their own key schemas against
+// it with go test -fuzz.
+//
+// The fuzzing engine supplies byte strings; a Source turns them into tuples
+// deterministically, so that every input is a valid tuple and the engine can
+// explore tuples rather than encodings. A typical schema fuzz test looks
+// like:
+//
+//	func FuzzOrderKeys(f *testing.F) {
+//		lexfuzz.FuzzSchema(f, tuple.Schema{Fields: []tuple.Field{
+//			{Name: "customer", Kind: tuple.KindString},
+//			{Name: "placed", Kind: tuple.KindInt},
+//			{Name: "order", Kind: tuple.KindULID},
+//		}})
+//	}
+package lexfuzz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/abdullin/lex-go/tuple"
+	"github.com/abdullin/lex-go/tuple/golden"
+)
+
+// Seeds returns the packed tuples of the seed corpus: the golden encoding
+// vectors and the samples of every element type used to audit the tuple
+// ordering (see tuple.AuditOrdering).
+func Seeds() [][]byte {
+	var seeds [][]byte
+	vs, err := golden.Vectors()
+	if err != nil {
+		panic(err)
+	}
+	for _, v := range vs {
+		seeds = append(seeds, v.Packed)
+	}
+	for _, s := range tuple.AuditOrdering().Samples {
+		b, err := hex.DecodeString(s.Packed)
+		if err != nil {
+			panic(err)
+		}
+		seeds = append(seeds, b)
+	}
+	return seeds
+}
+
+// AddSeeds adds every seed to the corpus of a fuzz target taking a single
+// []byte argument.
+func AddSeeds(f *testing.F) {
+	for _, b := range Seeds() {
+		f.Add(b)
+	}
+}
+
+// AddSeedPairs adds pairs of seeds to the corpus of a fuzz target taking two
+// []byte arguments: every seed with itself and with the next seed.
+func AddSeedPairs(f *testing.F) {
+	seeds := Seeds()
+	for i, b := range seeds {
+		f.Add(b, b)
+		f.Add(b, seeds[(i+1)%len(seeds)])
+	}
+}
+
+// FuzzDecode fuzzes Unpack and UnpackLenient with arbitrary encodings (see
+// CheckDecode).
+func FuzzDecode(f *testing.F) {
+	AddSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		CheckDecode(t, b)
+	})
+}
+
+// FuzzRoundTrip fuzzes Pack and Unpack with tuples read from a Source (see
+// CheckRoundTrip).
+func FuzzRoundTrip(f *testing.F) {
+	AddSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		CheckRoundTrip(t, NewSource(b).Tuple())
+	})
+}
+
+// FuzzOrdering fuzzes the ordering of pairs of tuples read from Sources (see
+// CheckOrdering).
+func FuzzOrdering(f *testing.F) {
+	AddSeedPairs(f)
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+		CheckOrdering(t, NewSource(a).Tuple(), NewSource(b).Tuple())
+	})
+}
+
+// FuzzSchema fuzzes pairs of tuples of the schema, read from Sources with
+// SchemaTuple: they must match the schema, both as tuples and once packed,
+// round-trip and sort like their packed encodings. The schema must only hold
+// kinds supported by SchemaTuple.
+func FuzzSchema(f *testing.F, s tuple.Schema) {
+	if _, err := NewSource(nil).SchemaTuple(s); err != nil {
+		f.Fatal(err)
+	}
+	AddSeedPairs(f)
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+		ta, _ := NewSource(a).SchemaTuple(s)
+		tb, _ := NewSource(b).SchemaTuple(s)
+		for _, tu := range []tuple.Tuple{ta, tb} {
+			if err := s.Validate(tu); err != nil {
+				t.Fatalf("generated tuple %#v does not match schema %s: %v", tu, s, err)
+			}
+			if err := s.ValidateKey(tu.Pack()); err != nil {
+				t.Fatalf("packed tuple %x does not match schema %s: %v", tu.Pack(), s, err)
+			}
+			CheckRoundTrip(t, tu)
+		}
+		CheckOrdering(t, ta, tb)
+	})
+}
+
+// CheckDecode checks that decoding an arbitrary byte string does not panic,
+// and that a tuple decoded from it by Unpack or UnpackLenient packs to a
+// canonical encoding: one which decodes to an equal tuple and packs to itself
+// again.
+func CheckDecode(t testing.TB, b []byte) {
+	t.Helper()
+	for _, unpack := range []func([]byte) (tuple.Tuple, error){tuple.Unpack, tuple.UnpackLenient} {
+		tu, err := unpack(b)
+		if err != nil {
+			continue
+		}
+		p := tu.Pack()
+		u, err := unpack(p)
+		if err != nil {
+			t.Fatalf("repacked tuple %x of %x does not decode: %v", p, b, err)
+		}
+		if !u.Equal(tu) {
+			t.Fatalf("repacked tuple %x of %x decodes to %#v, expected %#v", p, b, u, tu)
+		}
+		if q := u.Pack(); !bytes.Equal(q, p) {
+			t.Fatalf("repacked tuple %x of %x packs to %x", p, b, q)
+		}
+	}
+}
+
+// CheckRoundTrip checks that a tuple decodes to an equal tuple once packed,
+// which packs to the same bytes again (see tuple.CheckRoundTrip).
+func CheckRoundTrip(t testing.TB, tu tuple.Tuple) {
+	t.Helper()
+	if err := tuple.CheckRoundTrip(tu); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// CheckOrdering checks that two tuples compare like their packed encodings,
+// and that each packed tuple is a prefix of, and sorts before, the packed
+// concatenation of the tuples (see tuple.CheckOrdering).
+func CheckOrdering(t testing.TB, a, b tuple.Tuple) {
+	t.Helper()
+	if err := tuple.CheckOrdering(a, b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Source reads tuples from a fuzzing input. Every input, including an empty
+// one, yields valid tuples: once the input is exhausted, a Source reads zero
+// bytes.
+type Source struct {
+	data []byte
+}
+
+// NewSource returns a Source reading from data.
+func NewSource(data []byte) *Source {
+	return &Source{data}
+}
+
+// Byte reads a byte.
+func (s *Source) Byte() byte {
+	if len(s.data) == 0 {
+		return 0
+	}
+	c := s.data[0]
+	s.data = s.data[1:]
+	return c
+}
+
+// Read fills b, implementing io.Reader. It never fails.
+func (s *Source) Read(b []byte) (int, error) {
+	n := copy(b, s.data)
+	s.data = s.data[n:]
+	for i := n; i < len(b); i++ {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+// Int64 reads an int64 from 8 bytes.
+func (s *Source) Int64() int64 {
+	var b [8]byte
+	s.Read(b[:])
+	return int64(binary.BigEndian.Uint64(b[:]))
+}
+
+// Bytes reads a byte string of at most 31 bytes.
+func (s *Source) Bytes() []byte {
+	b := make([]byte, s.Byte()%32)
+	s.Read(b)
+	return b
+}
+
+// Text reads a string of at most 31 bytes, which may not be valid UTF-8.
+func (s *Source) Text() string {
+	return string(s.Bytes())
+}
+
+// Element reads an element of any type supported by this module: the types
+// decoded by Unpack and the types registered by the tuple package.
+func (s *Source) Element() tuple.Element {
+	kinds := []tuple.Kind{
+		tuple.KindNil, tuple.KindBytes, tuple.KindString, tuple.KindInt,
+		tuple.KindUUID, tuple.KindMoney, tuple.KindULID, tuple.KindDecimal, tuple.KindIP,
+		tuple.KindPrefixedBytes, tuple.KindBitmap,
+	}
+	el, _ := s.element(kinds[int(s.Byte())%len(kinds)])
+	return el
+}
+
+// Tuple reads a tuple of at most 7 elements (see Element).
+func (s *Source) Tuple() tuple.Tuple {
+	t := make(tuple.Tuple, s.Byte()%8)
+	for i := range t {
+		t[i] = s.Element()
+	}
+	return t
+}
+
+// SchemaTuple reads a tuple matching the schema: optional fields are present
+// or not and a variadic field repeats up to 3 times, depending on the input.
+// SchemaTuple returns an error if the schema holds a kind it cannot read,
+// which is any kind but KindAny, the kinds of the types decoded by Unpack
+// and the kinds of the types registered by the tuple package.
+func (s *Source) SchemaTuple(sc tuple.Schema) (tuple.Tuple, error) {
+	var t tuple.Tuple
+	for i, f := range sc.Fields {
+		n := 1
+		switch {
+		case sc.Variadic && i == len(sc.Fields)-1:
+			n = int(s.Byte() % 4)
+		case f.Optional:
+			if s.Byte()%2 == 0 {
+				return t, nil
+			}
+		}
+		for ; n > 0; n-- {
+			el, err := s.element(f.Kind)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", f.Name, err)
+			}
+			t = append(t, el)
+		}
+	}
+	return t, nil
+}
+
+func (s *Source) element(k tuple.Kind) (tuple.Element, error) {
+	switch k {
+	case tuple.KindAny:
+		return s.Element(), nil
+	case tuple.KindNil:
+		return nil, nil
+	case tuple.KindBytes:
+		return s.Bytes(), nil
+	case tuple.KindString:
+		return s.Text(), nil
+	case tuple.KindInt:
+		if s.Byte()%2 == 0 {
+			// Favour small integers, whose encodings are the most varied.
+			return int64(int8(s.Byte())), nil
+		}
+		return s.Int64(), nil
+	case tuple.KindUUID:
+		var u tuple.UUID
+		s.Read(u[:])
+		return u, nil
+	case tuple.KindULID:
+		var u tuple.ULID
+		s.Read(u[:])
+		return u, nil
+	case tuple.KindMoney:
+		return tuple.Money{Currency: []string{"EUR", "JPY", "USD"}[s.Byte()%3], Minor: s.Int64()}, nil
+	case tuple.KindDecimal:
+		return tuple.Decimal{Unscaled: big.NewInt(s.Int64()), Scale: int32(int8(s.Byte()))}, nil
+	case tuple.KindIP:
+		if s.Byte()%2 == 0 {
+			ip := make(net.IP, net.IPv4len)
+			s.Read(ip)
+			return ip, nil
+		}
+		ip := make(net.IP, net.IPv6len)
+		s.Read(ip)
+		return ip, nil
+	case tuple.KindPrefixedBytes:
+		return tuple.PrefixedBytes(s.Bytes()), nil
+	case tuple.KindBitmap:
+		return tuple.Bitmap(s.Int64()), nil
+	}
+	return nil, fmt.Errorf("unsupported kind %s", k)
+}
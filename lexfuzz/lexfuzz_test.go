@@ -0,0 +1,38 @@
+package lexfuzz_test
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go/lexfuzz"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// The fuzz targets add the seed corpus themselves, so that go test runs
+// every seed and go test -fuzz starts from them.
+
+func FuzzDecode(f *testing.F) {
+	lexfuzz.FuzzDecode(f)
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	lexfuzz.FuzzRoundTrip(f)
+}
+
+func FuzzOrdering(f *testing.F) {
+	lexfuzz.FuzzOrdering(f)
+}
+
+func FuzzSchema(f *testing.F) {
+	lexfuzz.FuzzSchema(f, tuple.Schema{Fields: []tuple.Field{
+		{Name: "customer", Kind: tuple.KindString},
+		{Name: "placed", Kind: tuple.KindInt},
+		{Name: "order", Kind: tuple.KindULID, Optional: true},
+		{Name: "total", Kind: tuple.KindDecimal, Optional: true},
+	}})
+}
+
+func TestSeeds(t *testing.T) {
+	if len(lexfuzz.Seeds()) == 0 {
+		t.Fatal("empty seed corpus")
+	}
+}
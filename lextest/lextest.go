@@ -0,0 +1,387 @@
+// Package lextest provides a conformance suite for lex.KVStore adapters, so
+// that third-party adapters can certify that they order keys, resolve key
+// selectors and scan ranges exactly like the reference implementation.
+//
+// A typical adapter test looks like:
+//
+//	func TestConformance(t *testing.T) {
+//		lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+//			return newEmptyStore(t)
+//		})
+//	}
+package lextest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// RunAdapterSuite runs the conformance suite as subtests of t. open is called
+// once per subtest and must return an empty store; it may register cleanup
+// with t.Cleanup.
+//
+// The suite never writes the empty key (which several engines reject) nor
+// keys starting with 0xFF, but does resolve selectors and ranges around both
+// boundaries.
+func RunAdapterSuite(t *testing.T, open func(t *testing.T) lex.KVStore) {
+	t.Run("Ordering", func(t *testing.T) { testOrdering(t, open(t)) })
+	t.Run("Selectors", func(t *testing.T) { testSelectors(t, open(t)) })
+	t.Run("Ranges", func(t *testing.T) { testRanges(t, open(t)) })
+	t.Run("Iterators", func(t *testing.T) { testIterators(t, open(t)) })
+	t.Run("Boundaries", func(t *testing.T) { testBoundaries(t, open(t)) })
+	t.Run("Mutations", func(t *testing.T) { testMutations(t, open(t)) })
+	t.Run("Visibility", func(t *testing.T) { testVisibility(t, open(t)) })
+}
+
+// fixture is the set of keys written by most tests: raw keys exercising 0x00
+// and 0xFF bytes, followed by tuples of every supported element type.
+func fixture() []lex.Key {
+	keys := []lex.Key{
+		{0x00},
+		{0x00, 0x00},
+		{0x00, 0xFF},
+		{0x01},
+		{0x01, 0x00},
+		{0x7F},
+		{0xFE},
+		{0xFE, 0xFF},
+		{0xFE, 0xFF, 0xFF},
+	}
+	for _, t := range []tuple.Tuple{
+		{"a"},
+		{"a", nil},
+		{"a", []byte{0x00}},
+		{"a", "b"},
+		{"a", int64(-1)},
+		{"a", int64(0)},
+		{"a", int64(1 << 40)},
+		{"b"},
+	} {
+		keys = append(keys, lex.Key(t.Pack()))
+	}
+	return keys
+}
+
+// load writes keys to both the store under test and a fresh reference model,
+// in a shuffled order.
+func load(t *testing.T, s lex.KVStore, keys []lex.Key) *MemStore {
+	t.Helper()
+
+	ref := NewMemStore()
+	for _, i := range rand.New(rand.NewSource(1)).Perm(len(keys)) {
+		v := []byte(fmt.Sprintf("v%d", i))
+		if err := s.Set(keys[i], v); err != nil {
+			t.Fatalf("Set(%q): %v", keys[i], err)
+		}
+		ref.Set(keys[i], v)
+	}
+	return ref
+}
+
+func all() lex.KeyRange {
+	return lex.AppKeys()
+}
+
+func checkRange(t *testing.T, s, ref lex.KVStore, r lex.Range, o lex.RangeOptions) {
+	t.Helper()
+
+	got, err := s.GetRange(r, o)
+	if err != nil {
+		t.Fatalf("GetRange(%s, %+v): %v", describe(r), o, err)
+	}
+	want, _ := ref.GetRange(r, o)
+	checkPairs(t, fmt.Sprintf("GetRange(%s, %+v)", describe(r), o), got, want)
+}
+
+// checkPairs checks that the pairs returned by a call are the expected ones.
+func checkPairs(t *testing.T, call string, got, want []lex.KeyValue) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d pairs %s, want %d %s",
+			call, len(got), keysOf(got), len(want), keysOf(want))
+	}
+	for i := range got {
+		if !bytes.Equal(got[i].Key, want[i].Key) || !bytes.Equal(got[i].Value, want[i].Value) {
+			t.Fatalf("%s[%d]: got %q=%q, want %q=%q",
+				call, i, got[i].Key, got[i].Value, want[i].Key, want[i].Value)
+		}
+	}
+}
+
+// checkIterator checks that iterating over the range with lex.Iterate yields
+// the pairs returned by GetRange on the reference store.
+func checkIterator(t *testing.T, s, ref lex.KVStore, r lex.Range, o lex.RangeOptions) {
+	t.Helper()
+
+	var got []lex.KeyValue
+	it := lex.Iterate(s, r, o)
+	for it.Next() {
+		got = append(got, lex.KeyValue{Key: it.Key(), Value: it.Value()})
+	}
+	if err := it.Close(); err != nil {
+		t.Fatalf("Iterate(%s, %+v): %v", describe(r), o, err)
+	}
+	mode := o.Mode
+	o.Mode = 0
+	want, _ := ref.GetRange(r, o)
+	o.Mode = mode
+	checkPairs(t, fmt.Sprintf("Iterate(%s, %+v)", describe(r), o), got, want)
+}
+
+func checkKey(t *testing.T, s, ref lex.KVStore, sel lex.KeySelector) {
+	t.Helper()
+
+	got, err := s.GetKey(sel)
+	if err != nil {
+		t.Fatalf("GetKey(%s): %v", describe(sel), err)
+	}
+	want, _ := ref.GetKey(sel)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("GetKey(%s): got %q, want %q", describe(sel), got, want)
+	}
+}
+
+func testOrdering(t *testing.T, s lex.KVStore) {
+	keys := fixture()
+	load(t, s, keys)
+
+	kvs, err := s.GetRange(all(), lex.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != len(keys) {
+		t.Fatalf("got %d keys, want %d", len(kvs), len(keys))
+	}
+	for i := 1; i < len(kvs); i++ {
+		if bytes.Compare(kvs[i-1].Key, kvs[i].Key) >= 0 {
+			t.Fatalf("keys out of order: %q before %q", kvs[i-1].Key, kvs[i].Key)
+		}
+	}
+}
+
+func testSelectors(t *testing.T, s lex.KVStore) {
+	keys := fixture()
+	ref := load(t, s, keys)
+
+	probes := append([]lex.Key{{}, {0x00, 0x01}, {0x02}, {0xFE, 0xFE}, {0xFF}, {0xFF, 0x00}}, keys...)
+	for _, k := range probes {
+		for _, orEqual := range []bool{false, true} {
+			for offset := -3; offset <= 3; offset++ {
+				checkKey(t, s, ref, lex.KeySelector{Key: k, OrEqual: orEqual, Offset: offset})
+			}
+		}
+	}
+}
+
+func testRanges(t *testing.T, s lex.KVStore) {
+	keys := fixture()
+	ref := load(t, s, keys)
+
+	ranges := []lex.Range{
+		all(),
+		lex.KeyRange{Begin: lex.Key{0x01}, End: lex.Key{0xFE, 0xFF}},
+		lex.KeyRange{Begin: lex.Key{0x02}, End: lex.Key{0x02, 0x00}},
+		lex.KeyRange{Begin: lex.Key{0x7F}, End: lex.Key{0x01}},
+		tuple.Tuple{"a"},
+		lex.SelectorRange{
+			Begin: lex.FirstGreaterThan(lex.Key{0x00}),
+			End:   lex.LastLessOrEqual(lex.Key{0xFE}),
+		},
+		lex.SelectorRange{
+			Begin: lex.KeySelector{Key: lex.Key{0x7F}, Offset: -2},
+			End:   lex.KeySelector{Key: lex.Key{0x7F}, Offset: 3},
+		},
+	}
+
+	for _, r := range ranges {
+		for _, reverse := range []bool{false, true} {
+			for _, limit := range []int{0, 1, 3, 100} {
+				checkRange(t, s, ref, r, lex.RangeOptions{Limit: limit, Reverse: reverse})
+			}
+		}
+	}
+}
+
+func testIterators(t *testing.T, s lex.KVStore) {
+	keys := fixture()
+	ref := load(t, s, keys)
+
+	ranges := []lex.Range{
+		all(),
+		lex.KeyRange{Begin: lex.Key{0x01}, End: lex.Key{0xFE, 0xFF}},
+		lex.KeyRange{Begin: lex.Key{0x7F}, End: lex.Key{0x01}},
+		tuple.Tuple{"a"},
+		lex.SelectorRange{
+			Begin: lex.FirstGreaterThan(lex.Key{0x00}),
+			End:   lex.LastLessOrEqual(lex.Key{0xFE}),
+		},
+	}
+	modes := []lex.StreamingMode{
+		lex.StreamingModeIterator,
+		lex.StreamingModeWantAll,
+		lex.StreamingModeSmall,
+	}
+
+	for _, r := range ranges {
+		for _, reverse := range []bool{false, true} {
+			for _, limit := range []int{0, 1, 17, 100} {
+				for _, mode := range modes {
+					o := lex.RangeOptions{Limit: limit, Reverse: reverse, Mode: mode}
+					checkIterator(t, s, ref, r, o)
+				}
+			}
+		}
+	}
+
+	// A store holding more keys than the first batches of the iterator.
+	var many []lex.Key
+	for i := 0; i < 100; i++ {
+		many = append(many, tuple.Tuple{"many", int64(i)}.LexKey())
+	}
+	ref = load(t, s, many)
+	for _, reverse := range []bool{false, true} {
+		for _, limit := range []int{0, 40} {
+			o := lex.RangeOptions{Limit: limit, Reverse: reverse}
+			checkIterator(t, s, ref, tuple.Tuple{"many"}, o)
+		}
+	}
+}
+
+func testBoundaries(t *testing.T, s lex.KVStore) {
+	ref := NewMemStore()
+
+	// An empty store.
+	checkKey(t, s, ref, lex.FirstGreaterOrEqual(lex.MinKey))
+	checkKey(t, s, ref, lex.LastLessOrEqual(lex.MaxAppKey))
+	checkRange(t, s, ref, all(), lex.RangeOptions{})
+	checkRange(t, s, ref, all(), lex.RangeOptions{Reverse: true})
+
+	// A store with a key right before 0xFF.
+	ref = load(t, s, []lex.Key{{0x00}, {0xFE, 0xFF, 0xFF, 0xFF}})
+	for _, k := range []lex.Key{{}, {0x00}, {0xFE, 0xFF}, {0xFF}} {
+		checkKey(t, s, ref, lex.FirstGreaterOrEqual(k))
+		checkKey(t, s, ref, lex.FirstGreaterThan(k))
+		checkKey(t, s, ref, lex.LastLessThan(k))
+		checkKey(t, s, ref, lex.LastLessOrEqual(k))
+	}
+	top := lex.KeyRange{Begin: lex.Key{0xFE, 0xFF}, End: lex.MaxAppKey}
+	checkRange(t, s, ref, top, lex.RangeOptions{})
+	bottom := lex.KeyRange{Begin: lex.MinKey, End: lex.Key{0x00}}
+	checkRange(t, s, ref, bottom, lex.RangeOptions{})
+	bottom = lex.KeyRange{Begin: lex.MinKey, End: lex.Key{0x00, 0x00}}
+	checkRange(t, s, ref, bottom, lex.RangeOptions{Reverse: true})
+}
+
+func testMutations(t *testing.T, s lex.KVStore) {
+	keys := fixture()
+	ref := load(t, s, keys)
+
+	for i, k := range keys {
+		switch i % 3 {
+		case 0:
+			if err := s.Clear(k); err != nil {
+				t.Fatalf("Clear(%q): %v", k, err)
+			}
+			ref.Clear(k)
+		case 1:
+			if err := s.Set(k, []byte("overwritten")); err != nil {
+				t.Fatalf("Set(%q): %v", k, err)
+			}
+			ref.Set(k, []byte("overwritten"))
+		}
+	}
+
+	// Clearing a missing key is not an error.
+	if err := s.Clear(lex.Key{0x03}); err != nil {
+		t.Fatalf("Clear of a missing key: %v", err)
+	}
+
+	checkRange(t, s, ref, all(), lex.RangeOptions{})
+	checkRange(t, s, ref, all(), lex.RangeOptions{Reverse: true})
+}
+
+// testVisibility checks that writes are visible to the reads which follow
+// them, from any goroutine, and that reads return copies rather than views of
+// the store. It does not check that reads are isolated from concurrent
+// writes: lex.KVStore does not promise snapshots, and an Iterator reading in
+// batches sees the writes made between two batches.
+func testVisibility(t *testing.T, s lex.KVStore) {
+	keys := fixture()
+	ref := load(t, s, keys)
+
+	before, err := s.GetRange(all(), lex.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := ref.GetRange(all(), lex.RangeOptions{})
+
+	// Writes from another goroutine, adding, overwriting and clearing keys,
+	// are visible to subsequent reads through the same store once they
+	// return.
+	done := make(chan error)
+	go func() {
+		if err := s.Set(lex.Key{0x02}, []byte("new")); err != nil {
+			done <- err
+			return
+		}
+		if err := s.Set(keys[0], []byte("overwritten")); err != nil {
+			done <- err
+			return
+		}
+		done <- s.Clear(keys[1])
+	}()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	ref.Set(lex.Key{0x02}, []byte("new"))
+	ref.Set(keys[0], []byte("overwritten"))
+	ref.Clear(keys[1])
+	checkRange(t, s, ref, all(), lex.RangeOptions{})
+
+	// The results of earlier reads are not affected by later writes: they
+	// hold the keys and values read, not views of the store.
+	checkPairs(t, "earlier GetRange", before, want)
+
+	// Modifying the results of a read does not affect the store.
+	for _, kv := range before {
+		for i := range kv.Key {
+			kv.Key[i] = 0xAA
+		}
+		for i := range kv.Value {
+			kv.Value[i] = 0xAA
+		}
+	}
+	checkRange(t, s, ref, all(), lex.RangeOptions{})
+
+	k, err := s.GetKey(lex.FirstGreaterOrEqual(lex.Key{0x02}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k[0] = 0xAA
+	checkKey(t, s, ref, lex.FirstGreaterOrEqual(lex.Key{0x02}))
+}
+
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case lex.KeySelector:
+		return fmt.Sprintf("{%q, %v, %d}", v.Key.LexKey(), v.OrEqual, v.Offset)
+	case lex.Range:
+		b, e := v.LexRangeKeySelectors()
+		return fmt.Sprintf("[%s, %s)", describe(b.LexKeySelector()), describe(e.LexKeySelector()))
+	}
+	return fmt.Sprint(v)
+}
+
+func keysOf(kvs []lex.KeyValue) string {
+	var ks []string
+	for _, kv := range kvs {
+		ks = append(ks, fmt.Sprintf("%q", kv.Key))
+	}
+	return fmt.Sprint(ks)
+}
@@ -0,0 +1,13 @@
+package lextest
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go"
+)
+
+func TestConformance(t *testing.T) {
+	RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		return NewMemStore()
+	})
+}
@@ -0,0 +1,144 @@
+package lextest
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/abdullin/lex-go"
+)
+
+// MemStore is a simple in-memory implementation of lex.KVStore, kept as a
+// sorted slice. It is the reference model of the conformance suite, and is
+// useful on its own for testing layers without a storage engine. The zero
+// value is an empty store ready for use; a MemStore is safe for concurrent
+// use.
+type MemStore struct {
+	mu  sync.RWMutex
+	kvs []lex.KeyValue
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{}
+}
+
+// search returns the index of the first key greater than or equal to k.
+func (m *MemStore) search(k []byte) int {
+	return sort.Search(len(m.kvs), func(i int) bool {
+		return bytes.Compare(m.kvs[i].Key, k) >= 0
+	})
+}
+
+// resolve returns the index a key selector resolves to, which is -1 or
+// len(m.kvs) if it resolves before the first or past the last key.
+func (m *MemStore) resolve(sel lex.Selectable) int {
+	ks, n := sel.LexKeySelector().Normalize()
+	switch {
+	case ks.BeforeAll():
+		return -1
+	case ks.AfterAll():
+		return len(m.kvs)
+	}
+	k := []byte(ks.Key.LexKey())
+
+	// i is the index of the first key greater than (or equal to, unless
+	// OrEqual is set) k, that is the position of FirstGreaterThan or
+	// FirstGreaterOrEqual, which LastLessOrEqual and LastLessThan precede.
+	i := m.search(k)
+	if ks.OrEqual && i < len(m.kvs) && bytes.Equal(m.kvs[i].Key, k) {
+		i++
+	}
+	if !ks.Forward() {
+		i--
+	}
+	i += n
+
+	switch {
+	case i < 0:
+		return -1
+	case i > len(m.kvs):
+		return len(m.kvs)
+	}
+	return i
+}
+
+func (m *MemStore) key(i int) lex.Key {
+	switch {
+	case i < 0:
+		return lex.MinKey
+	case i >= len(m.kvs):
+		return lex.MaxAppKey
+	}
+	return clone(m.kvs[i].Key)
+}
+
+// GetRange implements lex.KVStore.
+func (m *MemStore) GetRange(r lex.Range, options lex.RangeOptions) ([]lex.KeyValue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bsel, esel := r.LexRangeKeySelectors()
+	begin, end := m.search(m.key(m.resolve(bsel))), m.search(m.key(m.resolve(esel)))
+
+	var kvs []lex.KeyValue
+	for n := 0; begin < end; n++ {
+		if options.Limit > 0 && n == options.Limit {
+			break
+		}
+		var kv lex.KeyValue
+		if options.Reverse {
+			end--
+			kv = m.kvs[end]
+		} else {
+			kv = m.kvs[begin]
+			begin++
+		}
+		kvs = append(kvs, lex.KeyValue{Key: clone(kv.Key), Value: clone(kv.Value)})
+	}
+	return kvs, nil
+}
+
+// GetKey implements lex.KVStore.
+func (m *MemStore) GetKey(sel lex.Selectable) (lex.Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.key(m.resolve(sel)), nil
+}
+
+// Set implements lex.KVStore.
+func (m *MemStore) Set(key lex.KeyConvertible, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key.LexKey()
+	i := m.search(k)
+	if i < len(m.kvs) && bytes.Equal(m.kvs[i].Key, k) {
+		m.kvs[i].Value = clone(value)
+		return nil
+	}
+	m.kvs = append(m.kvs, lex.KeyValue{})
+	copy(m.kvs[i+1:], m.kvs[i:])
+	m.kvs[i] = lex.KeyValue{Key: clone(k), Value: clone(value)}
+	return nil
+}
+
+// Clear implements lex.KVStore.
+func (m *MemStore) Clear(key lex.KeyConvertible) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key.LexKey()
+	i := m.search(k)
+	if i < len(m.kvs) && bytes.Equal(m.kvs[i].Key, k) {
+		m.kvs = append(m.kvs[:i], m.kvs[i+1:]...)
+	}
+	return nil
+}
+
+func clone(b []byte) []byte {
+	r := make([]byte, len(b))
+	copy(r, b)
+	return r
+}
@@ -0,0 +1,45 @@
+package lex
+
+import "fmt"
+
+// MaxKeySize is the largest key FoundationDB accepts, in bytes. Other stores
+// accept larger keys, but keeping within the limit keeps a keyspace portable.
+const MaxKeySize = 10000
+
+// MaxValueSize is the largest value FoundationDB accepts, in bytes.
+const MaxValueSize = 100000
+
+// ErrKeyTooLarge is the error returned for keys longer than MaxKeySize.
+type ErrKeyTooLarge struct {
+	Size int
+}
+
+func (e ErrKeyTooLarge) Error() string {
+	return fmt.Sprintf("key of %d bytes exceeds the limit of %d bytes", e.Size, MaxKeySize)
+}
+
+// ErrValueTooLarge is the error returned for values longer than MaxValueSize.
+type ErrValueTooLarge struct {
+	Size int
+}
+
+func (e ErrValueTooLarge) Error() string {
+	return fmt.Sprintf("value of %d bytes exceeds the limit of %d bytes", e.Size, MaxValueSize)
+}
+
+// CheckKey returns an ErrKeyTooLarge if the key is longer than MaxKeySize.
+func CheckKey(k KeyConvertible) error {
+	if n := len(bytesOf(k)); n > MaxKeySize {
+		return ErrKeyTooLarge{n}
+	}
+	return nil
+}
+
+// CheckValue returns an ErrValueTooLarge if the value is longer than
+// MaxValueSize.
+func CheckValue(v []byte) error {
+	if len(v) > MaxValueSize {
+		return ErrValueTooLarge{len(v)}
+	}
+	return nil
+}
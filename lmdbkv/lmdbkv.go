@@ -0,0 +1,233 @@
+// Package lmdbkv adapts LMDB, through lmdb-go
+// (https://github.com/bmatsuo/lmdb-go), to the lex.KVStore interface, giving
+// memory-mapped, read-heavy workloads access to the tuple and subspace layers.
+//
+// Key selectors are resolved by positioning an LMDB cursor with MDB_SET_RANGE
+// and stepping it with MDB_NEXT and MDB_PREV. Note that LMDB does not accept
+// empty keys, and limits key size to 511 bytes by default.
+package lmdbkv
+
+import (
+	"bytes"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Store implements lex.KVStore on top of a single LMDB database. Every call
+// runs in its own LMDB transaction; use Txn to group several calls into one.
+type Store struct {
+	env *lmdb.Env
+	dbi lmdb.DBI
+}
+
+// New returns a Store keeping its keys in the database dbi of env.
+func New(env *lmdb.Env, dbi lmdb.DBI) *Store {
+	return &Store{env, dbi}
+}
+
+// GetRange implements lex.KVStore in a read-only LMDB transaction.
+func (s *Store) GetRange(r lex.Range, options lex.RangeOptions) (kvs []lex.KeyValue, err error) {
+	err = s.env.View(func(txn *lmdb.Txn) error {
+		kvs, err = Txn(txn, s.dbi).GetRange(r, options)
+		return err
+	})
+	return
+}
+
+// GetKey implements lex.KVStore in a read-only LMDB transaction.
+func (s *Store) GetKey(sel lex.Selectable) (k lex.Key, err error) {
+	err = s.env.View(func(txn *lmdb.Txn) error {
+		k, err = Txn(txn, s.dbi).GetKey(sel)
+		return err
+	})
+	return
+}
+
+// Set implements lex.KVStore in a read-write LMDB transaction.
+func (s *Store) Set(key lex.KeyConvertible, value []byte) error {
+	return s.env.Update(func(txn *lmdb.Txn) error {
+		return Txn(txn, s.dbi).Set(key, value)
+	})
+}
+
+// Clear implements lex.KVStore in a read-write LMDB transaction.
+func (s *Store) Clear(key lex.KeyConvertible) error {
+	return s.env.Update(func(txn *lmdb.Txn) error {
+		return Txn(txn, s.dbi).Clear(key)
+	})
+}
+
+// Txn returns a lex.KVStore which performs all reads and writes on the
+// database dbi in the provided LMDB transaction. Writes require a read-write
+// transaction. The caller remains responsible for committing or aborting the
+// transaction.
+func Txn(txn *lmdb.Txn, dbi lmdb.DBI) lex.KVStore {
+	return txnStore{txn, dbi}
+}
+
+type txnStore struct {
+	txn *lmdb.Txn
+	dbi lmdb.DBI
+}
+
+// cursor wraps an LMDB cursor, turning "not found" into a nil key.
+type cursor struct {
+	c *lmdb.Cursor
+}
+
+func (c cursor) get(k []byte, op uint) ([]byte, []byte, error) {
+	k, v, err := c.c.Get(k, nil, op)
+	if lmdb.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	return k, v, err
+}
+
+// seekBefore positions the cursor on the last key less than k.
+func (c cursor) seekBefore(k []byte) ([]byte, []byte, error) {
+	ck, _, err := c.get(k, lmdb.SetRange)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ck == nil {
+		return c.get(nil, lmdb.Last)
+	}
+	return c.get(nil, lmdb.Prev)
+}
+
+func (t txnStore) cursor() (cursor, error) {
+	c, err := t.txn.OpenCursor(t.dbi)
+	return cursor{c}, err
+}
+
+func (t txnStore) GetKey(sel lex.Selectable) (lex.Key, error) {
+	ks, n := sel.LexKeySelector().Normalize()
+	switch {
+	case ks.BeforeAll():
+		return lex.MinKey, nil
+	case ks.AfterAll():
+		return lex.MaxAppKey, nil
+	}
+	k := []byte(ks.Key.LexKey())
+
+	// The first key greater than k is the first key greater than or equal to
+	// k+0x00, and the last key less than or equal to k is the last key less
+	// than k+0x00.
+	if ks.OrEqual {
+		k = append(append([]byte{}, k...), 0x00)
+	}
+
+	c, err := t.cursor()
+	if err != nil {
+		return nil, err
+	}
+	defer c.c.Close()
+
+	var ck []byte
+
+	if ks.Forward() {
+		// LMDB rejects empty keys: the first key greater than or equal to the
+		// empty key is the first key.
+		if len(k) == 0 {
+			ck, _, err = c.get(nil, lmdb.First)
+		} else {
+			ck, _, err = c.get(k, lmdb.SetRange)
+		}
+		for i := 0; i < n && ck != nil && err == nil; i++ {
+			ck, _, err = c.get(nil, lmdb.Next)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ck == nil {
+			return lex.MaxAppKey, nil
+		}
+		return lex.Key(ck), nil
+	}
+
+	ck, _, err = c.seekBefore(k)
+	for i := 0; i < -n && ck != nil && err == nil; i++ {
+		ck, _, err = c.get(nil, lmdb.Prev)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ck == nil {
+		return lex.MinKey, nil
+	}
+	return lex.Key(ck), nil
+}
+
+func (t txnStore) GetRange(r lex.Range, options lex.RangeOptions) ([]lex.KeyValue, error) {
+	bsel, esel := r.LexRangeKeySelectors()
+
+	begin, err := t.GetKey(bsel)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.GetKey(esel)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Compare(begin, end) >= 0 {
+		return nil, nil
+	}
+
+	c, err := t.cursor()
+	if err != nil {
+		return nil, err
+	}
+	defer c.c.Close()
+
+	var kvs []lex.KeyValue
+	var k, v []byte
+
+	switch {
+	case options.Reverse:
+		k, v, err = c.seekBefore(end)
+	case len(begin) == 0:
+		k, v, err = c.get(nil, lmdb.First)
+	default:
+		k, v, err = c.get(begin, lmdb.SetRange)
+	}
+
+	for err == nil && k != nil {
+		if options.Reverse && bytes.Compare(k, begin) < 0 {
+			break
+		}
+		if !options.Reverse && bytes.Compare(k, end) >= 0 {
+			break
+		}
+
+		kvs = append(kvs, lex.KeyValue{Key: lex.Key(k), Value: v})
+
+		if options.Limit > 0 && len(kvs) == options.Limit {
+			break
+		}
+
+		if options.Reverse {
+			k, v, err = c.get(nil, lmdb.Prev)
+		} else {
+			k, v, err = c.get(nil, lmdb.Next)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+func (t txnStore) Set(key lex.KeyConvertible, value []byte) error {
+	return t.txn.Put(t.dbi, key.LexKey(), value, 0)
+}
+
+func (t txnStore) Clear(key lex.KeyConvertible) error {
+	err := t.txn.Del(t.dbi, key.LexKey(), nil)
+	if lmdb.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
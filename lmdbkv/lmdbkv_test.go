@@ -0,0 +1,35 @@
+package lmdbkv
+
+import (
+	"testing"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+)
+
+func TestConformance(t *testing.T) {
+	lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		env, err := lmdb.NewEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { env.Close() })
+		if err := env.SetMapSize(1 << 24); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.Open(t.TempDir(), 0, 0644); err != nil {
+			t.Fatal(err)
+		}
+		var dbi lmdb.DBI
+		err = env.Update(func(txn *lmdb.Txn) (err error) {
+			dbi, err = txn.OpenRoot(0)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return New(env, dbi)
+	})
+}
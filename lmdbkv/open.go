@@ -0,0 +1,75 @@
+package lmdbkv
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+
+	"github.com/abdullin/lex-go"
+)
+
+// The "lmdb" adapter opens the LMDB environment in the given directory,
+// keeping keys in its database named by the "db" parameter (or in the
+// unnamed database by default). The "mapsize" parameter sets the size of the
+// memory map in bytes.
+func init() {
+	lex.RegisterAdapter("lmdb", func(path string, o lex.OpenOptions) (lex.KVStore, io.Closer, error) {
+		env, err := lmdb.NewEnv()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := configure(env, o); err != nil {
+			env.Close()
+			return nil, nil, err
+		}
+
+		var flags uint
+		if o.ReadOnly {
+			flags = lmdb.Readonly
+		}
+		if err := env.Open(path, flags, 0644); err != nil {
+			env.Close()
+			return nil, nil, err
+		}
+
+		var dbi lmdb.DBI
+		open := env.Update
+		if o.ReadOnly {
+			open = env.View
+		}
+		err = open(func(txn *lmdb.Txn) (err error) {
+			if name := o.Params["db"]; name != "" {
+				var create uint
+				if !o.ReadOnly {
+					create = lmdb.Create
+				}
+				dbi, err = txn.OpenDBI(name, create)
+				return err
+			}
+			dbi, err = txn.OpenRoot(0)
+			return err
+		})
+		if err != nil {
+			env.Close()
+			return nil, nil, err
+		}
+		return New(env, dbi), env, nil
+	})
+}
+
+func configure(env *lmdb.Env, o lex.OpenOptions) error {
+	if o.Params["db"] != "" {
+		if err := env.SetMaxDBs(1); err != nil {
+			return err
+		}
+	}
+	if s := o.Params["mapsize"]; s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		return env.SetMapSize(n)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package lex
+
+import (
+	"bytes"
+	"container/heap"
+)
+
+// MergeOptions control how Merge combines iterators.
+type MergeOptions struct {
+	// Reverse indicates that the iterators return keys in descending order,
+	// and that the merged iterator must too.
+	Reverse bool
+
+	// Tombstone, if set, reports whether a pair marks the deletion of its
+	// key, such as a pair of a write buffer recording a cleared key: the key
+	// is then skipped, hiding the pairs of the key in the iterators
+	// following the one of the tombstone.
+	Tombstone func(kv KeyValue) bool
+}
+
+// Merge returns an Iterator combining the pairs of several iterators over
+// sorted keys into a single sorted sequence. The iterators are given in order
+// of precedence: when several of them hold the same key, the pair of the
+// first one is returned and the others are skipped, so that a write buffer
+// given first overlays a snapshot scan given next. Closing the merged
+// iterator closes every iterator; the first error of an iterator stops the
+// merged iterator.
+func Merge(its []Iterator, o MergeOptions) Iterator {
+	m := &mergeIterator{its: its, o: o}
+	m.h.reverse = o.Reverse
+	return m
+}
+
+type mergeIterator struct {
+	its     []Iterator
+	o       MergeOptions
+	h       mergeHeap
+	started bool
+	cur     KeyValue
+	err     error
+}
+
+// mergeCursor is the current pair of an iterator, whose index is its
+// precedence.
+type mergeCursor struct {
+	kv    KeyValue
+	index int
+}
+
+type mergeHeap struct {
+	cursors []mergeCursor
+	reverse bool
+}
+
+func (h mergeHeap) Len() int { return len(h.cursors) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	c := bytes.Compare(h.cursors[i].kv.Key, h.cursors[j].kv.Key)
+	if h.reverse {
+		c = -c
+	}
+	if c != 0 {
+		return c < 0
+	}
+	return h.cursors[i].index < h.cursors[j].index
+}
+
+func (h mergeHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.cursors = append(h.cursors, x.(mergeCursor)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	c := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return c
+}
+
+// advance moves the iterator of index i to its next pair, pushing the pair
+// on the heap.
+func (m *mergeIterator) advance(i int) bool {
+	it := m.its[i]
+	if it.Next() {
+		heap.Push(&m.h, mergeCursor{KeyValue{it.Key(), it.Value()}, i})
+		return true
+	}
+	if err := it.Err(); err != nil {
+		m.err = err
+		return false
+	}
+	return true
+}
+
+func (m *mergeIterator) Next() bool {
+	if m.err != nil {
+		return false
+	}
+	if !m.started {
+		m.started = true
+		for i := range m.its {
+			if !m.advance(i) {
+				return false
+			}
+		}
+	}
+
+	for m.h.Len() > 0 {
+		top := heap.Pop(&m.h).(mergeCursor)
+		if !m.advance(top.index) {
+			return false
+		}
+		// Skip the pairs of the same key in iterators of lower precedence.
+		for m.h.Len() > 0 && bytes.Equal(m.h.cursors[0].kv.Key, top.kv.Key) {
+			c := heap.Pop(&m.h).(mergeCursor)
+			if !m.advance(c.index) {
+				return false
+			}
+		}
+		if m.o.Tombstone != nil && m.o.Tombstone(top.kv) {
+			continue
+		}
+		m.cur = top.kv
+		return true
+	}
+	return false
+}
+
+func (m *mergeIterator) Key() Key {
+	return m.cur.Key
+}
+
+func (m *mergeIterator) Value() []byte {
+	return m.cur.Value
+}
+
+func (m *mergeIterator) Err() error {
+	return m.err
+}
+
+func (m *mergeIterator) Close() error {
+	for _, it := range m.its {
+		if err := it.Close(); err != nil && m.err == nil {
+			m.err = err
+		}
+	}
+	m.h.cursors = nil
+	return m.err
+}
+
+// SliceIterator returns an Iterator over the pairs of a slice, such as a
+// sorted write buffer to overlay on a scan with Merge.
+func SliceIterator(kvs []KeyValue) Iterator {
+	return &sliceIterator{kvs: kvs, i: -1}
+}
+
+type sliceIterator struct {
+	kvs []KeyValue
+	i   int
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.i+1 >= len(it.kvs) {
+		it.i = len(it.kvs)
+		return false
+	}
+	it.i++
+	return true
+}
+
+func (it *sliceIterator) Key() Key {
+	return it.kvs[it.i].Key
+}
+
+func (it *sliceIterator) Value() []byte {
+	return it.kvs[it.i].Value
+}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+func (it *sliceIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package multimap provides the key scheme of multimaps on an ordered
+// key-value store: maps associating a key with any number of values, stored
+// as one entry (key, value) per pair, so that the values of a key are read
+// in order with a single range read and a pair is added or removed without
+// reading the other values of its key.
+//
+// Keys and values are tuple elements. Entries may carry a payload, such as a
+// count or the time the pair was added, stored as the value of their key.
+package multimap
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Multimap builds the keys of a multimap: (key, value) in Subspace.
+type Multimap struct {
+	Subspace subspace.Subspace
+}
+
+// New returns the Multimap in the subspace s.
+func New(s subspace.Subspace) Multimap {
+	return Multimap{Subspace: s}
+}
+
+// Entry returns the key of the entry of a pair.
+func (m Multimap) Entry(key, value tuple.Element) lex.Key {
+	return m.Subspace.Pack(tuple.Tuple{key, value})
+}
+
+// ParseEntry decodes the key of an entry.
+func (m Multimap) ParseEntry(k lex.KeyConvertible) (key, value tuple.Element, err error) {
+	t, err := m.Subspace.Unpack(k)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(t) != 2 {
+		return nil, nil, fmt.Errorf("multimap: expected (key, value), got %d elements", len(t))
+	}
+	return t[0], t[1], nil
+}
+
+// Range returns the range of the entries of a key.
+func (m Multimap) Range(key tuple.Element) lex.KeyRange {
+	b, e := m.Subspace.Sub(key).LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: e}
+}
+
+// Add adds a pair to the multimap, with an empty payload. Adding a pair which
+// is present already is not an error.
+func (m Multimap) Add(store lex.KVStore, key, value tuple.Element) error {
+	return store.Set(m.Entry(key, value), nil)
+}
+
+// AddWith adds a pair to the multimap with a payload, replacing the payload
+// of the pair if it is present already.
+func (m Multimap) AddWith(store lex.KVStore, key, value tuple.Element, payload []byte) error {
+	return store.Set(m.Entry(key, value), payload)
+}
+
+// Remove removes a pair from the multimap. Removing a missing pair is not an
+// error.
+func (m Multimap) Remove(store lex.KVStore, key, value tuple.Element) error {
+	return store.Clear(m.Entry(key, value))
+}
+
+// RemoveAll removes every pair of a key.
+func (m Multimap) RemoveAll(store lex.KVStore, key tuple.Element) error {
+	kvs, err := store.GetRange(m.Range(key), lex.RangeOptions{})
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		if err := store.Clear(kv.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Has returns true if the multimap holds the pair.
+func (m Multimap) Has(store lex.KVStore, key, value tuple.Element) (bool, error) {
+	v, err := lex.Get(store, m.Entry(key, value))
+	return v != nil, err
+}
+
+// Payload returns the payload of a pair, or nil if the multimap does not hold
+// the pair (see lex.Get).
+func (m Multimap) Payload(store lex.KVStore, key, value tuple.Element) ([]byte, error) {
+	return lex.Get(store, m.Entry(key, value))
+}
+
+// GetAll returns the values of a key, in the order of their packed
+// encodings, reading at most limit values if limit is positive.
+func (m Multimap) GetAll(store lex.KVStore, key tuple.Element, limit int) ([]tuple.Element, error) {
+	kvs, err := store.GetRange(m.Range(key), lex.RangeOptions{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	values := make([]tuple.Element, len(kvs))
+	for i, kv := range kvs {
+		if _, values[i], err = m.ParseEntry(kv.Key); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Count returns the number of values of a key, reading every entry of the
+// key.
+func (m Multimap) Count(store lex.KVStore, key tuple.Element) (int, error) {
+	kvs, err := store.GetRange(m.Range(key), lex.RangeOptions{})
+	return len(kvs), err
+}
@@ -0,0 +1,119 @@
+// Package pagination provides cursor pagination over ranges of keys, such as
+// subspaces exposed by HTTP APIs: a page of a range ends with an opaque
+// continuation token, from which the range of the next page is
+// reconstructed.
+//
+// Tokens carry the last key of a page, the direction of the scan and an HMAC
+// binding them to the range being paginated, so that a client can neither
+// forge nor alter a token, nor use a token of one range to read another.
+// Tokens are not encrypted: the last key of a page can be decoded from its
+// token, which must not be given to clients not allowed to see that key.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+
+	"github.com/abdullin/lex-go"
+)
+
+// ErrInvalidToken is returned for tokens which are malformed, were signed
+// with another secret, or were issued for another range or direction.
+var ErrInvalidToken = errors.New("pagination: invalid token")
+
+// tokenVersion is the first byte of tokens.
+const tokenVersion = 1
+
+// macSize is the size of the truncated HMAC of tokens.
+const macSize = 16
+
+// Pager issues and verifies the continuation tokens of paginated scans.
+type Pager struct {
+	secret []byte
+}
+
+// New returns a Pager signing tokens with the secret, which must be kept
+// secret and shared by every server accepting the tokens.
+func New(secret []byte) *Pager {
+	return &Pager{append([]byte{}, secret...)}
+}
+
+func (p *Pager) mac(r lex.ExactRange, payload []byte) []byte {
+	b, e := r.LexRangeKeys()
+	h := hmac.New(sha256.New, p.secret)
+	for _, part := range [][]byte{b.LexKey(), e.LexKey(), payload} {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(part)))
+		h.Write(n[:])
+		h.Write(part)
+	}
+	return h.Sum(nil)[:macSize]
+}
+
+// Token returns the token resuming a scan of the range after the key last,
+// the last key of a page, in the given direction.
+func (p *Pager) Token(r lex.ExactRange, last lex.KeyConvertible, reverse bool) string {
+	payload := []byte{tokenVersion, 0}
+	if reverse {
+		payload[1] = 1
+	}
+	payload = append(payload, last.LexKey()...)
+	return base64.RawURLEncoding.EncodeToString(append(payload, p.mac(r, payload)...))
+}
+
+// Resume returns the range of the keys of r following the key of the token
+// in its direction: the keys after it for a forward scan, and the keys
+// before it for a reverse scan. An empty token resumes nothing and returns r
+// itself. Resume returns ErrInvalidToken unless the token was issued by
+// Token for r and the direction, with the same secret.
+func (p *Pager) Resume(r lex.ExactRange, token string, reverse bool) (lex.KeyRange, error) {
+	b, e := r.LexRangeKeys()
+	if token == "" {
+		return lex.KeyRange{Begin: b, End: e}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < 2+macSize {
+		return lex.KeyRange{}, ErrInvalidToken
+	}
+	payload, mac := raw[:len(raw)-macSize], raw[len(raw)-macSize:]
+	if !hmac.Equal(mac, p.mac(r, payload)) || payload[0] != tokenVersion {
+		return lex.KeyRange{}, ErrInvalidToken
+	}
+	if payload[1] > 1 || (payload[1] == 1) != reverse {
+		return lex.KeyRange{}, ErrInvalidToken
+	}
+
+	last := append(lex.Key{}, payload[2:]...)
+	if reverse {
+		return lex.KeyRange{Begin: b, End: last}, nil
+	}
+	return lex.KeyRange{Begin: append(last, 0x00), End: e}, nil
+}
+
+// Page reads a page of at most limit pairs of the range, resuming after the
+// token if it is not empty, and returns the pairs with the token of the next
+// page. The next token is empty once the range is exhausted; a full page is
+// always followed by a token, even if the next page turns out to be empty.
+func (p *Pager) Page(store lex.KVStore, r lex.ExactRange, token string, limit int,
+	reverse bool) ([]lex.KeyValue, string, error) {
+	rr, err := p.Resume(r, token, reverse)
+	if err != nil {
+		return nil, "", err
+	}
+	if lex.Compare(rr.Begin, rr.End) >= 0 {
+		return nil, "", nil
+	}
+
+	kvs, err := store.GetRange(rr, lex.RangeOptions{Limit: limit, Reverse: reverse})
+	if err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 || len(kvs) < limit {
+		return kvs, "", nil
+	}
+	return kvs, p.Token(r, kvs[len(kvs)-1].Key, reverse), nil
+}
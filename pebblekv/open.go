@@ -0,0 +1,25 @@
+package pebblekv
+
+import (
+	"io"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/abdullin/lex-go"
+)
+
+// The "pebble" adapter opens the Pebble database in the given directory.
+// Writes are synced unless the "sync" parameter is "false".
+func init() {
+	lex.RegisterAdapter("pebble", func(path string, o lex.OpenOptions) (lex.KVStore, io.Closer, error) {
+		db, err := pebble.Open(path, &pebble.Options{ReadOnly: o.ReadOnly})
+		if err != nil {
+			return nil, nil, err
+		}
+		wo := pebble.Sync
+		if o.Params["sync"] == "false" {
+			wo = pebble.NoSync
+		}
+		return New(db, wo), db, nil
+	})
+}
@@ -0,0 +1,324 @@
+// Package pebblekv adapts Pebble (https://github.com/cockroachdb/pebble) to the
+// lex.KVStore interface, so that key schemas built from tuples and subspaces
+// can be reused in CockroachDB-style embedded deployments.
+//
+// Exact ranges (such as subspaces, tuples and lex.KeyRange) are translated
+// directly into the LowerBound and UpperBound of a Pebble iterator, letting
+// Pebble skip everything outside of the range. Other ranges are first
+// resolved to keys with GetKey.
+//
+// Store implements lex.IterableStore, iterating over ranges with Pebble
+// iterators, and kvutil.BulkLoader: sorted batches loaded with kvutil.Load
+// are written to sstables and ingested directly into the database.
+package pebblekv
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Store implements lex.KVStore on top of a Pebble reader and writer.
+type Store struct {
+	db *pebble.DB
+	r  pebble.Reader
+	w  pebble.Writer
+	wo *pebble.WriteOptions
+
+	// fs and dir are the file system of the database and the directory of
+	// fs in which LoadBatch writes the sstables it ingests.
+	fs  vfs.FS
+	dir string
+}
+
+// New returns a Store reading from and writing to the provided database.
+// Writes are performed with the given write options (for example pebble.Sync).
+// The database must use the default file system, vfs.Default; use NewFS for
+// other file systems.
+func New(db *pebble.DB, wo *pebble.WriteOptions) *Store {
+	return NewFS(db, vfs.Default, os.TempDir(), wo)
+}
+
+// NewFS is like New for a database opened on the file system fs, such as
+// vfs.NewMem(). LoadBatch writes the sstables it ingests in the directory dir
+// of fs, which it creates if needed, since the database reads them from its
+// own file system.
+func NewFS(db *pebble.DB, fs vfs.FS, dir string, wo *pebble.WriteOptions) *Store {
+	return &Store{db: db, r: db, w: db, wo: wo, fs: fs, dir: dir}
+}
+
+// NewBatch returns a Store reading from and writing to the provided batch,
+// which must have been created with NewIndexedBatch in order to serve reads.
+// The caller remains responsible for committing the batch.
+func NewBatch(b *pebble.Batch) *Store {
+	return &Store{r: b, w: b}
+}
+
+// bounds returns the exact keys delimiting the range, resolving key
+// selectors only when the range is not an lex.ExactRange. Exact ranges end at
+// lex.MaxAppKey at the latest, so that no range reaches the keys starting
+// with 0xFF.
+func (s *Store) bounds(r lex.Range) ([]byte, []byte, error) {
+	if er, ok := r.(lex.ExactRange); ok {
+		b, e := er.LexRangeKeys()
+		end := e.LexKey()
+		if bytes.Compare(end, lex.MaxAppKey) > 0 {
+			end = lex.MaxAppKey
+		}
+		return b.LexKey(), end, nil
+	}
+
+	bsel, esel := r.LexRangeKeySelectors()
+	begin, err := s.GetKey(bsel)
+	if err != nil {
+		return nil, nil, err
+	}
+	end, err := s.GetKey(esel)
+	if err != nil {
+		return nil, nil, err
+	}
+	return begin, end, nil
+}
+
+// GetRange implements lex.KVStore.
+func (s *Store) GetRange(r lex.Range, options lex.RangeOptions) ([]lex.KeyValue, error) {
+	begin, end, err := s.bounds(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Compare(begin, end) >= 0 {
+		return nil, nil
+	}
+
+	it, err := s.r.NewIter(&pebble.IterOptions{LowerBound: begin, UpperBound: end})
+	if err != nil {
+		return nil, err
+	}
+
+	var kvs []lex.KeyValue
+
+	valid := it.First()
+	if options.Reverse {
+		valid = it.Last()
+	}
+
+	for ; valid; valid = step(it, options.Reverse) {
+		v, err := it.ValueAndErr()
+		if err != nil {
+			it.Close()
+			return nil, err
+		}
+		kvs = append(kvs, lex.KeyValue{Key: lex.Key(clone(it.Key())), Value: clone(v)})
+
+		if options.Limit > 0 && len(kvs) == options.Limit {
+			break
+		}
+	}
+
+	if err := it.Close(); err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+func step(it *pebble.Iterator, reverse bool) bool {
+	if reverse {
+		return it.Prev()
+	}
+	return it.Next()
+}
+
+// NewIterator implements lex.IterableStore. The iterator holds a Pebble
+// iterator until it is closed.
+func (s *Store) NewIterator(r lex.Range, options lex.RangeOptions) lex.Iterator {
+	it := &iterator{reverse: options.Reverse, limit: options.Limit}
+	begin, end, err := s.bounds(r)
+	if err != nil || bytes.Compare(begin, end) >= 0 {
+		it.err = err
+		return it
+	}
+	it.it, it.err = s.r.NewIter(&pebble.IterOptions{LowerBound: begin, UpperBound: end})
+	return it
+}
+
+type iterator struct {
+	it       *pebble.Iterator
+	reverse  bool
+	started  bool
+	limit, n int
+	key      lex.Key
+	value    []byte
+	err      error
+}
+
+func (i *iterator) Next() bool {
+	if i.it == nil || i.err != nil || (i.limit > 0 && i.n == i.limit) {
+		return false
+	}
+
+	var valid bool
+	switch {
+	case i.started:
+		valid = step(i.it, i.reverse)
+	case i.reverse:
+		valid = i.it.Last()
+	default:
+		valid = i.it.First()
+	}
+	i.started = true
+	if !valid {
+		i.err = i.it.Error()
+		return false
+	}
+
+	v, err := i.it.ValueAndErr()
+	if err != nil {
+		i.err = err
+		return false
+	}
+	i.key, i.value = lex.Key(clone(i.it.Key())), clone(v)
+	i.n++
+	return true
+}
+
+func (i *iterator) Key() lex.Key {
+	return i.key
+}
+
+func (i *iterator) Value() []byte {
+	return i.value
+}
+
+func (i *iterator) Err() error {
+	return i.err
+}
+
+func (i *iterator) Close() error {
+	if i.it != nil {
+		if err := i.it.Close(); i.err == nil {
+			i.err = err
+		}
+		i.it = nil
+	}
+	return i.err
+}
+
+// GetKey implements lex.KVStore.
+func (s *Store) GetKey(sel lex.Selectable) (lex.Key, error) {
+	ks, n := sel.LexKeySelector().Normalize()
+	switch {
+	case ks.BeforeAll():
+		return lex.MinKey, nil
+	case ks.AfterAll():
+		return lex.MaxAppKey, nil
+	}
+	k := []byte(ks.Key.LexKey())
+
+	// The first key greater than k is the first key greater than or equal to
+	// k+0x00, and the last key less than or equal to k is the last key less
+	// than k+0x00.
+	if ks.OrEqual {
+		k = append(clone(k), 0x00)
+	}
+
+	it, err := s.r.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res lex.Key
+
+	if ks.Forward() {
+		valid := it.SeekGE(k)
+		for i := 0; i < n && valid; i++ {
+			valid = it.Next()
+		}
+		res = lex.MaxAppKey
+		if valid {
+			res = lex.Key(clone(it.Key()))
+		}
+	} else {
+		valid := it.SeekLT(k)
+		for i := 0; i < -n && valid; i++ {
+			valid = it.Prev()
+		}
+		res = lex.MinKey
+		if valid {
+			res = lex.Key(clone(it.Key()))
+		}
+	}
+
+	if err := it.Close(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Set implements lex.KVStore.
+func (s *Store) Set(key lex.KeyConvertible, value []byte) error {
+	return s.w.Set(key.LexKey(), value, s.wo)
+}
+
+// Clear implements lex.KVStore.
+func (s *Store) Clear(key lex.KeyConvertible) error {
+	return s.w.Delete(key.LexKey(), s.wo)
+}
+
+// LoadBatch implements kvutil.BulkLoader. For a Store created with New, the
+// pairs are written to an sstable which is ingested into the database,
+// bypassing the memtable and the write-ahead log. For a Store created with
+// NewBatch, they are set in the batch.
+func (s *Store) LoadBatch(kvs []lex.KeyValue) error {
+	if s.db == nil {
+		for _, kv := range kvs {
+			if err := s.w.Set(kv.Key, kv.Value, s.wo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.fs.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("pebblekv-load-%d-%d.sst", os.Getpid(), atomic.AddUint64(&loads, 1))
+	path := s.fs.PathJoin(s.dir, name)
+	f, err := s.fs.Create(path)
+	if err != nil {
+		return err
+	}
+	defer s.fs.Remove(path)
+
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+		TableFormat: s.db.FormatMajorVersion().MaxTableFormat(),
+	})
+	for _, kv := range kvs {
+		if err := w.Set(kv.Key, kv.Value); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return s.db.Ingest([]string{path})
+}
+
+// loads numbers the sstables written by LoadBatch, so that concurrent loads
+// write distinct files.
+var loads uint64
+
+func clone(b []byte) []byte {
+	r := make([]byte, len(b))
+	copy(r, b)
+	return r
+}
@@ -0,0 +1,71 @@
+package pebblekv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/kvutil"
+	"github.com/abdullin/lex-go/lextest"
+)
+
+func open(t *testing.T) *Store {
+	fs := vfs.NewMem()
+	db, err := pebble.Open("db", &pebble.Options{FS: fs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewFS(db, fs, "tmp", pebble.Sync)
+}
+
+func TestConformance(t *testing.T) {
+	lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		return open(t)
+	})
+}
+
+// sliceSource is a kvutil.Source reading pairs from a slice.
+type sliceSource []lex.KeyValue
+
+func (s *sliceSource) Next() (lex.KeyValue, error) {
+	if len(*s) == 0 {
+		return lex.KeyValue{}, io.EOF
+	}
+	kv := (*s)[0]
+	*s = (*s)[1:]
+	return kv, nil
+}
+
+func TestLoadBatch(t *testing.T) {
+	s := open(t)
+
+	var want []lex.KeyValue
+	for i := 0; i < 25; i++ {
+		want = append(want, lex.KeyValue{
+			Key:   lex.Key(fmt.Sprintf("k%03d", i)),
+			Value: []byte(fmt.Sprint(i)),
+		})
+	}
+	src := sliceSource(append([]lex.KeyValue{}, want...))
+	rep, err := kvutil.Load(s, &src, kvutil.LoadOptions{Options: kvutil.Options{BatchSize: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Keys != len(want) {
+		t.Errorf("Load reported %d keys, want %d", rep.Keys, len(want))
+	}
+
+	got, err := s.GetRange(lex.KeyRange{Begin: lex.MinKey, End: lex.MaxAppKey}, lex.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRange after Load = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+// Package protokey builds tuples from the fields of protocol buffer messages,
+// so that indexing pipelines can declare which fields make up a key instead
+// of writing the extraction by hand.
+package protokey
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/abdullin/lex-go/tuple"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Extractor builds tuples from the fields of messages of a single type.
+type Extractor struct {
+	desc  protoreflect.MessageDescriptor
+	paths [][]protoreflect.FieldDescriptor
+}
+
+// New returns an Extractor of the fields at the given paths in messages
+// described by desc. A path is a sequence of field names separated by dots,
+// such as "customer.id", where every field but the last is a singular
+// message field. New returns an error if a path does not lead to a singular
+// field of a kind which can be encoded in a tuple: bool (encoded as 0 or 1),
+// enum (encoded as its number), integers, string and bytes. Floating point
+// fields are not supported.
+func New(desc protoreflect.MessageDescriptor, paths ...string) (*Extractor, error) {
+	e := &Extractor{desc: desc}
+	for _, p := range paths {
+		fds, err := resolve(desc, p)
+		if err != nil {
+			return nil, err
+		}
+		e.paths = append(e.paths, fds)
+	}
+	return e, nil
+}
+
+func resolve(desc protoreflect.MessageDescriptor, path string) ([]protoreflect.FieldDescriptor, error) {
+	var fds []protoreflect.FieldDescriptor
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := desc.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, fmt.Errorf("protokey: %s has no field %s (in path %s)", desc.FullName(), name, path)
+		}
+		if fd.Cardinality() == protoreflect.Repeated {
+			return nil, fmt.Errorf("protokey: field %s is repeated (in path %s)", fd.FullName(), path)
+		}
+		fds = append(fds, fd)
+
+		kind := fd.Kind()
+		last := i == len(names)-1
+		switch {
+		case !last && kind != protoreflect.MessageKind && kind != protoreflect.GroupKind:
+			return nil, fmt.Errorf("protokey: field %s is not a message (in path %s)", fd.FullName(), path)
+		case !last:
+			desc = fd.Message()
+		case kind == protoreflect.MessageKind || kind == protoreflect.GroupKind,
+			kind == protoreflect.FloatKind || kind == protoreflect.DoubleKind:
+			return nil, fmt.Errorf("protokey: field %s of kind %s cannot be encoded in a tuple", fd.FullName(), kind)
+		}
+	}
+	return fds, nil
+}
+
+// Tuple returns the tuple of the values of the fields of the message, in the
+// order of the paths of the Extractor. The value of a field which is not set
+// is its default value, unless the field tracks presence (such as optional
+// fields and fields of messages), in which case it is nil. Tuple returns an
+// error if the message is not of the type of the Extractor, or if an uint64
+// field holds a value which does not fit in an int64.
+func (e *Extractor) Tuple(m proto.Message) (tuple.Tuple, error) {
+	msg := m.ProtoReflect()
+	if msg.Descriptor().FullName() != e.desc.FullName() {
+		return nil, fmt.Errorf("protokey: expected a message of type %s, got %s", e.desc.FullName(), msg.Descriptor().FullName())
+	}
+
+	t := make(tuple.Tuple, len(e.paths))
+	for i, fds := range e.paths {
+		el, err := value(msg, fds)
+		if err != nil {
+			return nil, err
+		}
+		t[i] = el
+	}
+	return t, nil
+}
+
+func value(msg protoreflect.Message, fds []protoreflect.FieldDescriptor) (tuple.Element, error) {
+	for _, fd := range fds[:len(fds)-1] {
+		if !msg.Has(fd) {
+			return nil, nil
+		}
+		msg = msg.Get(fd).Message()
+	}
+
+	fd := fds[len(fds)-1]
+	if fd.HasPresence() && !msg.Has(fd) {
+		return nil, nil
+	}
+	v := msg.Get(fd)
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if v.Bool() {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case protoreflect.EnumKind:
+		return int64(v.Enum()), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int(), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("protokey: value %d of field %s does not fit in an int64", v.Uint(), fd.FullName())
+		}
+		return int64(v.Uint()), nil
+	case protoreflect.StringKind:
+		return v.String(), nil
+	case protoreflect.BytesKind:
+		return append([]byte{}, v.Bytes()...), nil
+	}
+	return nil, fmt.Errorf("protokey: field %s of kind %s cannot be encoded in a tuple", fd.FullName(), fd.Kind())
+}
+
+// Extract returns the tuple of the values of the fields of the message at the
+// given paths. It is a shorthand for New and Extractor.Tuple, which resolves
+// the paths on every call.
+func Extract(m proto.Message, paths ...string) (tuple.Tuple, error) {
+	e, err := New(m.ProtoReflect().Descriptor(), paths...)
+	if err != nil {
+		return nil, err
+	}
+	return e.Tuple(m)
+}
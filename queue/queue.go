@@ -0,0 +1,188 @@
+// Package queue provides the key scheme of FIFO queues on an ordered
+// key-value store, such as the queues of background jobs: the items of a
+// queue are keyed by a sequence which sorts in the order they were pushed, so
+// the head of the queue is the first key of its subspace, and producers may
+// tag items with a deduplication id so that an item pushed twice is queued
+// once.
+//
+// Push numbers items with consecutive integers, recording the next number in
+// a counter key, so that numbers are never reused, even once the queue is
+// drained. Numbering reads and updates the counter, so concurrent producers
+// must push in transactions, or use PushAt with sequences which they order
+// themselves, such as ULIDs, version 7 UUIDs or versionstamps assigned by the
+// store at commit.
+//
+// Tasks queues order delayed tasks by priority and due time instead, for
+// consumers picking the tasks due at a given time, highest priority first.
+package queue
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Item is an item of a queue: its sequence and its value.
+type Item struct {
+	Seq   tuple.Element
+	Value []byte
+}
+
+// Queue builds the keys of a queue: (seq) in Items, holding the value of the
+// item, (id) in Dedup, holding the packed sequence of the item pushed with the
+// deduplication id, and Counter, holding the packed sequence of the next item
+// pushed by Push.
+type Queue struct {
+	Items   subspace.Subspace
+	Dedup   subspace.Subspace
+	Counter lex.Key
+}
+
+// New returns the Queue in the subspace s, whose items are stored under
+// s.Sub(0), whose deduplication ids are stored under s.Sub(1), and whose
+// counter is the key (2) of s.
+func New(s subspace.Subspace) Queue {
+	return Queue{Items: s.Sub(0), Dedup: s.Sub(1), Counter: s.Pack(tuple.Tuple{2})}
+}
+
+// Key returns the key of the item with the given sequence.
+func (q Queue) Key(seq tuple.Element) lex.Key {
+	return q.Items.Pack(tuple.Tuple{seq})
+}
+
+// ParseKey decodes the sequence of the key of an item.
+func (q Queue) ParseKey(key lex.KeyConvertible) (tuple.Element, error) {
+	t, err := q.Items.Unpack(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(t) != 1 {
+		return nil, fmt.Errorf("queue: expected (seq), got %d elements", len(t))
+	}
+	return t[0], nil
+}
+
+// DedupKey returns the key recording the item pushed with a deduplication id.
+func (q Queue) DedupKey(id tuple.Element) lex.Key {
+	return q.Dedup.Pack(tuple.Tuple{id})
+}
+
+// NextSeq returns the sequence of the next item pushed by Push: the sequence
+// recorded in the counter, or the sequence following the last item of the
+// queue if it is greater, such as after items were pushed with PushAt, or 0
+// for a new queue. Sequences are not reused once a queue is drained. NextSeq
+// returns an error if the last item does not have an integer sequence.
+func (q Queue) NextSeq(store lex.KVStore) (int64, error) {
+	var next int64
+	v, err := lex.Get(store, q.Counter)
+	if err != nil {
+		return 0, err
+	}
+	if v != nil {
+		t, err := tuple.Unpack(v)
+		if err != nil {
+			return 0, fmt.Errorf("queue: invalid counter: %v", err)
+		}
+		if next, err = t.GetInt(0); err != nil {
+			return 0, fmt.Errorf("queue: invalid counter: %v", err)
+		}
+	}
+
+	_, e := q.Items.LexRangeKeys()
+	last, err := store.GetKey(lex.LastLessThan(e))
+	if err != nil || !q.Items.Contains(last) {
+		return next, err
+	}
+	seq, err := q.ParseKey(last)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := seq.(int64)
+	if !ok {
+		return 0, fmt.Errorf("queue: expected an int64 sequence, got %T", seq)
+	}
+	if n+1 > next {
+		next = n + 1
+	}
+	return next, nil
+}
+
+// Push appends the value to the queue, and returns its sequence. The item
+// and the counter are written together, so that the sequence is not reused.
+func (q Queue) Push(store lex.KVStore, value []byte) (int64, error) {
+	seq, err := q.NextSeq(store)
+	if err != nil {
+		return 0, err
+	}
+	if err := q.PushAt(store, seq, value); err != nil {
+		return 0, err
+	}
+	return seq, store.Set(q.Counter, tuple.Tuple{seq + 1}.Pack())
+}
+
+// PushAt stores the value as the item with the given sequence, which must
+// sort after the sequences of the items pushed before it.
+func (q Queue) PushAt(store lex.KVStore, seq tuple.Element, value []byte) error {
+	return store.Set(q.Key(seq), value)
+}
+
+// PushOnce is like Push, but pushes the value only if no item was pushed
+// with the deduplication id before. It returns the sequence of the item
+// pushed with the id, and whether the value was pushed by this call. Ids
+// are remembered after their item is popped, until Forget is called.
+func (q Queue) PushOnce(store lex.KVStore, id tuple.Element, value []byte) (int64, bool, error) {
+	v, err := lex.Get(store, q.DedupKey(id))
+	if err != nil {
+		return 0, false, err
+	}
+	if v != nil {
+		t, err := tuple.Unpack(v)
+		if err != nil {
+			return 0, false, fmt.Errorf("queue: invalid sequence of id %v: %v", id, err)
+		}
+		seq, err := t.GetInt(0)
+		if err != nil {
+			return 0, false, fmt.Errorf("queue: invalid sequence of id %v: %v", id, err)
+		}
+		return seq, false, nil
+	}
+
+	seq, err := q.Push(store, value)
+	if err != nil {
+		return 0, false, err
+	}
+	return seq, true, store.Set(q.DedupKey(id), tuple.Tuple{seq}.Pack())
+}
+
+// Forget removes a deduplication id, so that a value can be pushed with it
+// again.
+func (q Queue) Forget(store lex.KVStore, id tuple.Element) error {
+	return store.Clear(q.DedupKey(id))
+}
+
+// Peek returns the item at the head of the queue, and false if the queue is
+// empty.
+func (q Queue) Peek(store lex.KVStore) (Item, bool, error) {
+	kvs, err := store.GetRange(q.Items, lex.RangeOptions{Limit: 1})
+	if err != nil || len(kvs) == 0 {
+		return Item{}, false, err
+	}
+	seq, err := q.ParseKey(kvs[0].Key)
+	if err != nil {
+		return Item{}, false, err
+	}
+	return Item{seq, kvs[0].Value}, true, nil
+}
+
+// Pop removes and returns the item at the head of the queue, and false if
+// the queue is empty. Concurrent consumers must pop in transactions, lest
+// they pop the same item.
+func (q Queue) Pop(store lex.KVStore) (Item, bool, error) {
+	it, ok, err := q.Peek(store)
+	if err != nil || !ok {
+		return it, ok, err
+	}
+	return it, true, store.Clear(q.Key(it.Seq))
+}
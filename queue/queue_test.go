@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go/lextest"
+	"github.com/abdullin/lex-go/subspace"
+)
+
+func TestQueue(t *testing.T) {
+	s := lextest.NewMemStore()
+	q := New(subspace.Sub("q"))
+	for i, v := range []string{"a", "b", "c"} {
+		seq, err := q.Push(s, []byte(v))
+		if err != nil || seq != int64(i) {
+			t.Fatal(seq, err)
+		}
+	}
+	seq, pushed, err := q.PushOnce(s, "job-1", []byte("d"))
+	if err != nil || !pushed || seq != 3 {
+		t.Fatal(seq, pushed, err)
+	}
+	seq, pushed, err = q.PushOnce(s, "job-1", []byte("d"))
+	if err != nil || pushed || seq != 3 {
+		t.Fatal(seq, pushed, err)
+	}
+	var got string
+	for {
+		it, ok, err := q.Pop(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		got += string(it.Value)
+	}
+	if got != "abcd" {
+		t.Fatal(got)
+	}
+	// Sequences are not reused once the queue is drained.
+	if n, err := q.NextSeq(s); err != nil || n != 4 {
+		t.Fatal(n, err)
+	}
+	if seq, pushed, err := q.PushOnce(s, "job-2", nil); err != nil || !pushed || seq != 4 {
+		t.Fatal(seq, pushed, err)
+	}
+	if err := q.Forget(s, "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	if seq, pushed, _ := q.PushOnce(s, "job-1", nil); !pushed || seq != 5 {
+		t.Fatal("forget", seq)
+	}
+
+	// Items pushed with PushAt move the counter forward.
+	if err := q.PushAt(s, int64(10), nil); err != nil {
+		t.Fatal(err)
+	}
+	if seq, err := q.Push(s, nil); err != nil || seq != 11 {
+		t.Fatal(seq, err)
+	}
+}
@@ -0,0 +1,150 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/encoding/timestamp"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Task is a task of a Tasks queue, decoded from its key.
+type Task struct {
+	// Shard is the shard of the task, 0 if the queue is not sharded.
+	Shard    int64
+	Priority int64
+	Due      time.Time
+	ID       tuple.Element
+	Value    []byte
+}
+
+// Tasks builds the keys of a queue of delayed tasks with priorities:
+// (priority, due, id) in Subspace, where higher priorities sort first and
+// due times are counted in milliseconds, so that the tasks due at a time are
+// read highest priority first, and earliest first within a priority.
+//
+// Every task of an unsharded queue is written to and read from the same end
+// of the subspace. A sharded queue spreads the tasks over Shards subspaces,
+// keyed by (shard, priority, due, id) with the shard picked by hashing the
+// id, and consumers read the shards independently.
+type Tasks struct {
+	Subspace subspace.Subspace
+	Shards   int
+}
+
+// NewTasks returns the Tasks queue in the subspace s, spread over the given
+// number of shards, or unsharded if shards is less than 2.
+func NewTasks(s subspace.Subspace, shards int) Tasks {
+	if shards < 2 {
+		shards = 0
+	}
+	return Tasks{Subspace: s, Shards: shards}
+}
+
+// Shard returns the shard of the task with the given id.
+func (t Tasks) Shard(id tuple.Element) int64 {
+	if t.Shards == 0 {
+		return 0
+	}
+	return int64(lex.Key(tuple.Tuple{id}.Pack()).Hash64() % uint64(t.Shards))
+}
+
+// shard returns the subspace of a shard.
+func (t Tasks) shard(shard int64) subspace.Subspace {
+	if t.Shards == 0 {
+		return t.Subspace
+	}
+	return t.Subspace.Sub(shard)
+}
+
+// Key returns the key of a task.
+func (t Tasks) Key(priority int64, due time.Time, id tuple.Element) lex.Key {
+	return t.shard(t.Shard(id)).Pack(tuple.Tuple{^priority, timestamp.Millis.Element(due), id})
+}
+
+// ParseKey decodes the key of a task, leaving its Value unset.
+func (t Tasks) ParseKey(key lex.KeyConvertible) (Task, error) {
+	tu, err := t.Subspace.Unpack(key)
+	if err != nil {
+		return Task{}, err
+	}
+	var task Task
+	if t.Shards != 0 {
+		if len(tu) == 0 {
+			return Task{}, fmt.Errorf("queue: expected (shard, priority, due, id), got no elements")
+		}
+		if task.Shard, err = tu.GetInt(0); err != nil {
+			return Task{}, fmt.Errorf("queue: %v", err)
+		}
+		tu = tu[1:]
+	}
+	if len(tu) != 3 {
+		return Task{}, fmt.Errorf("queue: expected (priority, due, id), got %d elements", len(tu))
+	}
+	p, err := tu.GetInt(0)
+	if err != nil {
+		return Task{}, fmt.Errorf("queue: %v", err)
+	}
+	if task.Due, err = timestamp.Millis.FromElement(tu[1]); err != nil {
+		return Task{}, err
+	}
+	task.Priority, task.ID = ^p, tu[2]
+	return task, nil
+}
+
+// Due returns the range of the tasks of a shard with the given priority which
+// are due at or before now, earliest first.
+func (t Tasks) Due(shard, priority int64, now time.Time) lex.KeyRange {
+	s := t.shard(shard).Sub(^priority)
+	b, _ := s.LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: s.Pack(tuple.Tuple{timestamp.Millis.Int(now) + 1})}
+}
+
+// Ready returns at most limit tasks of a shard which are due at or before
+// now, highest priority first, or every such task if limit is not positive.
+// It reads the range of Due for every priority of the shard in turn, which
+// costs a read per priority: queues should use a small set of priorities.
+func (t Tasks) Ready(store lex.KVStore, shard int64, now time.Time, limit int) ([]Task, error) {
+	s := t.shard(shard)
+	begin, end := s.LexRangeKeys()
+	cursor := begin.LexKey()
+
+	var tasks []Task
+	for limit <= 0 || len(tasks) < limit {
+		// Find the next priority holding tasks.
+		k, err := store.GetKey(lex.FirstGreaterOrEqual(cursor))
+		if err != nil {
+			return nil, err
+		}
+		if lex.Compare(k, end.LexKey()) >= 0 {
+			break
+		}
+		task, err := t.ParseKey(k)
+		if err != nil {
+			return nil, err
+		}
+
+		opts := lex.RangeOptions{}
+		if limit > 0 {
+			opts.Limit = limit - len(tasks)
+		}
+		kvs, err := store.GetRange(t.Due(shard, task.Priority, now), opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range kvs {
+			task, err := t.ParseKey(kv.Key)
+			if err != nil {
+				return nil, err
+			}
+			task.Value = kv.Value
+			tasks = append(tasks, task)
+		}
+
+		_, next := s.Sub(^task.Priority).LexRangeKeys()
+		cursor = next.LexKey()
+	}
+	return tasks, nil
+}
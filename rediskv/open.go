@@ -0,0 +1,28 @@
+package rediskv
+
+import (
+	"io"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/abdullin/lex-go"
+)
+
+// The "redis" adapter connects to the Redis server at the given URL (such as
+// redis://localhost:6379/0), keeping keys in the sorted set named after the
+// "name" parameter ("lex" by default), as New does. Redis has no read-only
+// mode.
+func init() {
+	lex.RegisterAdapter("redis", func(url string, o lex.OpenOptions) (lex.KVStore, io.Closer, error) {
+		opts, err := redis.ParseURL(url)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := o.Params["name"]
+		if name == "" {
+			name = "lex"
+		}
+		c := redis.NewClient(opts)
+		return New(c, name), c, nil
+	})
+}
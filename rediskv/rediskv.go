@@ -0,0 +1,181 @@
+// Package rediskv adapts Redis to the lex.KVStore interface, so that
+// lightweight deployments can use tuple-ordered keys.
+//
+// Keys are stored as members of a sorted set, all with score 0, which Redis
+// orders lexicographically by their bytes; values are stored in a hash keyed
+// by the same members. Ranges map onto ZRANGEBYLEX and ZREVRANGEBYLEX.
+package rediskv
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Store implements lex.KVStore on top of a Redis sorted set and hash.
+type Store struct {
+	c      redis.UniversalClient
+	ctx    context.Context
+	keys   string
+	values string
+}
+
+// New returns a Store keeping its keys in the sorted set named {name}, and
+// its values in the hash named {name}:values. The braces make name the hash
+// tag of both, which Redis Cluster then stores in the same slot, as the
+// scripts and transactions of the Store use them together.
+func New(c redis.UniversalClient, name string) *Store {
+	tag := "{" + name + "}"
+	return &Store{c, context.Background(), tag, tag + ":values"}
+}
+
+// WithContext returns a copy of the Store which issues all commands with the
+// provided context.
+func (s *Store) WithContext(ctx context.Context) *Store {
+	r := *s
+	r.ctx = ctx
+	return &r
+}
+
+// scan reads the members in [min, max] along with their values atomically.
+// min and max use the ZRANGEBYLEX syntax; a count of -1 means no limit.
+var scan = redis.NewScript(`
+local m
+if ARGV[4] == "1" then
+	m = redis.call("ZREVRANGEBYLEX", KEYS[1], ARGV[2], ARGV[1], "LIMIT", 0, ARGV[3])
+else
+	m = redis.call("ZRANGEBYLEX", KEYS[1], ARGV[1], ARGV[2], "LIMIT", 0, ARGV[3])
+end
+local r = {}
+for i = 1, #m, 1000 do
+	local chunk = {}
+	for j = i, math.min(i + 999, #m) do
+		chunk[#chunk + 1] = m[j]
+	end
+	local v = redis.call("HMGET", KEYS[2], unpack(chunk))
+	for j = 1, #chunk do
+		r[#r + 1] = chunk[j]
+		r[#r + 1] = v[j] or ""
+	end
+end
+return r
+`)
+
+// GetRange implements lex.KVStore. Exact ranges are read with a single
+// atomic script; other ranges first resolve their key selectors with GetKey,
+// which is not atomic with respect to the read that follows.
+func (s *Store) GetRange(r lex.Range, options lex.RangeOptions) ([]lex.KeyValue, error) {
+	var begin, end []byte
+
+	if er, ok := r.(lex.ExactRange); ok {
+		// Exact ranges end at lex.MaxAppKey at the latest, so that no range
+		// reaches the keys starting with 0xFF.
+		b, e := er.LexRangeKeys()
+		begin, end = b.LexKey(), e.LexKey()
+		if string(end) > string(lex.MaxAppKey) {
+			end = lex.MaxAppKey
+		}
+	} else {
+		bsel, esel := r.LexRangeKeySelectors()
+		var err error
+		if begin, err = s.GetKey(bsel); err != nil {
+			return nil, err
+		}
+		if end, err = s.GetKey(esel); err != nil {
+			return nil, err
+		}
+	}
+	if string(begin) >= string(end) {
+		return nil, nil
+	}
+
+	count := -1
+	if options.Limit > 0 {
+		count = options.Limit
+	}
+	reverse := "0"
+	if options.Reverse {
+		reverse = "1"
+	}
+
+	res, err := scan.Run(s.ctx, s.c, []string{s.keys, s.values},
+		"["+string(begin), "("+string(end), strconv.Itoa(count), reverse).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make([]lex.KeyValue, 0, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		kvs = append(kvs, lex.KeyValue{Key: lex.Key(res[i]), Value: []byte(res[i+1])})
+	}
+	return kvs, nil
+}
+
+// GetKey implements lex.KVStore.
+func (s *Store) GetKey(sel lex.Selectable) (lex.Key, error) {
+	ks, n := sel.LexKeySelector().Normalize()
+	switch {
+	case ks.BeforeAll():
+		return lex.MinKey, nil
+	case ks.AfterAll():
+		return lex.MaxAppKey, nil
+	}
+	k := string(ks.Key.LexKey())
+
+	if ks.Forward() {
+		// The first key greater than (or equal to, unless OrEqual is set) the
+		// selector key, then n keys forward.
+		min := "[" + k
+		if ks.OrEqual {
+			min = "(" + k
+		}
+		res, err := s.c.ZRangeByLex(s.ctx, s.keys, &redis.ZRangeBy{Min: min, Max: "+", Offset: int64(n), Count: 1}).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 0 {
+			return lex.MaxAppKey, nil
+		}
+		return lex.Key(res[0]), nil
+	}
+
+	// The last key less than (or equal to, if OrEqual is set) the selector
+	// key, then -n keys backward.
+	max := "(" + k
+	if ks.OrEqual {
+		max = "[" + k
+	}
+	res, err := s.c.ZRevRangeByLex(s.ctx, s.keys, &redis.ZRangeBy{Min: "-", Max: max, Offset: int64(-n), Count: 1}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return lex.MinKey, nil
+	}
+	return lex.Key(res[0]), nil
+}
+
+// Set implements lex.KVStore.
+func (s *Store) Set(key lex.KeyConvertible, value []byte) error {
+	k := string(key.LexKey())
+	_, err := s.c.TxPipelined(s.ctx, func(p redis.Pipeliner) error {
+		p.ZAdd(s.ctx, s.keys, redis.Z{Score: 0, Member: k})
+		p.HSet(s.ctx, s.values, k, value)
+		return nil
+	})
+	return err
+}
+
+// Clear implements lex.KVStore.
+func (s *Store) Clear(key lex.KeyConvertible) error {
+	k := string(key.LexKey())
+	_, err := s.c.TxPipelined(s.ctx, func(p redis.Pipeliner) error {
+		p.ZRem(s.ctx, s.keys, k)
+		p.HDel(s.ctx, s.values, k)
+		return nil
+	})
+	return err
+}
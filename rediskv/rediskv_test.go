@@ -0,0 +1,30 @@
+package rediskv
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+)
+
+// TestConformance runs against the Redis server at the address in the
+// LEX_REDIS_ADDR environment variable, and is skipped if it is not set. The
+// keys of every subtest are deleted once it completes.
+func TestConformance(t *testing.T) {
+	addr := os.Getenv("LEX_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("LEX_REDIS_ADDR is not set")
+	}
+	c := redis.NewClient(&redis.Options{Addr: addr})
+	defer c.Close()
+
+	lextest.RunAdapterSuite(t, func(t *testing.T) lex.KVStore {
+		s := New(c, "lextest:"+t.Name())
+		t.Cleanup(func() { c.Del(context.Background(), s.keys, s.values) })
+		return s
+	})
+}
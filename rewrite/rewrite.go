@@ -0,0 +1,270 @@
+// Package rewrite compiles declarative, element-level rewrite rules into a
+// transformer over packed tuple keys. It is intended to drive large-scale key
+// migrations: rules such as "lowercase the string at position 2" or "insert a
+// constant at position 0" are declared once and applied to millions of keys.
+//
+// A compiled Transformer works on the spans of a packed key (see tuple.Spans):
+// elements untouched by the rules are copied verbatim without being decoded,
+// constants are encoded once at compile time, and each rewritten key is built
+// with a single allocation.
+package rewrite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// A Rule is a single element-level rewrite. Rules are applied in the order in
+// which they are passed to Compile, and element positions always refer to the
+// key as rewritten by the preceding rules.
+type Rule interface {
+	apply(k *key) error
+	compile() error
+}
+
+// key holds the encoded elements of a key being rewritten. Elements which
+// have not been rewritten alias the source key.
+type key [][]byte
+
+func (k key) check(pos int) error {
+	if pos < 0 || pos >= len(k) {
+		return fmt.Errorf("element %d out of range: key has %d elements", pos, len(k))
+	}
+	return nil
+}
+
+func encode(el tuple.Element) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return tuple.Tuple{el}.Pack(), nil
+}
+
+func checkPos(pos int) error {
+	if pos < 0 {
+		return fmt.Errorf("negative element position %d", pos)
+	}
+	return nil
+}
+
+type mapRule struct {
+	pos int
+	fn  func(tuple.Element) (tuple.Element, error)
+}
+
+// Map returns a Rule which decodes the element at pos, passes it to fn and
+// replaces it with the encoding of the result.
+func Map(pos int, fn func(tuple.Element) (tuple.Element, error)) Rule {
+	return mapRule{pos, fn}
+}
+
+func (r mapRule) compile() error {
+	if r.fn == nil {
+		return errors.New("map rule has no function")
+	}
+	return checkPos(r.pos)
+}
+
+func (r mapRule) apply(k *key) error {
+	if err := k.check(r.pos); err != nil {
+		return err
+	}
+	t, err := tuple.Unpack((*k)[r.pos])
+	if err != nil {
+		return err
+	}
+	el, err := r.fn(t[0])
+	if err != nil {
+		return fmt.Errorf("element %d: %v", r.pos, err)
+	}
+	b, err := encode(el)
+	if err != nil {
+		return fmt.Errorf("element %d: %v", r.pos, err)
+	}
+	(*k)[r.pos] = b
+	return nil
+}
+
+// Lowercase returns a Rule which lowercases the string element at pos. The
+// rule fails on keys where the element is not a string.
+func Lowercase(pos int) Rule {
+	return Map(pos, func(el tuple.Element) (tuple.Element, error) {
+		s, ok := el.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, found %T", el)
+		}
+		return strings.ToLower(s), nil
+	})
+}
+
+type setRule struct {
+	pos    int
+	el     tuple.Element
+	b      []byte
+	insert bool
+}
+
+// Set returns a Rule which replaces the element at pos with the constant el.
+func Set(pos int, el tuple.Element) Rule {
+	return &setRule{pos: pos, el: el}
+}
+
+// Insert returns a Rule which inserts the constant el at pos, shifting the
+// following elements. A position equal to the number of elements appends el.
+func Insert(pos int, el tuple.Element) Rule {
+	return &setRule{pos: pos, el: el, insert: true}
+}
+
+func (r *setRule) compile() (err error) {
+	if err = checkPos(r.pos); err != nil {
+		return err
+	}
+	r.b, err = encode(r.el)
+	return err
+}
+
+func (r *setRule) apply(k *key) error {
+	if !r.insert {
+		if err := k.check(r.pos); err != nil {
+			return err
+		}
+		(*k)[r.pos] = r.b
+		return nil
+	}
+	if r.pos > len(*k) {
+		return fmt.Errorf("cannot insert at %d: key has %d elements", r.pos, len(*k))
+	}
+	*k = append(*k, nil)
+	copy((*k)[r.pos+1:], (*k)[r.pos:])
+	(*k)[r.pos] = r.b
+	return nil
+}
+
+type deleteRule int
+
+// Delete returns a Rule which removes the element at pos.
+func Delete(pos int) Rule {
+	return deleteRule(pos)
+}
+
+func (r deleteRule) compile() error {
+	return checkPos(int(r))
+}
+
+func (r deleteRule) apply(k *key) error {
+	if err := k.check(int(r)); err != nil {
+		return err
+	}
+	*k = append((*k)[:r], (*k)[r+1:]...)
+	return nil
+}
+
+type moveRule struct {
+	from, to int
+}
+
+// Move returns a Rule which moves the element at from so that it ends up at
+// position to, shifting the elements in between.
+func Move(from, to int) Rule {
+	return moveRule{from, to}
+}
+
+func (r moveRule) compile() error {
+	if err := checkPos(r.from); err != nil {
+		return err
+	}
+	return checkPos(r.to)
+}
+
+func (r moveRule) apply(k *key) error {
+	if err := k.check(r.from); err != nil {
+		return err
+	}
+	if err := k.check(r.to); err != nil {
+		return err
+	}
+	el := (*k)[r.from]
+	if r.from < r.to {
+		copy((*k)[r.from:r.to], (*k)[r.from+1:r.to+1])
+	} else {
+		copy((*k)[r.to+1:r.from+1], (*k)[r.to:r.from])
+	}
+	(*k)[r.to] = el
+	return nil
+}
+
+// Transformer applies a compiled list of rules to packed keys. A Transformer
+// is safe for concurrent use.
+type Transformer struct {
+	rules []Rule
+}
+
+// Compile validates the rules and pre-encodes their constants, returning a
+// Transformer which applies them in order.
+func Compile(rules ...Rule) (*Transformer, error) {
+	for i, r := range rules {
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+	}
+	return &Transformer{rules}, nil
+}
+
+// Apply rewrites the packed tuple b and returns the rewritten packed tuple. b
+// itself is never modified.
+func (t *Transformer) Apply(b []byte) ([]byte, error) {
+	spans, err := tuple.Spans(b)
+	if err != nil {
+		return nil, err
+	}
+
+	k := make(key, len(spans))
+	for i, s := range spans {
+		k[i] = b[s.Begin:s.End]
+	}
+
+	for i, r := range t.rules {
+		if err := r.apply(&k); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+	}
+
+	var n int
+	for _, el := range k {
+		n += len(el)
+	}
+	out := make([]byte, 0, n)
+	for _, el := range k {
+		out = append(out, el...)
+	}
+	return out, nil
+}
+
+// Rewrite rewrites a key from the subspace from: the prefix of from is
+// removed, the remaining tuple is rewritten with Apply, and the prefix of to
+// is prepended to the result.
+func (t *Transformer) Rewrite(k lex.KeyConvertible, from, to subspace.Subspace) (lex.Key, error) {
+	b := k.LexKey()
+	if !bytes.HasPrefix(b, from.Bytes()) {
+		return nil, errors.New("key is not in subspace")
+	}
+
+	r, err := t.Apply(b[len(from.Bytes()):])
+	if err != nil {
+		return nil, err
+	}
+
+	p := to.Bytes()
+	out := make([]byte, len(p)+len(r))
+	copy(out, p)
+	copy(out[len(p):], r)
+	return lex.Key(out), nil
+}
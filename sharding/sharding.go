@@ -0,0 +1,102 @@
+// Package sharding spreads keys over hash buckets, so that sequential keys,
+// such as keys holding a timestamp or a counter, are not all written to the
+// same end of the key space, where a single node of a distributed store would
+// take every write.
+//
+// The key of a tuple t in a Sharded subspace s is (bucket, t...) in s, where
+// bucket is a hash of t modulo the number of buckets. The keys of a range of
+// tuples are found in every bucket, so that reading them takes one range per
+// bucket, which the range constructors of Sharded return.
+package sharding
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Sharded builds the keys of a subspace spread over a number of buckets.
+type Sharded struct {
+	Subspace subspace.Subspace
+	Buckets  int
+}
+
+// New returns the Sharded subspace s spread over the given number of buckets.
+// The number of buckets is part of the layout of the keys: changing it moves
+// the keys to other buckets. New will panic if buckets is less than 1.
+func New(s subspace.Subspace, buckets int) Sharded {
+	if buckets < 1 {
+		panic(fmt.Sprintf("sharding: invalid number of buckets %d", buckets))
+	}
+	return Sharded{Subspace: s, Buckets: buckets}
+}
+
+// Bucket returns the bucket of the tuple, which only depends on the encoding
+// of the tuple and on the number of buckets.
+func (sh Sharded) Bucket(t tuple.Tuple) int64 {
+	return int64(lex.Key(t.Pack()).Hash64() % uint64(sh.Buckets))
+}
+
+// Bucketspace returns the subspace of a bucket.
+func (sh Sharded) Bucketspace(bucket int64) subspace.Subspace {
+	return sh.Subspace.Sub(bucket)
+}
+
+// Pack returns the key of the tuple, in its bucket.
+func (sh Sharded) Pack(t tuple.Tuple) lex.Key {
+	return sh.Bucketspace(sh.Bucket(t)).Pack(t)
+}
+
+// Unpack returns the tuple of a key, and its bucket. It returns an error if
+// the key is not in the subspace, or is not in the bucket of its tuple.
+func (sh Sharded) Unpack(k lex.KeyConvertible) (tuple.Tuple, int64, error) {
+	t, err := sh.Subspace.Unpack(k)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(t) == 0 {
+		return nil, 0, fmt.Errorf("sharding: key %q has no bucket", []byte(k.LexKey()))
+	}
+	bucket, err := t.GetInt(0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("sharding: key %q: %v", []byte(k.LexKey()), err)
+	}
+	t = t[1:]
+	if want := sh.Bucket(t); bucket != want {
+		return nil, 0, fmt.Errorf("sharding: key %q is in bucket %d, expected %d", []byte(k.LexKey()), bucket, want)
+	}
+	return t, bucket, nil
+}
+
+// All returns the ranges of every bucket, which together hold every key,
+// including the key of the empty tuple.
+func (sh Sharded) All() []lex.KeyRange {
+	rs := sh.Prefix(nil)
+	rs[sh.Bucket(nil)].Begin = sh.Pack(nil)
+	return rs
+}
+
+// Prefix returns the ranges, one per bucket, of the keys of the tuples
+// starting with the elements of prefix, other than the prefix itself.
+func (sh Sharded) Prefix(prefix tuple.Tuple) []lex.KeyRange {
+	rs := make([]lex.KeyRange, sh.Buckets)
+	for i := range rs {
+		b, e := sh.Bucketspace(int64(i)).Sub(prefix...).LexRangeKeys()
+		rs[i] = lex.KeyRange{Begin: b, End: e}
+	}
+	return rs
+}
+
+// Between returns the ranges, one per bucket, of the keys of the tuples from
+// begin (inclusive) to end (exclusive), such as the keys of a period of time
+// when the tuples start with a timestamp.
+func (sh Sharded) Between(begin, end tuple.Tuple) []lex.KeyRange {
+	rs := make([]lex.KeyRange, sh.Buckets)
+	for i := range rs {
+		s := sh.Bucketspace(int64(i))
+		rs[i] = lex.KeyRange{Begin: s.Pack(begin), End: s.Pack(end)}
+	}
+	return rs
+}
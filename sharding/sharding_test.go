@@ -0,0 +1,37 @@
+package sharding
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/lextest"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+func TestAll(t *testing.T) {
+	sh := New(subspace.Sub("s"), 4)
+	s := lextest.NewMemStore()
+	ts := []tuple.Tuple{nil, {"a"}, {"b"}, {int64(1)}, {"a", int64(2)}}
+	for _, tu := range ts {
+		s.Set(sh.Pack(tu), []byte("v"))
+	}
+	s.Set(subspace.Sub("t").Pack(nil), []byte("v"))
+
+	n := 0
+	for _, r := range sh.All() {
+		kvs, err := s.GetRange(r, lex.RangeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, kv := range kvs {
+			if _, _, err := sh.Unpack(kv.Key); err != nil {
+				t.Errorf("All holds %q: %v", kv.Key, err)
+			}
+		}
+		n += len(kvs)
+	}
+	if n != len(ts) {
+		t.Errorf("All holds %d keys, want %d", n, len(ts))
+	}
+}
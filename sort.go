@@ -0,0 +1,21 @@
+package lex
+
+import (
+	"bytes"
+	"sort"
+)
+
+// KeySlice attaches the methods of sort.Interface to [][]byte, sorting in
+// increasing lexicographic order.
+type KeySlice [][]byte
+
+func (p KeySlice) Len() int           { return len(p) }
+func (p KeySlice) Less(i, j int) bool { return bytes.Compare(p[i], p[j]) < 0 }
+func (p KeySlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// SortKeys sorts a slice of keys in increasing lexicographic order, the order
+// in which they are stored. Writing keys in that order is usually much faster
+// with stores based on LSM trees, or B-trees filled sequentially.
+func SortKeys(keys [][]byte) {
+	sort.Sort(KeySlice(keys))
+}
@@ -0,0 +1,50 @@
+package lex
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+)
+
+// sortable64 is a variant of base64 whose alphabet is in ASCII order, so that
+// the encoded strings sort like the bytes they encode. It is URL-safe.
+var sortable64 = base64.NewEncoding("-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz").
+	WithPadding(base64.NoPadding).Strict()
+
+// sortable32 is the base32 encoding with the extended hex alphabet, which is
+// in ASCII order, without padding.
+var sortable32 = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+// SortableString returns the key encoded in a variant of base64 whose
+// alphabet (-, digits, upper case letters, _ and lower case letters) is in
+// ASCII order, without padding. The strings of two keys compare like the
+// keys, so they can be used as sort keys by systems which only accept
+// strings, such as the sort keys of DynamoDB or the names of S3 objects.
+func (k Key) SortableString() string {
+	return sortable64.EncodeToString(k)
+}
+
+// ParseSortableString decodes a string returned by Key.SortableString.
+func ParseSortableString(s string) (Key, error) {
+	b, err := sortable64.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Key(b), nil
+}
+
+// SortableBase32String is like SortableString, but encodes the key in base32
+// with the extended hex alphabet (digits and upper case letters from A to V),
+// for systems which do not preserve the case of strings.
+func (k Key) SortableBase32String() string {
+	return sortable32.EncodeToString(k)
+}
+
+// ParseSortableBase32String decodes a string returned by
+// Key.SortableBase32String.
+func ParseSortableBase32String(s string) (Key, error) {
+	b, err := sortable32.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Key(b), nil
+}
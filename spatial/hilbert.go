@@ -0,0 +1,66 @@
+package spatial
+
+// axesToTranspose converts the coordinates of a point into the transposed
+// form of its position along the Hilbert curve, in place: interleaving the
+// bits of the transposed form yields the position. This is the algorithm of
+// J. Skilling, "Programming the Hilbert curve" (AIP Conference Proceedings
+// 707, 2004).
+func axesToTranspose(x []uint64, bits int) {
+	m := uint64(1) << uint(bits-1)
+	n := len(x)
+
+	// Inverse undo.
+	for q := m; q > 1; q >>= 1 {
+		p := q - 1
+		for i := 0; i < n; i++ {
+			if x[i]&q != 0 {
+				x[0] ^= p
+			} else {
+				t := (x[0] ^ x[i]) & p
+				x[0] ^= t
+				x[i] ^= t
+			}
+		}
+	}
+
+	// Gray encode.
+	for i := 1; i < n; i++ {
+		x[i] ^= x[i-1]
+	}
+	var t uint64
+	for q := m; q > 1; q >>= 1 {
+		if x[n-1]&q != 0 {
+			t ^= q - 1
+		}
+	}
+	for i := range x {
+		x[i] ^= t
+	}
+}
+
+// transposeToAxes is the inverse of axesToTranspose.
+func transposeToAxes(x []uint64, bits int) {
+	end := uint64(2) << uint(bits-1)
+	n := len(x)
+
+	// Gray decode.
+	t := x[n-1] >> 1
+	for i := n - 1; i > 0; i-- {
+		x[i] ^= x[i-1]
+	}
+	x[0] ^= t
+
+	// Undo excess work.
+	for q := uint64(2); q != end; q <<= 1 {
+		p := q - 1
+		for i := n - 1; i >= 0; i-- {
+			if x[i]&q != 0 {
+				x[0] ^= p
+			} else {
+				t := (x[0] ^ x[i]) & p
+				x[0] ^= t
+				x[i] ^= t
+			}
+		}
+	}
+}
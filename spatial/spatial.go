@@ -0,0 +1,311 @@
+// Package spatial maps points of 2 to 4 dimensions, such as latitude and
+// longitude cells, to positions along a space-filling curve, the Z-order
+// curve or the Hilbert curve, so that they can
+// be stored in the keys of an ordered key-value store: points close in space
+// tend to have close positions, and a box query decomposes into a small set
+// of key ranges.
+//
+// A Curve maps points whose coordinates are integers of a fixed number of
+// bits, so that applications quantize their coordinates first (for instance,
+// latitudes from -90 to 90 to integers from 0 to 2^32-1). The position of a
+// point is an integer of Dims×Bits bits, which is encoded in keys as a
+// fixed-width, big-endian fragment of Size bytes: fragments sort like
+// positions, whether appended to raw keys or packed as []byte tuple
+// elements.
+package spatial
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// DefaultMaxRanges is the number of ranges box queries are decomposed into
+// when no maximum is given.
+const DefaultMaxRanges = 32
+
+// Curve is a space-filling curve over points of Dims dimensions, whose
+// coordinates are integers of Bits bits. The curves of this package share
+// their API, so that an index picks its curve when it is created and the
+// rest of the code does not depend on it.
+type Curve struct {
+	dims, bits int
+	hilbert    bool
+}
+
+// ZOrder returns the Z-order curve (or Morton order), whose positions
+// interleave the bits of the coordinates of points, which is the cheapest to
+// compute. ZOrder will panic unless dims is 2, 3 or 4, and bits is between 1
+// and 32 and such that positions fit in 64 bits.
+func ZOrder(dims, bits int) *Curve {
+	return newCurve(dims, bits, false)
+}
+
+// Hilbert returns the Hilbert curve, whose consecutive positions are always
+// neighbouring points, and whose locality is better than the locality of the
+// Z-order curve: box queries decompose into fewer ranges, and ranges
+// covering more points than the box (see Ranges) hold fewer extra points.
+// Hilbert will panic in the same circumstances as ZOrder.
+func Hilbert(dims, bits int) *Curve {
+	return newCurve(dims, bits, true)
+}
+
+func newCurve(dims, bits int, hilbert bool) *Curve {
+	if dims < 2 || dims > 4 {
+		panic(fmt.Sprintf("spatial: %d dimensions, expected 2 to 4", dims))
+	}
+	if bits < 1 || bits > 32 || dims*bits > 64 {
+		panic(fmt.Sprintf("spatial: %d bits per dimension, expected 1 to %d", bits, 64/dims))
+	}
+	return &Curve{dims: dims, bits: bits, hilbert: hilbert}
+}
+
+// String returns the name of the curve, with its dimensions and bits, such
+// as hilbert(2, 16).
+func (c *Curve) String() string {
+	name := "zorder"
+	if c.hilbert {
+		name = "hilbert"
+	}
+	return fmt.Sprintf("%s(%d, %d)", name, c.dims, c.bits)
+}
+
+// Dims returns the number of dimensions of the points of the curve.
+func (c *Curve) Dims() int {
+	return c.dims
+}
+
+// Bits returns the number of bits of the coordinates of the points of the
+// curve.
+func (c *Curve) Bits() int {
+	return c.bits
+}
+
+// Size returns the length of the fragments encoding positions.
+func (c *Curve) Size() int {
+	return (c.dims*c.bits + 7) / 8
+}
+
+// Index returns the position of a point along the curve. Index will panic if
+// the point does not have Dims coordinates of at most Bits bits.
+func (c *Curve) Index(coords ...uint32) uint64 {
+	if len(coords) != c.dims {
+		panic(fmt.Sprintf("spatial: point has %d coordinates, expected %d", len(coords), c.dims))
+	}
+	for _, x := range coords {
+		if c.bits < 32 && x>>uint(c.bits) != 0 {
+			panic(fmt.Sprintf("spatial: coordinate %d does not fit in %d bits", x, c.bits))
+		}
+	}
+	x := make([]uint64, c.dims)
+	for d, v := range coords {
+		x[d] = uint64(v)
+	}
+	if c.hilbert {
+		axesToTranspose(x, c.bits)
+	}
+	var i uint64
+	for j := c.bits - 1; j >= 0; j-- {
+		for _, v := range x {
+			i = i<<1 | v>>uint(j)&1
+		}
+	}
+	return i
+}
+
+// Coords returns the point at a position along the curve.
+func (c *Curve) Coords(i uint64) []uint32 {
+	x := make([]uint64, c.dims)
+	for j := 0; j < c.bits; j++ {
+		for d := c.dims - 1; d >= 0; d-- {
+			x[d] |= (i & 1) << uint(j)
+			i >>= 1
+		}
+	}
+	if c.hilbert {
+		transposeToAxes(x, c.bits)
+	}
+	coords := make([]uint32, c.dims)
+	for d, v := range x {
+		coords[d] = uint32(v)
+	}
+	return coords
+}
+
+// Append appends the fragment encoding the position of a point to b.
+func (c *Curve) Append(b []byte, coords ...uint32) []byte {
+	return c.appendIndex(b, c.Index(coords...))
+}
+
+func (c *Curve) appendIndex(b []byte, i uint64) []byte {
+	for n := c.Size() - 1; n >= 0; n-- {
+		b = append(b, byte(i>>(8*uint(n))))
+	}
+	return b
+}
+
+// Decode decodes the point whose fragment starts b, and returns it with the
+// bytes which follow the fragment.
+func (c *Curve) Decode(b []byte) ([]uint32, []byte, error) {
+	if len(b) < c.Size() {
+		return nil, nil, fmt.Errorf("spatial: %d bytes, expected a fragment of %d bytes", len(b), c.Size())
+	}
+	var i uint64
+	for _, x := range b[:c.Size()] {
+		i = i<<8 | uint64(x)
+	}
+	if total := c.dims * c.bits; total < 64 && i>>uint(total) != 0 {
+		return nil, nil, fmt.Errorf("spatial: position %d does not fit in %d bits", i, total)
+	}
+	return c.Coords(i), b[c.Size():], nil
+}
+
+// Range is an interval of positions along a curve, from First to Last
+// included.
+type Range struct {
+	First, Last uint64
+}
+
+// block is a block of positions: the positions starting with the prefix of
+// level × Dims bits, which are the points of a cube of side 2^(Bits-level).
+type block struct {
+	prefix uint64
+	level  int
+	full   bool
+}
+
+// shift returns the number of bits of positions following the prefix of a
+// block.
+func (c *Curve) shift(b block) uint {
+	return uint(c.dims * (c.bits - b.level))
+}
+
+func (c *Curve) rangeOf(b block) Range {
+	first := b.prefix << c.shift(b)
+	return Range{first, first + (1 << c.shift(b)) - 1}
+}
+
+// classify returns whether the cube of a block is inside the box, and whether
+// it overlaps the box.
+func (c *Curve) classify(b block, lo, hi []uint32) (inside, overlaps bool) {
+	side := uint64(1) << uint(c.bits-b.level)
+	corner := c.Coords(b.prefix << c.shift(b))
+	inside = true
+	for d := range corner {
+		first := uint64(corner[d]) &^ (side - 1)
+		last := first + side - 1
+		if last < uint64(lo[d]) || first > uint64(hi[d]) {
+			return false, false
+		}
+		if first < uint64(lo[d]) || last > uint64(hi[d]) {
+			inside = false
+		}
+	}
+	return inside, true
+}
+
+// Ranges returns the ranges of positions covering the box of the points
+// whose coordinates are between those of lo and hi included, in order. The
+// box is decomposed into at most maxRanges ranges, or DefaultMaxRanges if
+// maxRanges is not positive: the ranges cover the box exactly if possible,
+// and cover more points otherwise, which callers filter out. Ranges will
+// panic if lo or hi do not have Dims coordinates.
+func (c *Curve) Ranges(lo, hi []uint32, maxRanges int) []Range {
+	if len(lo) != c.dims || len(hi) != c.dims {
+		panic(fmt.Sprintf("spatial: box has %d and %d coordinates, expected %d", len(lo), len(hi), c.dims))
+	}
+	if maxRanges <= 0 {
+		maxRanges = DefaultMaxRanges
+	}
+	for d := range lo {
+		if lo[d] > hi[d] {
+			return nil
+		}
+	}
+
+	blocks := []block{{}}
+	if inside, _ := c.classify(blocks[0], lo, hi); inside {
+		blocks[0].full = true
+	}
+	for level := 1; level <= c.bits; level++ {
+		var next []block
+		split := false
+		for _, b := range blocks {
+			if b.full {
+				next = append(next, b)
+				continue
+			}
+			split = true
+			for child := uint64(0); child < 1<<uint(c.dims); child++ {
+				cb := block{prefix: b.prefix<<uint(c.dims) | child, level: level}
+				inside, overlaps := c.classify(cb, lo, hi)
+				if overlaps {
+					cb.full = inside
+					next = append(next, cb)
+				}
+			}
+		}
+		if !split || len(c.merge(next)) > maxRanges {
+			break
+		}
+		blocks = next
+	}
+	return c.merge(blocks)
+}
+
+// merge returns the ranges of the blocks, which are in order, merging
+// adjacent ranges.
+func (c *Curve) merge(blocks []block) []Range {
+	var rs []Range
+	for _, b := range blocks {
+		r := c.rangeOf(b)
+		if n := len(rs); n > 0 && rs[n-1].Last+1 == r.First {
+			rs[n-1].Last = r.Last
+			continue
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// KeyRanges returns the key ranges of the box (see Ranges) for keys made of
+// the prefix followed by the fragment of the position of a point, and of any
+// suffix.
+func (c *Curve) KeyRanges(prefix []byte, lo, hi []uint32, maxRanges int) []lex.KeyRange {
+	rs := c.Ranges(lo, hi, maxRanges)
+	krs := make([]lex.KeyRange, len(rs))
+	for i, r := range rs {
+		begin := c.appendIndex(append([]byte{}, prefix...), r.First)
+		end := strinc(c.appendIndex(append([]byte{}, prefix...), r.Last))
+		krs[i] = lex.KeyRange{Begin: lex.Key(begin), End: end}
+	}
+	return krs
+}
+
+// TupleRanges returns the key ranges of the box (see Ranges) for keys of the
+// subspace s holding the fragment of the position of a point as a []byte
+// element, followed by any elements.
+func (c *Curve) TupleRanges(s subspace.Subspace, lo, hi []uint32, maxRanges int) []lex.KeyRange {
+	rs := c.Ranges(lo, hi, maxRanges)
+	krs := make([]lex.KeyRange, len(rs))
+	for i, r := range rs {
+		_, end := s.Sub(c.appendIndex(nil, r.Last)).LexRangeKeys()
+		krs[i] = lex.KeyRange{Begin: s.Pack(tuple.Tuple{c.appendIndex(nil, r.First)}), End: end}
+	}
+	return krs
+}
+
+// strinc returns the first key greater than every key starting with b, or
+// lex.MaxAppKey if b consists only of 0xFF bytes.
+func strinc(b []byte) lex.Key {
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] != 0xFF {
+			r := append(lex.Key{}, b[:i+1]...)
+			r[i]++
+			return r
+		}
+	}
+	return lex.MaxAppKey
+}
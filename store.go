@@ -0,0 +1,69 @@
+package lex
+
+// KeyValue represents a single key-value pair read from a KVStore.
+type KeyValue struct {
+	Key   Key
+	Value []byte
+}
+
+// RangeOptions specify how a range read is performed. The default zero-value
+// of RangeOptions reads every key in the range in ascending order.
+type RangeOptions struct {
+	// Limit restricts the number of key-value pairs returned by a range read.
+	// A value of 0 indicates no limit.
+	Limit int
+
+	// Reverse indicates that the range should be read in descending order
+	// (starting from the end of the range). Limit is applied after ordering,
+	// so a reverse read with a limit returns the last keys of the range.
+	Reverse bool
+
+	// Mode controls how an Iterator returned by Iterate batches its reads. It
+	// is ignored by GetRange.
+	Mode StreamingMode
+}
+
+// KVStore is a minimal ordered key-value store. Layers built from subspaces
+// and tuples can be written once against KVStore and run on top of any
+// ordered storage engine for which an adapter exists.
+//
+// Key selectors are resolved with the usual FoundationDB semantics: a
+// selector first finds the last key less than (or, if OrEqual is set, less
+// than or equal to) its Key, then moves Offset keys forward. A selector
+// resolving before the first key in the store resolves to MinKey (the empty
+// key), and one resolving past the last key resolves to MaxAppKey (the
+// single byte 0xFF).
+type KVStore interface {
+	// GetRange returns the key-value pairs in the range, ordered and limited
+	// as described by options.
+	GetRange(r Range, options RangeOptions) ([]KeyValue, error)
+
+	// GetKey resolves the key selector to a key present in the store (or to
+	// one of the boundary keys described above).
+	GetKey(sel Selectable) (Key, error)
+
+	// Set associates the given key and value, overwriting any previous value.
+	Set(key KeyConvertible, value []byte) error
+
+	// Clear removes the given key (and its value). Clearing a key which is not
+	// present is not an error.
+	Clear(key KeyConvertible) error
+}
+
+// Get returns the value associated with key in the store, or nil if the key
+// is not present. A key present with an empty value yields a non-nil empty
+// slice.
+func Get(store KVStore, key KeyConvertible) ([]byte, error) {
+	k := key.LexKey()
+	end := make(Key, len(k)+1)
+	copy(end, k)
+
+	kvs, err := store.GetRange(KeyRange{k, end}, RangeOptions{Limit: 1})
+	if err != nil || len(kvs) == 0 {
+		return nil, err
+	}
+	if kvs[0].Value == nil {
+		return []byte{}, nil
+	}
+	return kvs[0].Value, nil
+}
@@ -0,0 +1,48 @@
+package subspace
+
+import (
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Guard returns a Subspace which behaves like s, except that it refuses to
+// produce keys in the system keyspace, which starts with 0xFF (see
+// lex.MaxAppKey): Pack, Sub and LexKey panic if the resulting key or prefix
+// would start with 0xFF. Subspaces obtained with Sub are guarded as well.
+//
+// Tuple elements never start with 0xFF, so such keys only arise from raw
+// prefixes (such as those built with FromBytes) or from opaque elements and
+// raw suffixes; using the unguarded Subspace explicitly allows them.
+func Guard(s Subspace) Subspace {
+	if g, ok := s.(guarded); ok {
+		return g
+	}
+	return guarded{s}
+}
+
+type guarded struct {
+	Subspace
+}
+
+func check(k lex.Key) lex.Key {
+	if !lex.IsAppKey(k) {
+		panic(fmt.Sprintf("key %q is in the system keyspace", []byte(k)))
+	}
+	return k
+}
+
+func (g guarded) Sub(el ...tuple.Element) Subspace {
+	s := g.Subspace.Sub(el...)
+	check(s.LexKey())
+	return guarded{s}
+}
+
+func (g guarded) Pack(t tuple.Tuple) lex.Key {
+	return check(g.Subspace.Pack(t))
+}
+
+func (g guarded) LexKey() lex.Key {
+	return check(g.Subspace.LexKey())
+}
@@ -0,0 +1,97 @@
+package subspace
+
+import (
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// PackHook transforms the tuples packed by a Subspace, before the prefix is
+// prepended. Like Pack, BeforePack may panic on tuples it cannot handle.
+type PackHook interface {
+	BeforePack(t tuple.Tuple) tuple.Tuple
+}
+
+// UnpackHook transforms the tuples unpacked by a Subspace, after the prefix
+// has been removed. AfterUnpack returns an error to reject a key.
+type UnpackHook interface {
+	AfterUnpack(t tuple.Tuple) (tuple.Tuple, error)
+}
+
+// Middleware is a cross-cutting key transform, such as tenancy injection,
+// redaction, hashing or metrics, which can be layered onto a Subspace with
+// Use. A Middleware transforming keys in a reversible way undoes in
+// AfterUnpack what it did in BeforePack.
+type Middleware interface {
+	PackHook
+	UnpackHook
+}
+
+// PackFunc is a Middleware which only transforms packed tuples.
+type PackFunc func(t tuple.Tuple) tuple.Tuple
+
+// BeforePack calls f(t).
+func (f PackFunc) BeforePack(t tuple.Tuple) tuple.Tuple {
+	return f(t)
+}
+
+// AfterUnpack returns t unchanged.
+func (f PackFunc) AfterUnpack(t tuple.Tuple) (tuple.Tuple, error) {
+	return t, nil
+}
+
+// UnpackFunc is a Middleware which only transforms unpacked tuples.
+type UnpackFunc func(t tuple.Tuple) (tuple.Tuple, error)
+
+// BeforePack returns t unchanged.
+func (f UnpackFunc) BeforePack(t tuple.Tuple) tuple.Tuple {
+	return t
+}
+
+// AfterUnpack calls f(t).
+func (f UnpackFunc) AfterUnpack(t tuple.Tuple) (tuple.Tuple, error) {
+	return f(t)
+}
+
+// Use returns a Subspace which behaves like s, except that every tuple it
+// packs is passed through the BeforePack hooks of the middlewares in order,
+// and every tuple it unpacks through their AfterUnpack hooks in reverse
+// order. Subspaces obtained with Sub keep the middlewares; the elements
+// passed to Sub become part of the prefix and are not transformed.
+func Use(s Subspace, mw ...Middleware) Subspace {
+	if h, ok := s.(hooked); ok {
+		return hooked{h.Subspace, append(append([]Middleware{}, h.mw...), mw...)}
+	}
+	return hooked{s, mw}
+}
+
+type hooked struct {
+	Subspace
+	mw []Middleware
+}
+
+func (h hooked) Sub(el ...tuple.Element) Subspace {
+	return hooked{h.Subspace.Sub(el...), h.mw}
+}
+
+func (h hooked) Pack(t tuple.Tuple) lex.Key {
+	for _, m := range h.mw {
+		t = m.BeforePack(t)
+	}
+	return h.Subspace.Pack(t)
+}
+
+func (h hooked) Unpack(k lex.KeyConvertible) (tuple.Tuple, error) {
+	t, err := h.Subspace.Unpack(k)
+	for i := len(h.mw) - 1; i >= 0 && err == nil; i-- {
+		t, err = h.mw[i].AfterUnpack(t)
+	}
+	return t, err
+}
+
+func (h hooked) UnpackInto(k lex.KeyConvertible, t *tuple.Tuple) error {
+	err := h.Subspace.UnpackInto(k, t)
+	for i := len(h.mw) - 1; i >= 0 && err == nil; i-- {
+		*t, err = h.mw[i].AfterUnpack(*t)
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package subspace
+
+import (
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// KeyBuilder builds a key from fragments: the prefix of a subspace, tuple
+// elements and raw bytes, such as a fixed-width hash or a key of another
+// encoding following a tuple prefix. Its methods return the KeyBuilder, so
+// that calls can be chained:
+//
+//	key := subspace.NewKeyBuilder(users).Sub("by-email").Raw(hash).Tuple(tuple.Tuple{id}).Key()
+//
+// Use Clone to build several keys sharing a beginning.
+type KeyBuilder struct {
+	b []byte
+}
+
+// NewKeyBuilder returns a KeyBuilder starting with the prefix of s, or with
+// nothing if s is nil.
+func NewKeyBuilder(s Subspace) *KeyBuilder {
+	kb := &KeyBuilder{}
+	if s != nil {
+		kb.b = append(kb.b, s.Bytes()...)
+	}
+	return kb
+}
+
+// Sub appends the encoding of the elements, like Subspace.Sub. Sub will panic
+// if any of the elements are not a valid tuple.Element.
+func (kb *KeyBuilder) Sub(el ...tuple.Element) *KeyBuilder {
+	return kb.Tuple(tuple.Tuple(el))
+}
+
+// Tuple appends the packed tuple. Tuple will panic in the same circumstances
+// as tuple.Tuple.Pack.
+func (kb *KeyBuilder) Tuple(t tuple.Tuple) *KeyBuilder {
+	kb.b = t.AppendPack(kb.b)
+	return kb
+}
+
+// Raw appends the bytes verbatim.
+func (kb *KeyBuilder) Raw(b []byte) *KeyBuilder {
+	kb.b = append(kb.b, b...)
+	return kb
+}
+
+// Subspace appends the prefix of s.
+func (kb *KeyBuilder) Subspace(s Subspace) *KeyBuilder {
+	return kb.Raw(s.Bytes())
+}
+
+// Clone returns a copy of the KeyBuilder, which can be extended independently.
+func (kb *KeyBuilder) Clone() *KeyBuilder {
+	return &KeyBuilder{append([]byte{}, kb.b...)}
+}
+
+// Key returns a copy of the key built so far.
+func (kb *KeyBuilder) Key() lex.Key {
+	return append(lex.Key{}, kb.b...)
+}
+
+// LexKey returns the key built so far, and allows a KeyBuilder to satisfy the
+// lex.KeyConvertible interface.
+func (kb *KeyBuilder) LexKey() lex.Key {
+	return kb.Key()
+}
+
+// AsSubspace returns the Subspace whose prefix is the key built so far.
+func (kb *KeyBuilder) AsSubspace() Subspace {
+	return FromBytes(kb.b)
+}
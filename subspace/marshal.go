@@ -0,0 +1,161 @@
+package subspace
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdullin/lex-go/tuple"
+)
+
+func (s subspace) MarshalBinary() ([]byte, error) {
+	return concat(s.b), nil
+}
+
+func (s subspace) MarshalText() ([]byte, error) {
+	t, err := tuple.Unpack(s.b)
+	if err == nil && literal(t) && bytes.Equal(t.Pack(), s.b) {
+		return []byte(formatPath(t)), nil
+	}
+	return []byte(strconv.Quote(string(s.b))), nil
+}
+
+// literal returns true if every element of the tuple can be parsed back by
+// Parse.
+func literal(t tuple.Tuple) bool {
+	for _, el := range t {
+		switch el.(type) {
+		case nil, []byte, string, int64:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Parse returns the Subspace described by text, in the format produced by the
+// MarshalText method of Subspaces: either a tuple literal such as
+// ("app", b"\x01", 5, nil), whose encoding is the prefix, or the quoted bytes
+// of the prefix, such as "\xfe".
+func Parse(text string) (Subspace, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, `"`) {
+		b, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subspace %s: %v", text, err)
+		}
+		return FromBytes([]byte(b)), nil
+	}
+
+	if !strings.HasPrefix(text, "(") || !strings.HasSuffix(text, ")") {
+		return nil, fmt.Errorf("invalid subspace %s: expected a tuple or a quoted string", text)
+	}
+
+	var t tuple.Tuple
+	rest := strings.TrimSpace(text[1 : len(text)-1])
+	for rest != "" {
+		el, n, err := parseElement(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subspace %s: %v", text, err)
+		}
+		t = append(t, el)
+
+		rest = strings.TrimSpace(rest[n:])
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("invalid subspace %s: expected a comma before %s", text, rest)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+	return subspace{t.Pack()}, nil
+}
+
+// parseElement parses the tuple element at the start of s, returning it and
+// its length.
+func parseElement(s string) (tuple.Element, int, error) {
+	switch {
+	case strings.HasPrefix(s, "nil"):
+		return nil, 3, nil
+	case strings.HasPrefix(s, `"`):
+		q, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, err := strconv.Unquote(q)
+		return v, len(q), err
+	case strings.HasPrefix(s, `b"`):
+		q, err := strconv.QuotedPrefix(s[1:])
+		if err != nil {
+			return nil, 0, err
+		}
+		v, err := strconv.Unquote(q)
+		return []byte(v), len(q) + 1, err
+	}
+
+	n := strings.IndexAny(s, ", ")
+	if n < 0 {
+		n = len(s)
+	}
+	i, err := strconv.ParseInt(s[:n], 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unexpected element %s", s[:n])
+	}
+	return i, n, nil
+}
+
+// Value holds a Subspace and implements encoding.BinaryUnmarshaler,
+// encoding.TextUnmarshaler and flag.Value, so that prefixes can be stored in
+// configuration files, passed over RPC or set from the command line, and
+// reconstructed identically. The zero Value holds no Subspace, and marshals
+// as an empty prefix.
+type Value struct {
+	Subspace
+}
+
+func (v Value) subspace() Subspace {
+	if v.Subspace == nil {
+		return AllKeys()
+	}
+	return v.Subspace
+}
+
+// MarshalBinary returns the prefix of the Subspace.
+func (v Value) MarshalBinary() ([]byte, error) {
+	return v.subspace().MarshalBinary()
+}
+
+// UnmarshalBinary sets the Subspace to the one with the given prefix.
+func (v *Value) UnmarshalBinary(b []byte) error {
+	v.Subspace = FromBytes(b)
+	return nil
+}
+
+// MarshalText returns the text representation of the Subspace.
+func (v Value) MarshalText() ([]byte, error) {
+	return v.subspace().MarshalText()
+}
+
+// UnmarshalText sets the Subspace to the one described by text, as accepted
+// by Parse.
+func (v *Value) UnmarshalText(text []byte) error {
+	s, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	v.Subspace = s
+	return nil
+}
+
+// String returns the text representation of the Subspace.
+func (v Value) String() string {
+	b, _ := v.MarshalText()
+	return string(b)
+}
+
+// Set implements flag.Value.
+func (v *Value) Set(s string) error {
+	return v.UnmarshalText([]byte(s))
+}
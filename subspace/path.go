@@ -0,0 +1,120 @@
+package subspace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// PathSubspace is a Subspace which records the tuple elements used to build
+// its prefix. The path is included in the errors and in the String
+// representation of the subspace, which helps when debugging key layouts.
+type PathSubspace interface {
+	Subspace
+
+	// Path returns the tuple elements whose encoding is the prefix of this
+	// Subspace.
+	Path() []tuple.Element
+
+	// String returns the path of this Subspace formatted as a tuple.
+	String() string
+}
+
+type pathSubspace struct {
+	subspace
+	path []tuple.Element
+}
+
+// WithPath returns a new PathSubspace whose prefix is the encoding of the
+// provided element(s). Subspaces created from it with Sub are PathSubspaces
+// as well. If any of the elements are not a valid tuple.Element, a runtime
+// panic will occur.
+func WithPath(el ...tuple.Element) PathSubspace {
+	return pathSubspace{subspace{tuple.Tuple(el).Pack()}, append([]tuple.Element{}, el...)}
+}
+
+// FromPath returns a new PathSubspace from a human-readable path such as
+// "app/users/by-email", whose segments are encoded as string elements: it is
+// equivalent to WithPath("app", "users", "by-email"). Empty segments, as
+// produced by leading, trailing or repeated slashes, are ignored.
+func FromPath(path string) PathSubspace {
+	return FromPathSep(path, "/")
+}
+
+// FromPathSep is like FromPath, but splits the path on the given separator.
+func FromPathSep(path, sep string) PathSubspace {
+	var el []tuple.Element
+	for _, seg := range strings.Split(path, sep) {
+		if seg != "" {
+			el = append(el, seg)
+		}
+	}
+	return WithPath(el...)
+}
+
+func (s pathSubspace) Sub(el ...tuple.Element) Subspace {
+	path := make([]tuple.Element, 0, len(s.path)+len(el))
+	path = append(append(path, s.path...), el...)
+	return pathSubspace{subspace{concat(s.b, tuple.Tuple(el).Pack()...)}, path}
+}
+
+func (s pathSubspace) Path() []tuple.Element {
+	return append([]tuple.Element{}, s.path...)
+}
+
+func (s pathSubspace) Unpack(k lex.KeyConvertible) (tuple.Tuple, error) {
+	t, err := s.subspace.Unpack(k)
+	if err != nil {
+		return nil, fmt.Errorf("subspace %s: %v", s, err)
+	}
+	return t, nil
+}
+
+func (s pathSubspace) UnpackInto(k lex.KeyConvertible, t *tuple.Tuple) error {
+	if err := s.subspace.UnpackInto(k, t); err != nil {
+		return fmt.Errorf("subspace %s: %v", s, err)
+	}
+	return nil
+}
+
+func (s pathSubspace) Strip(k lex.KeyConvertible) ([]byte, error) {
+	b, err := s.subspace.Strip(k)
+	if err != nil {
+		return nil, fmt.Errorf("subspace %s: %v", s, err)
+	}
+	return b, nil
+}
+
+func (s pathSubspace) String() string {
+	return formatPath(s.path)
+}
+
+func (s pathSubspace) GoString() string {
+	parts := make([]string, len(s.path))
+	for i, el := range s.path {
+		parts[i] = fmt.Sprintf("%#v", el)
+	}
+	return "subspace.WithPath(" + strings.Join(parts, ", ") + ")"
+}
+
+// formatPath formats tuple elements as a tuple literal, such as
+// ("app", b"\x01", 5).
+func formatPath(path []tuple.Element) string {
+	parts := make([]string, len(path))
+	for i, el := range path {
+		switch el := el.(type) {
+		case nil:
+			parts[i] = "nil"
+		case []byte:
+			parts[i] = "b" + strconv.Quote(string(el))
+		case string:
+			parts[i] = strconv.Quote(el)
+		default:
+			parts[i] = fmt.Sprint(el)
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
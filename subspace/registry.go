@@ -0,0 +1,148 @@
+package subspace
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// OverlapError is returned when registering a Subspace whose prefix is a
+// prefix of (or has as prefix) the prefix of an already registered Subspace.
+type OverlapError struct {
+	Name     string
+	Subspace Subspace
+
+	// Other is the name of the registered Subspace, and OtherSubspace the
+	// Subspace itself.
+	Other         string
+	OtherSubspace Subspace
+}
+
+func (e *OverlapError) Error() string {
+	rel := "overlaps"
+	switch {
+	case bytes.Equal(e.Subspace.Bytes(), e.OtherSubspace.Bytes()):
+		rel = "has the same prefix as"
+	case e.OtherSubspace.Contains(e.Subspace):
+		rel = "is nested in"
+	case e.Subspace.Contains(e.OtherSubspace):
+		rel = "contains"
+	}
+	return fmt.Sprintf("subspace %q %s %s subspace %q %s", e.Name, e.Subspace, rel, e.Other, e.OtherSubspace)
+}
+
+type registered struct {
+	name   string
+	s      Subspace
+	parent string
+}
+
+// Registry records the named subspaces of an application, typically declared
+// at startup, and rejects any subspace whose keys would silently overlap with
+// those of another one. Subspaces meant to be nested within a registered
+// Subspace are declared with RegisterWithin. A Registry is safe for
+// concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]registered
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: map[string]registered{}}
+}
+
+// Register records the Subspace under the given name. It returns an
+// *OverlapError if the prefix of the Subspace is a prefix of the prefix of a
+// registered Subspace, or the other way around.
+func (r *Registry) Register(name string, s Subspace) error {
+	return r.register(name, s, "")
+}
+
+// RegisterWithin records the Subspace under the given name, as intentionally
+// nested within the registered Subspace named parent. The Subspace must be
+// contained in its parent, and must not overlap with any other Subspace.
+func (r *Registry) RegisterWithin(parent, name string, s Subspace) error {
+	return r.register(name, s, parent)
+}
+
+// MustRegister is like Register but panics if the Subspace cannot be
+// registered. It returns the Subspace, so that it can be used to initialize
+// package-level variables.
+func (r *Registry) MustRegister(name string, s Subspace) Subspace {
+	if err := r.Register(name, s); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (r *Registry) register(name string, s Subspace, parent string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[name]; ok {
+		return fmt.Errorf("subspace %q is already registered", name)
+	}
+	if parent != "" {
+		p, ok := r.entries[parent]
+		if !ok {
+			return fmt.Errorf("parent subspace %q of %q is not registered", parent, name)
+		}
+		if !p.s.Contains(s) || bytes.Equal(p.s.Bytes(), s.Bytes()) {
+			return fmt.Errorf("subspace %q %s is not nested in its parent %q %s", name, s, parent, p.s)
+		}
+	}
+
+	for _, e := range r.entries {
+		if r.ancestor(e.name, parent) {
+			continue
+		}
+		if e.s.Contains(s) || s.Contains(e.s) {
+			return &OverlapError{name, s, e.name, e.s}
+		}
+	}
+
+	r.entries[name] = registered{name, s, parent}
+	return nil
+}
+
+// ancestor returns true if the registered Subspace named a is name or one of
+// its ancestors.
+func (r *Registry) ancestor(a, name string) bool {
+	for name != "" {
+		if a == name {
+			return true
+		}
+		name = r.entries[name].parent
+	}
+	return false
+}
+
+// Lookup returns the Subspace registered under the given name.
+func (r *Registry) Lookup(name string) (Subspace, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.entries[name]
+	return e.s, ok
+}
+
+// Names returns the names of the registered subspaces, ordered by prefix.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := r.entries[names[i]].s.Bytes(), r.entries[names[j]].s.Bytes()
+		if c := bytes.Compare(a, b); c != 0 {
+			return c < 0
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
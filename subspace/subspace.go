@@ -12,7 +12,11 @@ package subspace
 
 import (
 	"bytes"
+	"encoding"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/abdullin/lex-go/tuple"
 
@@ -39,6 +43,15 @@ type Subspace interface {
 	// Subspace or does not encode a well-formed Tuple.
 	Unpack(k lex.KeyConvertible) (tuple.Tuple, error)
 
+	// UnpackInto is like Unpack, but decodes the Tuple into t, reusing its
+	// backing array (see tuple.UnpackInto).
+	UnpackInto(k lex.KeyConvertible, t *tuple.Tuple) error
+
+	// Strip returns the bytes of the given key following the prefix of this
+	// Subspace, without decoding them, for keys whose suffix is not a tuple.
+	// Strip will return an error if the key is not in this Subspace.
+	Strip(k lex.KeyConvertible) ([]byte, error)
+
 	// Contains returns true if the provided key starts with the prefix of this
 	// Subspace, indicating that the Subspace logically contains the key.
 	Contains(k lex.KeyConvertible) bool
@@ -50,6 +63,20 @@ type Subspace interface {
 	// All Subspaces implement lex.ExactRange and lex.Range, and describe all
 	// keys logically in this Subspace.
 	lex.ExactRange
+
+	// All Subspaces implement fmt.Stringer and fmt.GoStringer. String returns
+	// the prefix decoded as a tuple, such as ("app", "users"), or the quoted
+	// bytes of the prefix if it is not a well-formed tuple. GoString returns
+	// the Go expression creating the Subspace.
+	fmt.Stringer
+	fmt.GoStringer
+
+	// All Subspaces implement encoding.BinaryMarshaler, which returns the
+	// prefix, and encoding.TextMarshaler, which returns the prefix decoded as
+	// a tuple literal when possible and its quoted bytes otherwise. Both can
+	// be unmarshaled with Value.
+	encoding.BinaryMarshaler
+	encoding.TextMarshaler
 }
 
 type subspace struct {
@@ -76,6 +103,56 @@ func FromBytes(b []byte) Subspace {
 	return subspace{s}
 }
 
+// PackChecked is like s.Pack, but returns a lex.ErrKeyTooLarge if the key is
+// longer than lex.MaxKeySize, the limit FoundationDB enforces on keys, rather
+// than letting the oversized key fail when it is written.
+func PackChecked(s Subspace, t tuple.Tuple) (lex.Key, error) {
+	k := s.Pack(t)
+	if err := lex.CheckKey(k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// PackBatch returns the keys encoding the tuples with the prefix of s
+// prepended, like s.Pack applied to every tuple. For subspaces created by
+// this package (but not those returned by Guard or Use, whose Pack is
+// called for every tuple), the keys are packed into a single buffer, which
+// makes writing thousands of index entries cost a few allocations rather
+// than a few per key. The keys share the buffer but do not overlap: each has
+// its own capacity.
+func PackBatch(s Subspace, ts []tuple.Tuple) []lex.Key {
+	keys := make([]lex.Key, len(ts))
+
+	var prefix []byte
+	switch s := s.(type) {
+	case subspace:
+		prefix = s.b
+	case pathSubspace:
+		prefix = s.b
+	default:
+		for i, t := range ts {
+			keys[i] = s.Pack(t)
+		}
+		return keys
+	}
+
+	// The buffer may move as it grows, so keys are sliced once every tuple
+	// is packed; ends records where each key ends meanwhile.
+	ends := make([]int, len(ts))
+	buf := make([]byte, 0, len(ts)*(len(prefix)+16))
+	for i, t := range ts {
+		buf = t.AppendPack(append(buf, prefix...))
+		ends[i] = len(buf)
+	}
+	begin := 0
+	for i, end := range ends {
+		keys[i] = lex.Key(buf[begin:end:end])
+		begin = end
+	}
+	return keys
+}
+
 func (s subspace) Sub(el ...tuple.Element) Subspace {
 	return subspace{concat(s.Bytes(), tuple.Tuple(el).Pack()...)}
 }
@@ -89,11 +166,27 @@ func (s subspace) Pack(t tuple.Tuple) lex.Key {
 }
 
 func (s subspace) Unpack(k lex.KeyConvertible) (tuple.Tuple, error) {
+	b, err := s.Strip(k)
+	if err != nil {
+		return nil, err
+	}
+	return tuple.Unpack(b)
+}
+
+func (s subspace) UnpackInto(k lex.KeyConvertible, t *tuple.Tuple) error {
+	b, err := s.Strip(k)
+	if err != nil {
+		return err
+	}
+	return tuple.UnpackInto(b, t)
+}
+
+func (s subspace) Strip(k lex.KeyConvertible) ([]byte, error) {
 	key := k.LexKey()
 	if !bytes.HasPrefix(key, s.b) {
 		return nil, errors.New("key is not in subspace")
 	}
-	return tuple.Unpack(key[len(s.b):])
+	return key[len(s.b):], nil
 }
 
 func (s subspace) Contains(k lex.KeyConvertible) bool {
@@ -113,6 +206,29 @@ func (s subspace) LexRangeKeySelectors() (lex.Selectable, lex.Selectable) {
 	return lex.FirstGreaterOrEqual(begin), lex.FirstGreaterOrEqual(end)
 }
 
+func (s subspace) String() string {
+	t, err := tuple.Unpack(s.b)
+	if err != nil {
+		return strconv.Quote(string(s.b))
+	}
+	return formatPath(t)
+}
+
+func (s subspace) GoString() string {
+	if len(s.b) == 0 {
+		return "subspace.AllKeys()"
+	}
+	t, err := tuple.Unpack(s.b)
+	if err != nil {
+		return fmt.Sprintf("subspace.FromBytes(%#v)", s.b)
+	}
+	parts := make([]string, len(t))
+	for i, el := range t {
+		parts[i] = fmt.Sprintf("%#v", el)
+	}
+	return "subspace.Sub(" + strings.Join(parts, ", ") + ")"
+}
+
 func concat(a []byte, b ...byte) []byte {
 	r := make([]byte, len(a)+len(b))
 	copy(r, a)
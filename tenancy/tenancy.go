@@ -0,0 +1,168 @@
+// Package tenancy derives the subspaces of the tenants of a multi-tenant
+// application from their IDs, so that the keys of a tenant share a prefix
+// and can be checked against the tenant they are expected to belong to.
+//
+// The subspace of a tenant is (id) in a root subspace or, if tenant IDs are
+// hashed, (hash) where hash is a salted hash of the ID: hashing keeps tenant
+// IDs out of keys and spreads tenants evenly across the key space, at the
+// cost of the order of tenants and of recovering IDs from keys.
+package tenancy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/kvutil"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// HashSize is the size of the hashes of tenant IDs.
+const HashSize = 16
+
+// Options configure Tenants.
+type Options struct {
+	// Hash stores the hashes of tenant IDs in keys, rather than the IDs.
+	Hash bool
+
+	// Salt is the key of the hash of tenant IDs (an HMAC-SHA256 truncated to
+	// HashSize bytes). It should be kept secret if tenant IDs are not to be
+	// guessed from keys. Changing it moves every tenant to another subspace.
+	Salt []byte
+}
+
+// Tenants derives the subspaces of tenants in a root subspace.
+type Tenants struct {
+	root subspace.Subspace
+	o    Options
+}
+
+// New returns the Tenants whose subspaces are stored in the subspace root.
+func New(root subspace.Subspace, o Options) Tenants {
+	return Tenants{root: root, o: o}
+}
+
+// Element returns the element identifying a tenant in keys: its ID, or the
+// hash of its packed ID as a byte string if IDs are hashed. Element will
+// panic if the ID is not a valid tuple element.
+func (t Tenants) Element(id tuple.Element) tuple.Element {
+	if !t.o.Hash {
+		return id
+	}
+	h := hmac.New(sha256.New, t.o.Salt)
+	h.Write(tuple.Tuple{id}.Pack())
+	return h.Sum(nil)[:HashSize]
+}
+
+// Subspace returns the subspace of a tenant. Its Pack and Sub panic if they
+// would produce a key or prefix outside the tenant subspace (which tuples
+// never do, but raw suffixes and middlewares may), and its Unpack, UnpackInto
+// and Strip return a *MismatchError for keys of other tenants.
+func (t Tenants) Subspace(id tuple.Element) subspace.Subspace {
+	s := t.root.Sub(t.Element(id))
+	return tenant{s, id, s.Bytes(), len(t.root.Bytes())}
+}
+
+// Tenant returns the element identifying the tenant of a key (see Element),
+// or an error if the key is not in the subspace of a tenant.
+func (t Tenants) Tenant(key lex.KeyConvertible) (tuple.Element, error) {
+	b, err := t.root.Strip(key)
+	if err != nil {
+		return nil, fmt.Errorf("tenancy: key %s is not in %s", kvutil.FormatKey(key.LexKey()), t.root)
+	}
+	el, err := tuple.UnpackWithSuffix(b, 1)
+	if err != nil {
+		return nil, fmt.Errorf("tenancy: key %s has no tenant: %v", kvutil.FormatKey(key.LexKey()), err)
+	}
+	return el[0], nil
+}
+
+// Verify returns a *MismatchError unless the key belongs to the tenant.
+func (t Tenants) Verify(id tuple.Element, key lex.KeyConvertible) error {
+	return tenant{id: id, prefix: t.root.Sub(t.Element(id)).Bytes(), root: len(t.root.Bytes())}.verify(key)
+}
+
+// MismatchError reports a key which does not belong to the expected tenant.
+type MismatchError struct {
+	Tenant tuple.Element
+	Key    lex.Key
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("tenancy: key %s does not belong to tenant %v", kvutil.FormatKey(e.Key), e.Tenant)
+}
+
+type tenant struct {
+	subspace.Subspace
+	id     tuple.Element
+	prefix []byte
+
+	// root is the length of the prefix of the root subspace, which the
+	// element of the tenant follows.
+	root int
+}
+
+// owns returns true if the key is in the subspace of the tenant. Starting
+// with the prefix of the tenant is not enough: the element of another tenant
+// may extend the element of the tenant, such as the ID "acme\x00evil",
+// encoded with an escaped 0x00, extends "acme". The element of the key must
+// end with the prefix.
+func (s tenant) owns(k lex.Key) bool {
+	if !bytes.HasPrefix(k, s.prefix) {
+		return false
+	}
+	n, err := tuple.PackedPrefixLen(k[s.root:], 1)
+	return err == nil && s.root+n == len(s.prefix)
+}
+
+func (s tenant) check(k lex.Key) lex.Key {
+	if !s.owns(k) {
+		panic((&MismatchError{s.id, k}).Error())
+	}
+	return k
+}
+
+func (s tenant) verify(k lex.KeyConvertible) error {
+	if key := k.LexKey(); !s.owns(key) {
+		return &MismatchError{s.id, append(lex.Key{}, key...)}
+	}
+	return nil
+}
+
+func (s tenant) Sub(el ...tuple.Element) subspace.Subspace {
+	sub := s.Subspace.Sub(el...)
+	s.check(sub.LexKey())
+	return tenant{sub, s.id, s.prefix, s.root}
+}
+
+func (s tenant) Pack(t tuple.Tuple) lex.Key {
+	return s.check(s.Subspace.Pack(t))
+}
+
+func (s tenant) Unpack(k lex.KeyConvertible) (tuple.Tuple, error) {
+	if err := s.verify(k); err != nil {
+		return nil, err
+	}
+	return s.Subspace.Unpack(k)
+}
+
+func (s tenant) UnpackInto(k lex.KeyConvertible, t *tuple.Tuple) error {
+	if err := s.verify(k); err != nil {
+		return err
+	}
+	return s.Subspace.UnpackInto(k, t)
+}
+
+func (s tenant) Contains(k lex.KeyConvertible) bool {
+	return s.owns(k.LexKey())
+}
+
+func (s tenant) Strip(k lex.KeyConvertible) ([]byte, error) {
+	if err := s.verify(k); err != nil {
+		return nil, err
+	}
+	return s.Subspace.Strip(k)
+}
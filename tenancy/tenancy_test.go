@@ -0,0 +1,70 @@
+package tenancy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+func TestTenancy(t *testing.T) {
+	for _, o := range []Options{{}, {Hash: true, Salt: []byte("s")}} {
+		ts := New(subspace.Sub("app"), o)
+		a, ab := ts.Subspace("a"), ts.Subspace("ab")
+		k := a.Sub("users").Pack(tuple.Tuple{int64(1)})
+		if err := ts.Verify("a", k); err != nil {
+			t.Fatal(err)
+		}
+		var me *MismatchError
+		if err := ts.Verify("ab", k); !errors.As(err, &me) {
+			t.Fatal(err)
+		}
+		if _, err := ab.Unpack(k); !errors.As(err, &me) {
+			t.Fatal(err)
+		}
+		if tu, err := a.Unpack(k); err != nil || len(tu) != 2 {
+			t.Fatal(tu, err)
+		}
+		el, err := ts.Tenant(k)
+		if err != nil || tuple.Compare(tuple.Tuple{el}, tuple.Tuple{ts.Element("a")}) != 0 {
+			t.Fatal(el, err)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("no panic")
+				}
+			}()
+			a.Pack(tuple.Tuple{tuple.RawSuffix("\xff")})
+		}()
+	}
+}
+
+func TestEscapedTenant(t *testing.T) {
+	for _, ids := range [][2]tuple.Element{
+		{"acme", "acme\x00evil"},
+		{[]byte("ab"), []byte("ab\x00")},
+	} {
+		ts := New(subspace.Sub("app"), Options{})
+		s, other := ts.Subspace(ids[0]), ts.Subspace(ids[1])
+		k := other.Pack(tuple.Tuple{"users", int64(1)})
+
+		var me *MismatchError
+		if err := ts.Verify(ids[0], k); !errors.As(err, &me) {
+			t.Fatalf("%q: Verify: %v", ids[1], err)
+		}
+		if _, err := s.Strip(k); !errors.As(err, &me) {
+			t.Fatalf("%q: Strip: %v", ids[1], err)
+		}
+		if s.Contains(k) {
+			t.Fatalf("%q: Contains", ids[1])
+		}
+		if err := ts.Verify(ids[1], k); err != nil || !other.Contains(k) {
+			t.Fatalf("%q: %v", ids[1], err)
+		}
+		if err := ts.Verify(ids[0], s.Pack(tuple.Tuple{"users"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package lex
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MarshalText implements encoding.TextMarshaler. A key is written in the
+// printable form used by FoundationDB tools: printable ASCII characters
+// stand for themselves, a backslash is escaped as \\ and every other byte is
+// written as \xNN. The printable form of a key sorts like the key only for
+// printable keys; it is meant to be read and copied, not sorted.
+func (k Key) MarshalText() ([]byte, error) {
+	const hex = "0123456789abcdef"
+	b := make([]byte, 0, len(k))
+	for _, c := range k {
+		switch {
+		case c == '\\':
+			b = append(b, '\\', '\\')
+		case c >= 0x20 && c < 0x7F:
+			b = append(b, c)
+		default:
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xF])
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding the printable
+// form written by MarshalText.
+func (k *Key) UnmarshalText(text []byte) error {
+	r, err := ParseKey(string(text))
+	if err != nil {
+		return err
+	}
+	*k = r
+	return nil
+}
+
+// ParseKey decodes the printable form of a key written by Key.MarshalText,
+// such as \x02users\x00\x15\x01. Bytes escaped as \xNN may be written in
+// upper or lower case.
+func ParseKey(s string) (Key, error) {
+	k := make(Key, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			k = append(k, c)
+			continue
+		}
+		switch {
+		case i+1 < len(s) && s[i+1] == '\\':
+			k = append(k, '\\')
+			i++
+		case i+3 < len(s) && s[i+1] == 'x':
+			n, err := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape %s at offset %d in key", s[i:i+4], i)
+			}
+			k = append(k, byte(n))
+			i += 3
+		default:
+			return nil, fmt.Errorf("invalid escape at offset %d in key", i)
+		}
+	}
+	return k, nil
+}
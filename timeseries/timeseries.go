@@ -0,0 +1,186 @@
+// Package timeseries provides the key scheme of time series on an ordered
+// key-value store, such as metrics or events: the points of a series are
+// keyed by (series, bucket, time, seq), where the bucket is the number of
+// whole buckets of a fixed size (such as an hour or a day) since the Unix
+// epoch, the time is counted in milliseconds, and seq tells apart the points
+// of a series at the same millisecond.
+//
+// Points sort by time within a series, so any interval reads with a single
+// range read; the bucket groups the points of a series into ranges of a
+// bounded size, which can be cleared whole once they expire.
+package timeseries
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/encoding/timestamp"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// The usual sizes of buckets.
+const (
+	Hour = time.Hour
+	Day  = 24 * time.Hour
+)
+
+// Point is a point of a series, decoded from its key, and its value.
+type Point struct {
+	Time  time.Time
+	Seq   int64
+	Value []byte
+}
+
+// Keys builds the keys of the points of time series: (series, bucket, time,
+// seq) in Subspace, with buckets of the size Bucket. Series are identified by
+// any tuple element, such as a string or a tuple of labels packed as bytes.
+type Keys struct {
+	Subspace subspace.Subspace
+	Bucket   time.Duration
+}
+
+// New returns the Keys of the series in the subspace s, with buckets of the
+// given size. New will panic unless the size is a positive number of
+// milliseconds.
+func New(s subspace.Subspace, bucket time.Duration) Keys {
+	if bucket < time.Millisecond || bucket%time.Millisecond != 0 {
+		panic(fmt.Sprintf("timeseries: bucket size %v is not a positive number of milliseconds", bucket))
+	}
+	return Keys{Subspace: s, Bucket: bucket}
+}
+
+// bucket returns the bucket holding the time given in milliseconds.
+func (k Keys) bucket(ms int64) int64 {
+	size := int64(k.Bucket / time.Millisecond)
+	b := ms / size
+	if ms%size < 0 {
+		b--
+	}
+	return b
+}
+
+// BucketStart returns the start of the bucket holding the time t.
+func (k Keys) BucketStart(t time.Time) time.Time {
+	ms := k.bucket(timestamp.Millis.Int(t)) * int64(k.Bucket/time.Millisecond)
+	return time.Unix(0, 0).Add(time.Duration(ms) * time.Millisecond).UTC()
+}
+
+// Key returns the key of a point of a series.
+func (k Keys) Key(series tuple.Element, t time.Time, seq int64) lex.Key {
+	ms := timestamp.Millis.Int(t)
+	return k.Subspace.Pack(tuple.Tuple{series, k.bucket(ms), ms, seq})
+}
+
+// ParseKey decodes the key of a point, leaving its Value unset.
+func (k Keys) ParseKey(key lex.KeyConvertible) (tuple.Element, Point, error) {
+	t, err := k.Subspace.Unpack(key)
+	if err != nil {
+		return nil, Point{}, err
+	}
+	if len(t) != 4 {
+		return nil, Point{}, fmt.Errorf("timeseries: expected (series, bucket, time, seq), got %d elements", len(t))
+	}
+	ms, err := t.GetInt(2)
+	if err != nil {
+		return nil, Point{}, fmt.Errorf("timeseries: %v", err)
+	}
+	seq, err := t.GetInt(3)
+	if err != nil {
+		return nil, Point{}, fmt.Errorf("timeseries: %v", err)
+	}
+	tm, _ := timestamp.Millis.FromElement(ms)
+	return t[0], Point{Time: tm, Seq: seq}, nil
+}
+
+// bound returns the key preceding every point of a series at or after the
+// time t.
+func (k Keys) bound(series tuple.Element, t time.Time) lex.Key {
+	ms := timestamp.Millis.Int(t)
+	return k.Subspace.Pack(tuple.Tuple{series, k.bucket(ms), ms})
+}
+
+// Series returns the range of every point of a series.
+func (k Keys) Series(series tuple.Element) lex.KeyRange {
+	b, e := k.Subspace.Sub(series).LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: e}
+}
+
+// Range returns the range of the points of a series from the time from
+// included to the time to excluded.
+func (k Keys) Range(series tuple.Element, from, to time.Time) lex.KeyRange {
+	return lex.KeyRange{Begin: k.bound(series, from), End: k.bound(series, to)}
+}
+
+// BucketRange returns the range of the points of a series in the bucket
+// holding the time t.
+func (k Keys) BucketRange(series tuple.Element, t time.Time) lex.KeyRange {
+	b, e := k.Subspace.Sub(series, k.bucket(timestamp.Millis.Int(t))).LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: e}
+}
+
+// Expired returns the range of the points of a series older than the
+// retention period at the time now, for clearing them. The range ends at the
+// start of the bucket holding now minus the retention, so that expired points
+// are cleared a bucket at a time: points are kept for at most the retention
+// period plus the size of a bucket.
+func (k Keys) Expired(series tuple.Element, retention time.Duration, now time.Time) lex.KeyRange {
+	b, _ := k.Subspace.Sub(series).LexRangeKeys()
+	oldest := k.bucket(timestamp.Millis.Int(now.Add(-retention)))
+	return lex.KeyRange{Begin: b, End: k.Subspace.Pack(tuple.Tuple{series, oldest})}
+}
+
+// Window is an interval of time, from Start included to End excluded, and the
+// range of the points of a series in the interval.
+type Window struct {
+	Start, End time.Time
+	Range      lex.KeyRange
+}
+
+// Windows returns the consecutive windows of the given width covering the
+// interval from the time from included to the time to excluded, for
+// downsampling a series: the last window ends at to, and may be narrower.
+// Windows will panic if the width is not positive.
+func (k Keys) Windows(series tuple.Element, from, to time.Time, width time.Duration) []Window {
+	if width <= 0 {
+		panic(fmt.Sprintf("timeseries: window width %v is not positive", width))
+	}
+	var ws []Window
+	for start := from; start.Before(to); start = start.Add(width) {
+		end := start.Add(width)
+		if end.After(to) {
+			end = to
+		}
+		ws = append(ws, Window{start, end, k.Range(series, start, end)})
+	}
+	return ws
+}
+
+// Downsample reads the points of a series from the time from included to the
+// time to excluded, a window of the given width at a time (see Windows), and
+// calls fn with every window and its points, in order, such as to aggregate
+// the points of every window. Windows without points are passed to fn too.
+// Downsample stops at the first error, which it returns.
+func (k Keys) Downsample(store lex.KVStore, series tuple.Element, from, to time.Time, width time.Duration,
+	fn func(w Window, points []Point) error) error {
+	for _, w := range k.Windows(series, from, to, width) {
+		kvs, err := store.GetRange(w.Range, lex.RangeOptions{})
+		if err != nil {
+			return err
+		}
+		points := make([]Point, len(kvs))
+		for i, kv := range kvs {
+			_, p, err := k.ParseKey(kv.Key)
+			if err != nil {
+				return err
+			}
+			p.Value = kv.Value
+			points[i] = p
+		}
+		if err := fn(w, points); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package ttl provides an expiration index on an ordered key-value store: an
+// index of the primary keys of records by the time they expire, from which
+// reapers read the records expired at a given time with a single range read,
+// earliest first.
+//
+// The index is made of entries (expires, key), where the expiration time is
+// counted in milliseconds, and of the expiration time of every key, so that
+// the entry of a key can be moved when its expiration time changes. Set and
+// Remove read the expiration time of the key before writing, so concurrent
+// writers must update an index in transactions.
+package ttl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abdullin/lex-go"
+	"github.com/abdullin/lex-go/encoding/timestamp"
+	"github.com/abdullin/lex-go/subspace"
+	"github.com/abdullin/lex-go/tuple"
+)
+
+// Index builds the keys of an expiration index: (expires, key) in Entries,
+// and (key) in Keys, holding the packed expiration time of the key.
+type Index struct {
+	Entries subspace.Subspace
+	Keys    subspace.Subspace
+}
+
+// New returns the Index in the subspace s, whose entries are stored under
+// s.Sub(0) and whose expiration times are stored under s.Sub(1).
+func New(s subspace.Subspace) Index {
+	return Index{Entries: s.Sub(0), Keys: s.Sub(1)}
+}
+
+// Entry returns the key of the entry of a primary key expiring at a time.
+func (x Index) Entry(expires time.Time, key lex.KeyConvertible) lex.Key {
+	return x.Entries.Pack(tuple.Tuple{timestamp.Millis.Element(expires), []byte(key.LexKey())})
+}
+
+// ParseEntry decodes the key of an entry.
+func (x Index) ParseEntry(k lex.KeyConvertible) (time.Time, lex.Key, error) {
+	t, err := x.Entries.Unpack(k)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if len(t) != 2 {
+		return time.Time{}, nil, fmt.Errorf("ttl: expected (expires, key), got %d elements", len(t))
+	}
+	expires, err := timestamp.Millis.FromElement(t[0])
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	key, err := t.GetBytes(1)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("ttl: %v", err)
+	}
+	return expires, lex.Key(key), nil
+}
+
+// expiresKey returns the key holding the expiration time of a primary key.
+func (x Index) expiresKey(key lex.KeyConvertible) lex.Key {
+	return x.Keys.Pack(tuple.Tuple{[]byte(key.LexKey())})
+}
+
+// Expired returns the range of the entries of the keys expired at the time
+// now: those expiring at or before now.
+func (x Index) Expired(now time.Time) lex.KeyRange {
+	b, _ := x.Entries.LexRangeKeys()
+	return lex.KeyRange{Begin: b, End: x.Entries.Pack(tuple.Tuple{timestamp.Millis.Int(now) + 1})}
+}
+
+// Expires returns the expiration time of a primary key, and false if the key
+// is not in the index.
+func (x Index) Expires(store lex.KVStore, key lex.KeyConvertible) (time.Time, bool, error) {
+	v, err := lex.Get(store, x.expiresKey(key))
+	if err != nil || v == nil {
+		return time.Time{}, false, err
+	}
+	t, err := tuple.Unpack(v)
+	if err == nil && len(t) != 1 {
+		err = fmt.Errorf("expected (expires), got %d elements", len(t))
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("ttl: invalid expiration time of %s: %v", key.LexKey(), err)
+	}
+	expires, err := timestamp.Millis.FromElement(t[0])
+	return expires, err == nil, err
+}
+
+// Set sets the expiration time of a primary key, moving its entry if the key
+// is in the index already.
+func (x Index) Set(store lex.KVStore, key lex.KeyConvertible, expires time.Time) error {
+	if err := x.Remove(store, key); err != nil {
+		return err
+	}
+	if err := store.Set(x.Entry(expires, key), nil); err != nil {
+		return err
+	}
+	return store.Set(x.expiresKey(key), tuple.Tuple{timestamp.Millis.Element(expires)}.Pack())
+}
+
+// Remove removes a primary key from the index, such as when its record is
+// deleted before it expires. Removing a missing key is not an error.
+func (x Index) Remove(store lex.KVStore, key lex.KeyConvertible) error {
+	expires, ok, err := x.Expires(store, key)
+	if err != nil || !ok {
+		return err
+	}
+	if err := store.Clear(x.Entry(expires, key)); err != nil {
+		return err
+	}
+	return store.Clear(x.expiresKey(key))
+}
+
+// Reap reads at most limit primary keys expired at the time now, earliest
+// first, or every such key if limit is not positive, and calls fn with every
+// key, such as to delete its record, before removing it from the index. Reap
+// returns the number of keys removed, and stops at the first error of fn,
+// leaving its key in the index.
+func (x Index) Reap(store lex.KVStore, now time.Time, limit int, fn func(key lex.Key) error) (int, error) {
+	kvs, err := store.GetRange(x.Expired(now), lex.RangeOptions{Limit: limit})
+	if err != nil {
+		return 0, err
+	}
+	for i, kv := range kvs {
+		_, key, err := x.ParseEntry(kv.Key)
+		if err != nil {
+			return i, err
+		}
+		if err := fn(key); err != nil {
+			return i, err
+		}
+		if err := store.Clear(kv.Key); err != nil {
+			return i, err
+		}
+		if err := store.Clear(x.expiresKey(key)); err != nil {
+			return i, err
+		}
+	}
+	return len(kvs), nil
+}
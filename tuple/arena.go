@@ -0,0 +1,177 @@
+package tuple
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const arenaChunk = 64 << 10
+
+// Arena owns the memory of tuples decoded through it: the element slices and
+// the contents of their []byte and string elements. Calling Reset releases
+// all of it at once to be reused by the next batch, which gives long-running
+// scan pipelines predictable memory behavior.
+//
+// Tuples decoded through an Arena (and every []byte and string within them)
+// must not be used after the next call to Reset; use Clone on a Tuple that
+// has to be retained. When built with the lexdebug build tag, Reset poisons
+// the released memory and Valid reports tuples from earlier generations, so
+// use-after-reset bugs surface in tests instead of as silent corruption.
+//
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	bytes [][]byte
+	elems [][]Element
+	bi    int // index of the current byte chunk
+	ei    int // index of the current element chunk
+
+	arenaDebug
+}
+
+// NewArena returns an empty Arena.
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// allocBytes returns a zero-length slice with capacity n from the arena.
+func (a *Arena) allocBytes(n int) []byte {
+	for ; a.bi < len(a.bytes); a.bi++ {
+		c := a.bytes[a.bi]
+		if cap(c)-len(c) >= n {
+			a.bytes[a.bi] = c[:len(c)+n]
+			return c[len(c) : len(c) : len(c)+n]
+		}
+	}
+	size := arenaChunk
+	if n > size {
+		size = n
+	}
+	c := make([]byte, n, size)
+	a.bytes = append(a.bytes, c)
+	return c[:0:n]
+}
+
+// allocElems returns a Tuple of length n from the arena.
+func (a *Arena) allocElems(n int) Tuple {
+	for ; a.ei < len(a.elems); a.ei++ {
+		c := a.elems[a.ei]
+		if cap(c)-len(c) >= n {
+			a.elems[a.ei] = c[:len(c)+n]
+			return Tuple(c[len(c) : len(c)+n : len(c)+n])
+		}
+	}
+	size := arenaChunk / 16
+	if n > size {
+		size = n
+	}
+	c := make([]Element, n, size)
+	a.elems = append(a.elems, c)
+	return Tuple(c[:n:n])
+}
+
+// unescape decodes the escaped bytes of a []byte or string element starting
+// at b[0] into the arena, and returns them with the length of the encoding.
+func (a *Arena) unescape(b []byte) ([]byte, int, error) {
+	n, err := elementLen(b)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := a.allocBytes(n - 2)
+	for i := 1; i < n-1; i++ {
+		out = append(out, b[i])
+		if b[i] == 0x00 {
+			i++
+		}
+	}
+	return out, n, nil
+}
+
+// Unpack decodes a packed tuple like Unpack, allocating the tuple and the
+// contents of its []byte and string elements from the arena.
+func (a *Arena) Unpack(b []byte) (Tuple, error) {
+	spans, err := Spans(b)
+	if err != nil {
+		return nil, err
+	}
+
+	t := a.allocElems(len(spans))
+	for i, s := range spans {
+		if t[i], err = a.decodeElement(b[s.Begin:s.End]); err != nil {
+			return nil, err
+		}
+	}
+
+	a.track(t)
+	return t, nil
+}
+
+// UnpackInto decodes a packed tuple into t like UnpackInto, reusing the
+// backing array of t but allocating the contents of its []byte and string
+// elements from the arena. The elements of t must not be used after the next
+// call to Reset, although t itself may be reused.
+func (a *Arena) UnpackInto(b []byte, t *Tuple) error {
+	*t = (*t)[:0]
+	for i := 0; i < len(b); {
+		n, err := elementLen(b[i:])
+		if err != nil {
+			return err
+		}
+		el, err := a.decodeElement(b[i : i+n])
+		if err != nil {
+			return err
+		}
+		*t = append(*t, el)
+		i += n
+	}
+	return nil
+}
+
+// decodeElement decodes the single element encoded by b, allocating the
+// contents of []byte and string elements from the arena.
+func (a *Arena) decodeElement(b []byte) (Element, error) {
+	switch b[0] {
+	case 0x01, 0x02:
+		v, _, err := a.unescape(b)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case b[0] == 0x01:
+			return v, nil
+		case len(v) > 0:
+			return unsafe.String(&v[0], len(v)), nil
+		}
+		return "", nil
+	}
+	el, _, err := decodeElement(b)
+	return el, err
+}
+
+// Reset releases every tuple decoded through the arena, making its memory
+// available to subsequent calls to Unpack.
+func (a *Arena) Reset() {
+	a.release()
+	for i := range a.bytes {
+		a.bytes[i] = a.bytes[i][:0]
+	}
+	for i := range a.elems {
+		a.elems[i] = a.elems[i][:0]
+	}
+	a.bi, a.ei = 0, 0
+}
+
+// Valid reports whether t may still be used, that is whether it was not
+// decoded through the arena before the last call to Reset. Without the
+// lexdebug build tag the arena does not track tuples and Valid always
+// returns true.
+func (a *Arena) Valid(t Tuple) bool {
+	return a.valid(t)
+}
+
+// MustValid panics if Valid(t) returns false.
+func (a *Arena) MustValid(t Tuple) {
+	if !a.Valid(t) {
+		panic(fmt.Sprintf("tuple used after arena reset (%d elements)", len(t)))
+	}
+}
@@ -0,0 +1,47 @@
+//go:build lexdebug
+
+package tuple
+
+// arenaDebug tracks the generation each tuple was decoded in, so that
+// tuples used after a Reset can be detected.
+type arenaDebug struct {
+	gen  uint64
+	gens map[*Element]uint64
+}
+
+// releasedElement replaces the elements of released tuples, so that using
+// them fails loudly (Pack panics on it, and type assertions fail).
+type releasedElement struct{}
+
+func (d *arenaDebug) track(t Tuple) {
+	if len(t) == 0 {
+		return
+	}
+	if d.gens == nil {
+		d.gens = map[*Element]uint64{}
+	}
+	d.gens[&t[0]] = d.gen
+}
+
+func (d *arenaDebug) valid(t Tuple) bool {
+	if len(t) == 0 {
+		return true
+	}
+	gen, ok := d.gens[&t[0]]
+	return !ok || gen == d.gen
+}
+
+// release poisons the memory of released tuples.
+func (a *Arena) release() {
+	for _, c := range a.bytes {
+		for i := range c {
+			c[i] = 0xDD
+		}
+	}
+	for _, c := range a.elems {
+		for i := range c {
+			c[i] = releasedElement{}
+		}
+	}
+	a.gen++
+}
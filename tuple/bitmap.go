@@ -0,0 +1,84 @@
+package tuple
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// bitmapCode is the user typecode of Bitmap elements. Following the
+// length-prefix convention for user typecodes (see UnpackLenient), it is
+// followed by the length of the bitmap.
+const bitmapCode = 0x45
+
+const bitmapSize = 8
+
+// Bitmap is an Element holding up to 64 boolean flags, numbered from 0, in a
+// fixed width of 10 bytes, for index keys partitioning on several flags at
+// once. Flag 0 is the most significant bit, so packed bitmaps sort by flag 0
+// first, then by flag 1, and so on: the keys of bitmaps sharing their first
+// flags are contiguous.
+type Bitmap uint64
+
+func init() {
+	RegisterType(Type{
+		Name:   "bitmap",
+		Code:   bitmapCode,
+		Value:  Bitmap(0),
+		Encode: func(el Element) []byte { return encodeBitmap(el.(Bitmap)) },
+		Decode: func(b []byte) (Element, int, error) { return decodeBitmap(b) },
+		Size:   fixed(1 + bitmapSize),
+	})
+}
+
+func encodeBitmap(m Bitmap) []byte {
+	b := make([]byte, 2+bitmapSize)
+	b[0], b[1] = bitmapCode, bitmapSize
+	binary.BigEndian.PutUint64(b[2:], uint64(m))
+	return b
+}
+
+func decodeBitmap(b []byte) (Bitmap, int, error) {
+	if len(b) < 2+bitmapSize || b[1] != bitmapSize {
+		return 0, 0, errTruncated
+	}
+	return Bitmap(binary.BigEndian.Uint64(b[2:])), 2 + bitmapSize, nil
+}
+
+// NewBitmap returns the bitmap of the flags, the first of which is flag 0.
+// NewBitmap will panic if there are more than 64 flags.
+func NewBitmap(flags ...bool) Bitmap {
+	if len(flags) > 64 {
+		panic("more than 64 flags")
+	}
+	var m Bitmap
+	for i, f := range flags {
+		m = m.Set(i, f)
+	}
+	return m
+}
+
+// bit returns the bit of flag i, which will panic if i is not in [0, 64).
+func bit(i int) Bitmap {
+	if i < 0 || i >= 64 {
+		panic("flag index out of range")
+	}
+	return 1 << (63 - uint(i))
+}
+
+// Has returns true if flag i is set.
+func (m Bitmap) Has(i int) bool {
+	return m&bit(i) != 0
+}
+
+// Set returns the bitmap with flag i set to v.
+func (m Bitmap) Set(i int, v bool) Bitmap {
+	if v {
+		return m | bit(i)
+	}
+	return m &^ bit(i)
+}
+
+// Count returns the number of flags set.
+func (m Bitmap) Count() int {
+	return bits.OnesCount64(uint64(m))
+}
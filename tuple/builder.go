@@ -0,0 +1,105 @@
+package tuple
+
+import (
+	"bytes"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Builder accumulates the encoding of a tuple, element by element, without
+// building a Tuple: the typed Append methods encode their argument directly,
+// so that hot paths packing keys of a known shape neither box elements into
+// interfaces nor allocate more than the buffer of the Builder. The Append
+// methods return the Builder, so that calls can be chained:
+//
+//	key := new(tuple.Builder).AppendString("users").AppendInt(id).Key()
+//
+// The zero-value of Builder is an empty Builder ready for use. A Builder may
+// be reused with Reset.
+type Builder struct {
+	buf bytes.Buffer
+	n   int
+}
+
+// NewBuilder returns an empty Builder whose buffer can hold size bytes before
+// growing.
+func NewBuilder(size int) *Builder {
+	b := &Builder{}
+	b.buf.Grow(size)
+	return b
+}
+
+// AppendNil appends a nil element.
+func (b *Builder) AppendNil() *Builder {
+	b.buf.WriteByte(0x00)
+	b.n++
+	return b
+}
+
+// AppendBytes appends a []byte element.
+func (b *Builder) AppendBytes(v []byte) *Builder {
+	encodeBytes(&b.buf, 0x01, v)
+	b.n++
+	return b
+}
+
+// AppendString appends a string element.
+func (b *Builder) AppendString(v string) *Builder {
+	encodeString(&b.buf, v)
+	b.n++
+	return b
+}
+
+// AppendInt appends an integer element.
+func (b *Builder) AppendInt(v int64) *Builder {
+	encodeInt(&b.buf, v)
+	b.n++
+	return b
+}
+
+// AppendElement appends an element of any type supported by Pack, such as a
+// registered type. AppendElement will panic in the same circumstances as
+// Pack.
+func (b *Builder) AppendElement(el Element) *Builder {
+	Tuple{el}.pack(&b.buf)
+	b.n++
+	return b
+}
+
+// AppendTuple appends the elements of a tuple. AppendTuple will panic in the
+// same circumstances as Pack.
+func (b *Builder) AppendTuple(t Tuple) *Builder {
+	t.pack(&b.buf)
+	b.n += len(t)
+	return b
+}
+
+// Len returns the number of elements appended so far.
+func (b *Builder) Len() int {
+	return b.n
+}
+
+// Bytes returns the encoding of the elements appended so far. The slice
+// aliases the buffer of the Builder, and is only valid until the next call
+// to an Append method or to Reset.
+func (b *Builder) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Pack returns a copy of the encoding of the elements appended so far, as
+// Pack would return it for the tuple of these elements.
+func (b *Builder) Pack() []byte {
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+// Key returns a copy of the encoding of the elements appended so far as a
+// key.
+func (b *Builder) Key() lex.Key {
+	return lex.Key(b.Pack())
+}
+
+// Reset empties the Builder, retaining its buffer.
+func (b *Builder) Reset() {
+	b.buf.Reset()
+	b.n = 0
+}
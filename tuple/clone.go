@@ -0,0 +1,64 @@
+package tuple
+
+import (
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Clone returns a deep copy of the tuple, which shares no memory with t. The
+// []byte elements of tuples decoded by UnpackWithSuffix and UnpackLenient
+// (RawSuffix and OpaqueElement) alias the decoded bytes, and every []byte and
+// string element of tuples decoded through an Arena aliases the arena: Clone
+// such tuples to retain them beyond the lifetime of the decoded bytes, such
+// as a buffer reused by a scan, or beyond the next Reset of the arena.
+//
+// Elements of registered types are copied by value, except for net.IP,
+// Decimal and PrefixedBytes, whose contents are copied as well.
+func (t Tuple) Clone() Tuple {
+	if t == nil {
+		return nil
+	}
+	c := make(Tuple, len(t))
+	for i, el := range t {
+		c[i] = CloneElement(el)
+	}
+	return c
+}
+
+// CloneElement returns a deep copy of an element (see Tuple.Clone).
+func CloneElement(el Element) Element {
+	switch el := el.(type) {
+	case []byte:
+		return cloneBytes(el)
+	case string:
+		return strings.Clone(el)
+	case RawSuffix:
+		return RawSuffix(cloneBytes(el))
+	case OpaqueElement:
+		return OpaqueElement(cloneBytes(el))
+	case PrefixedBytes:
+		return PrefixedBytes(cloneBytes(el))
+	case lex.Key:
+		return lex.Key(cloneBytes(el))
+	case net.IP:
+		return net.IP(cloneBytes(el))
+	case Decimal:
+		if el.Unscaled != nil {
+			el.Unscaled = new(big.Int).Set(el.Unscaled)
+		}
+		return el
+	}
+	return el
+}
+
+// cloneBytes copies b, preserving the distinction between nil and empty
+// slices.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,36 @@
+package tuple
+
+import "fmt"
+
+// Codec encodes tuples into keys and decodes them back. The tuple layer of
+// this package is the Default codec; packages implementing the key encodings
+// of other databases provide alternate codecs, so code building keys can be
+// written once against a Codec.
+type Codec interface {
+	// Pack returns the encoding of the tuple, or an error if it holds an
+	// element the codec cannot encode.
+	Pack(t Tuple) ([]byte, error)
+
+	// Unpack returns the tuple encoded by b, or an error if b is not a
+	// well-formed encoding.
+	Unpack(b []byte) (Tuple, error)
+}
+
+// Default is the Codec of the tuple layer, whose Pack returns an error in the
+// same circumstances as Tuple.Pack panics.
+var Default Codec = defaultCodec{}
+
+type defaultCodec struct{}
+
+func (defaultCodec) Pack(t Tuple) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return t.Pack(), nil
+}
+
+func (defaultCodec) Unpack(b []byte) (Tuple, error) {
+	return Unpack(b)
+}
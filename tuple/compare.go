@@ -0,0 +1,107 @@
+package tuple
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Compare returns an integer comparing two tuples in the order of their
+// packed encodings: 0 if a == b, -1 if a < b and +1 if a > b. Elements of
+// different types are ordered by typecode, integers numerically and byte
+// strings and strings lexicographically; a tuple sorts before every tuple
+// it is a prefix of.
+//
+// Compare does not pack the tuples, except from the first element of a
+// registered type (or an OpaqueElement or a RawSuffix) on, where it compares
+// the encodings of the remaining elements. Compare will panic in the same
+// circumstances as Pack.
+func Compare(a, b Tuple) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, ok := builtinRank(a[i])
+		if !ok {
+			return bytes.Compare(a[i:].Pack(), b[i:].Pack())
+		}
+		cb, ok := builtinRank(b[i])
+		if !ok {
+			return bytes.Compare(a[i:].Pack(), b[i:].Pack())
+		}
+		if ca != cb {
+			if ca < cb {
+				return -1
+			}
+			return 1
+		}
+		if c := compareElements(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// builtinRank returns the typecode of the encoding of an element of a type
+// encoded by Pack (0x14 for every integer), and false for other elements.
+func builtinRank(el Element) (byte, bool) {
+	switch el.(type) {
+	case nil:
+		return 0x00, true
+	case int64, uint32, uint64, int, byte:
+		return 0x14, true
+	case []byte:
+		return 0x01, true
+	case string:
+		return 0x02, true
+	case RawSuffix, OpaqueElement:
+		return 0, false
+	case lex.KeyConvertible:
+		return 0x01, true
+	}
+	return 0, false
+}
+
+// compareElements compares two elements of the same builtinRank.
+func compareElements(a, b Element) int {
+	switch a := a.(type) {
+	case nil:
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case int64, uint32, uint64, int, byte:
+		x, _ := toInt64(a)
+		y, _ := toInt64(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return bytes.Compare(bytesOf(a), bytesOf(b))
+}
+
+// bytesOf returns the bytes of a []byte or lex.KeyConvertible element.
+func bytesOf(el Element) []byte {
+	if b, ok := el.([]byte); ok {
+		return b
+	}
+	return el.(lex.KeyConvertible).LexKey()
+}
+
+// Equal returns true if the tuples have as many elements, which are equal
+// one by one: integers of different Go types are equal if their values are,
+// byte strings are compared by content (whether given as []byte or
+// lex.KeyConvertible), and elements of registered types by their encoding.
+// Tuples holding a RawSuffix or an OpaqueElement may pack to the same bytes
+// without being equal, as these elements may hold the encoding of several
+// others. Equal will panic in the same circumstances as Pack.
+func (t Tuple) Equal(o Tuple) bool {
+	return len(t) == len(o) && Compare(t, o) == 0
+}
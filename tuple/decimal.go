@@ -0,0 +1,236 @@
+package tuple
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// decimalCode is the user typecode of Decimal elements. Unlike the other user
+// typecodes of this package, it is not followed by a length, which would
+// break the numeric order of variable-length decimals: the encoding is
+// self-delimiting instead, so Decimal elements can only be skipped by readers
+// which register the type.
+const decimalCode = 0x43
+
+// decimalMaxDigits is the maximum number of digits of an unscaled value.
+const decimalMaxDigits = 1000
+
+// The classes of decimals, which start their encodings.
+const (
+	decimalNeg  = 0x00
+	decimalZero = 0x01
+	decimalPos  = 0x02
+)
+
+// Decimal is an Element holding a fixed-point decimal number: the integer
+// Unscaled divided by 10 to the power of Scale, so that 12.30 has an unscaled
+// value of 1230 and a scale of 2. A nil Unscaled is zero.
+//
+// Packed decimals sort numerically, whatever their scales, so amounts can be
+// indexed without the rounding hazards of floating-point numbers. Equal
+// numbers of different scales, such as 1.5 and 1.50, are distinct elements
+// which sort by scale. Pack will panic if the unscaled value has more than
+// 1000 digits.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int32
+}
+
+func init() {
+	RegisterType(Type{
+		Name:   "decimal",
+		Code:   decimalCode,
+		Value:  Decimal{},
+		Encode: func(el Element) []byte { return encodeDecimal(el.(Decimal)) },
+		Decode: func(b []byte) (Element, int, error) { return decodeDecimal(b) },
+	})
+}
+
+// NewDecimal returns the decimal unscaled divided by 10 to the power of scale.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{big.NewInt(unscaled), scale}
+}
+
+// ParseDecimal parses a decimal number such as -12.30, whose scale is the
+// number of digits after the decimal point.
+func ParseDecimal(s string) (Decimal, error) {
+	digits, scale := s, 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits, scale = s[:i]+s[i+1:], len(s)-i-1
+		if scale == 0 {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+	}
+	u, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return Decimal{u, int32(scale)}, nil
+}
+
+// sign returns -1, 0 or +1 depending on the sign of the decimal.
+func (d Decimal) sign() int {
+	if d.Unscaled == nil {
+		return 0
+	}
+	return d.Unscaled.Sign()
+}
+
+// Rat returns the value of the decimal.
+func (d Decimal) Rat() *big.Rat {
+	r := new(big.Rat)
+	if d.Unscaled != nil {
+		r.SetInt(d.Unscaled)
+	}
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs32(d.Scale))), nil)
+	if d.Scale >= 0 {
+		return r.Quo(r, new(big.Rat).SetInt(p))
+	}
+	return r.Mul(r, new(big.Rat).SetInt(p))
+}
+
+// Cmp compares the values of two decimals, returning -1, 0 or +1. Unlike
+// packed decimals, decimals of equal values and different scales compare
+// equal.
+func (d Decimal) Cmp(o Decimal) int {
+	return d.Rat().Cmp(o.Rat())
+}
+
+// String returns the decimal with Scale digits after the decimal point, such
+// as -12.30.
+func (d Decimal) String() string {
+	u := new(big.Int)
+	if d.Unscaled != nil {
+		u.Abs(d.Unscaled)
+	}
+	s := u.String()
+	switch {
+	case d.Scale < 0:
+		s += strings.Repeat("0", int(-d.Scale))
+	case d.Scale > 0:
+		if n := int(d.Scale) + 1 - len(s); n > 0 {
+			s = strings.Repeat("0", n) + s
+		}
+		s = s[:len(s)-int(d.Scale)] + "." + s[len(s)-int(d.Scale):]
+	}
+	if d.sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+func abs32(n int32) int64 {
+	if n < 0 {
+		return -int64(n)
+	}
+	return int64(n)
+}
+
+// encodeDecimal encodes a decimal as its class, then for non-zero decimals
+// the exponent e and the significant digits d1 d2 ... dn (without trailing
+// zeros) of the absolute value 0.d1d2...dn × 10^e, and finally the scale.
+// The exponent is 8 bytes of a big-endian int64 with its sign bit flipped,
+// and every digit is a byte holding its value plus 1, followed by a 0 byte.
+// The bytes of the exponent and the digits of negative decimals are inverted,
+// so that greater magnitudes sort first.
+func encodeDecimal(d Decimal) []byte {
+	b := []byte{decimalCode, decimalZero}
+	if sign := d.sign(); sign != 0 {
+		digits := new(big.Int).Abs(d.Unscaled).String()
+		if len(digits) > decimalMaxDigits {
+			panic(fmt.Sprintf("decimal has %d digits, more than %d", len(digits), decimalMaxDigits))
+		}
+		exp := int64(len(digits)) - int64(d.Scale)
+		digits = strings.TrimRight(digits, "0")
+
+		var e [8]byte
+		binary.BigEndian.PutUint64(e[:], uint64(exp)^(1<<63))
+		b[1] = decimalPos
+		b = append(b, e[:]...)
+		for i := 0; i < len(digits); i++ {
+			b = append(b, digits[i]-'0'+1)
+		}
+		b = append(b, 0x00)
+		if sign < 0 {
+			b[1] = decimalNeg
+			for i := 2; i < len(b); i++ {
+				b[i] = ^b[i]
+			}
+		}
+	}
+	var s [4]byte
+	binary.BigEndian.PutUint32(s[:], uint32(d.Scale)^(1<<31))
+	return append(b, s[:]...)
+}
+
+func decodeDecimal(b []byte) (Decimal, int, error) {
+	if len(b) < 2 {
+		return Decimal{}, 0, errTruncated
+	}
+	class, n := b[1], 2
+
+	var digits []byte
+	var exp int64
+	switch class {
+	case decimalZero:
+	case decimalNeg, decimalPos:
+		var inv byte
+		if class == decimalNeg {
+			inv = 0xFF
+		}
+		if len(b) < n+8 {
+			return Decimal{}, 0, errTruncated
+		}
+		var e [8]byte
+		for i := range e {
+			e[i] = b[n+i] ^ inv
+		}
+		exp = int64(binary.BigEndian.Uint64(e[:]) ^ (1 << 63))
+		n += 8
+		for {
+			if n >= len(b) {
+				return Decimal{}, 0, errTruncated
+			}
+			c := b[n] ^ inv
+			n++
+			if c == 0x00 {
+				break
+			}
+			if c > 10 {
+				return Decimal{}, 0, fmt.Errorf("invalid decimal digit %02x", c)
+			}
+			if len(digits) == decimalMaxDigits {
+				return Decimal{}, 0, fmt.Errorf("decimal has more than %d digits", decimalMaxDigits)
+			}
+			digits = append(digits, '0'+c-1)
+		}
+		if len(digits) == 0 || digits[0] == '0' || digits[len(digits)-1] == '0' {
+			return Decimal{}, 0, fmt.Errorf("invalid decimal digits %q", digits)
+		}
+	default:
+		return Decimal{}, 0, fmt.Errorf("invalid decimal class %02x", class)
+	}
+
+	if len(b) < n+4 {
+		return Decimal{}, 0, errTruncated
+	}
+	d := Decimal{new(big.Int), int32(binary.BigEndian.Uint32(b[n:]) ^ (1 << 31))}
+	n += 4
+	if class == decimalZero {
+		return d, n, nil
+	}
+
+	// The digits are followed by as many zeros as needed to reach the scale.
+	zeros := exp - int64(len(digits)) + int64(d.Scale)
+	if zeros < 0 || zeros > int64(decimalMaxDigits-len(digits)) {
+		return Decimal{}, 0, fmt.Errorf("decimal exponent %d does not match scale %d", exp, d.Scale)
+	}
+	d.Unscaled.SetString(string(digits), 10)
+	d.Unscaled.Mul(d.Unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(zeros), nil))
+	if class == decimalNeg {
+		d.Unscaled.Neg(d.Unscaled)
+	}
+	return d, n, nil
+}
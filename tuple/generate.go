@@ -0,0 +1,163 @@
+package tuple
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"net"
+)
+
+// GenerateOptions configures Generate.
+type GenerateOptions struct {
+	// MaxLen is the maximum number of elements of a tuple. It defaults to 8.
+	MaxLen int
+
+	// MaxBytes is the maximum length of byte strings and of strings (in
+	// runes). It defaults to 16.
+	MaxBytes int
+
+	// Kinds lists the kinds of the generated elements, drawn with equal
+	// probability: a kind listed twice is drawn twice as often. It defaults
+	// to every kind of the types decoded by Unpack and registered by this
+	// package. KindAny stands for any of these kinds.
+	Kinds []Kind
+}
+
+// generatedKinds lists the kinds Generate can produce.
+var generatedKinds = []Kind{
+	KindNil, KindBytes, KindString, KindInt,
+	KindUUID, KindMoney, KindULID, KindIP, KindDecimal, KindPrefixedBytes,
+	KindBitmap,
+}
+
+// Generate returns a random tuple, for property-based tests. Tuples do not
+// nest in this package, so the size of a tuple is only bounded by its number
+// of elements. Generate favours the values whose encodings are the most
+// varied: byte strings and strings holding 0x00 and 0xFF bytes, integers
+// close to the boundaries of the integer encodings, and so on. Generate will
+// panic if opts lists a kind it cannot generate.
+func Generate(r *rand.Rand, opts GenerateOptions) Tuple {
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = 8
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 16
+	}
+	if len(opts.Kinds) == 0 {
+		opts.Kinds = generatedKinds
+	}
+
+	t := make(Tuple, r.Intn(opts.MaxLen+1))
+	for i := range t {
+		t[i] = generateElement(r, opts.Kinds[r.Intn(len(opts.Kinds))], opts.MaxBytes)
+	}
+	return t
+}
+
+// generatedBytes and generatedRunes are the bytes and runes of generated
+// byte strings and strings.
+var (
+	generatedBytes = []byte{0x00, 0x01, 0x7F, 0xFE, 0xFF, 'a', 'b'}
+	generatedRunes = []rune{0, 'a', 'b', 'z', 0x7F, 'é', '€', 0x10FFFF}
+)
+
+func generateElement(r *rand.Rand, k Kind, maxBytes int) Element {
+	switch k {
+	case KindAny:
+		return generateElement(r, generatedKinds[r.Intn(len(generatedKinds))], maxBytes)
+	case KindNil:
+		return nil
+	case KindBytes:
+		b := make([]byte, r.Intn(maxBytes+1))
+		for i := range b {
+			b[i] = generatedBytes[r.Intn(len(generatedBytes))]
+		}
+		return b
+	case KindString:
+		s := make([]rune, r.Intn(maxBytes+1))
+		for i := range s {
+			s[i] = generatedRunes[r.Intn(len(generatedRunes))]
+		}
+		return string(s)
+	case KindInt:
+		return generateInt(r)
+	case KindUUID:
+		var u UUID
+		r.Read(u[:])
+		return u
+	case KindULID:
+		var u ULID
+		r.Read(u[:])
+		return u
+	case KindMoney:
+		return Money{[]string{"EUR", "JPY", "USD"}[r.Intn(3)], generateInt(r)}
+	case KindIP:
+		ip := make(net.IP, []int{net.IPv4len, net.IPv6len}[r.Intn(2)])
+		r.Read(ip)
+		return ip
+	case KindDecimal:
+		return Decimal{big.NewInt(generateInt(r)), int32(r.Intn(21) - 5)}
+	case KindPrefixedBytes:
+		return PrefixedBytes(generateElement(r, KindBytes, maxBytes).([]byte))
+	case KindBitmap:
+		return Bitmap(generateInt(r))
+	}
+	panic(fmt.Sprintf("cannot generate elements of kind %s", k))
+}
+
+// generateInt returns an integer close to a power of 256, to 0 or to the
+// boundaries of int64.
+func generateInt(r *rand.Rand) int64 {
+	switch r.Intn(4) {
+	case 0:
+		return int64(r.Intn(512) - 256)
+	case 1:
+		return []int64{math.MinInt64, math.MinInt64 + 1, math.MaxInt64 - 1, math.MaxInt64}[r.Intn(4)]
+	case 2:
+		n := int64(1) << (8 * uint(r.Intn(7)+1))
+		n += int64(r.Intn(5) - 2)
+		if r.Intn(2) == 0 {
+			n = -n
+		}
+		return n
+	}
+	return r.Int63() - r.Int63()
+}
+
+// CheckRoundTrip returns an error unless the tuple decodes to an equal tuple
+// once packed, which packs to the same bytes again.
+func CheckRoundTrip(t Tuple) error {
+	p := t.Pack()
+	u, err := Unpack(p)
+	if err != nil {
+		return fmt.Errorf("packed tuple %#v does not decode: %v (encoding %x)", t, err, p)
+	}
+	if !u.Equal(t) {
+		return fmt.Errorf("packed tuple %#v decodes to %#v (encoding %x)", t, u, p)
+	}
+	if q := u.Pack(); !bytes.Equal(q, p) {
+		return fmt.Errorf("decoded tuple %#v packs to %x, expected %x", u, q, p)
+	}
+	return nil
+}
+
+// CheckOrdering returns an error unless two tuples compare like their packed
+// encodings (see Compare), and each packed tuple is a prefix of, and sorts
+// before, the packed concatenation of the tuples.
+func CheckOrdering(a, b Tuple) error {
+	pa, pb := a.Pack(), b.Pack()
+	if c, e := Compare(a, b), bytes.Compare(pa, pb); c != e {
+		return fmt.Errorf("Compare(%#v, %#v) = %d, but their encodings compare as %d (%x, %x)", a, b, c, e, pa, pb)
+	}
+
+	pab := append(append(Tuple{}, a...), b...).Pack()
+	if !bytes.HasPrefix(pab, pa) {
+		return fmt.Errorf("packed tuple %x is not a prefix of %x", pa, pab)
+	}
+	if len(b) > 0 && bytes.Compare(pa, pab) >= 0 {
+		return fmt.Errorf("packed tuple %x does not sort before %x", pa, pab)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package tuple
+
+import "fmt"
+
+// element returns the element at index i, or an error if i is out of range.
+func (t Tuple) element(i int) (Element, error) {
+	if i < 0 || i >= len(t) {
+		return nil, fmt.Errorf("element index %d out of range [0, %d)", i, len(t))
+	}
+	return t[i], nil
+}
+
+// mismatch returns the error of an element of an unexpected type.
+func mismatch(i int, el Element, want string) error {
+	return fmt.Errorf("element %d is %T (%v), not %s", i, el, el, want)
+}
+
+// GetInt returns the integer element at index i (an int64 as decoded by
+// Unpack, or an int). It returns an error if i is out of range or the
+// element is of another type.
+func (t Tuple) GetInt(i int) (int64, error) {
+	el, err := t.element(i)
+	if err != nil {
+		return 0, err
+	}
+	switch v := el.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	}
+	return 0, mismatch(i, el, "int64")
+}
+
+// GetString returns the string element at index i. It returns an error if i
+// is out of range or the element is of another type.
+func (t Tuple) GetString(i int) (string, error) {
+	el, err := t.element(i)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := el.(string); ok {
+		return v, nil
+	}
+	return "", mismatch(i, el, "string")
+}
+
+// GetBytes returns the []byte element at index i. It returns an error if i is
+// out of range or the element is of another type.
+func (t Tuple) GetBytes(i int) ([]byte, error) {
+	el, err := t.element(i)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := el.([]byte); ok {
+		return v, nil
+	}
+	return nil, mismatch(i, el, "[]byte")
+}
+
+// GetUUID returns the UUID element at index i. It returns an error if i is
+// out of range or the element is of another type.
+func (t Tuple) GetUUID(i int) (UUID, error) {
+	el, err := t.element(i)
+	if err != nil {
+		return UUID{}, err
+	}
+	if v, ok := el.(UUID); ok {
+		return v, nil
+	}
+	return UUID{}, mismatch(i, el, "UUID")
+}
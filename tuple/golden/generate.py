@@ -0,0 +1,111 @@
+#!/usr/bin/env python3
+"""Generates vectors.json, the corpus of tuple encoding vectors.
+
+Usage:
+
+    python3 generate.py > vectors.json          # packs with the fdb binding
+    python3 generate.py --spec > vectors.json   # packs with pack_spec below
+
+By default, the tuples are packed by the tuple layer of the official
+FoundationDB Python binding (pip install foundationdb, which needs the
+FoundationDB client library), so that the corpus is the reference encoding.
+With --spec, they are packed by pack_spec, a transcription of the encoding of
+the tuple specification
+(https://github.com/apple/foundationdb/blob/main/design/tuple.md) for the
+element types of the corpus, for environments without the client library.
+Both must produce the same corpus: run the script without --spec and diff its
+output with vectors.json to check the corpus against the binding.
+"""
+
+import base64
+import json
+import sys
+
+# The cases of the corpus: a name and a tuple of None, bytes, str and int
+# elements. Integers are limited to the range of int64, which the Go codec
+# supports.
+CASES = [
+    ("empty tuple", ()),
+    ("nil", (None,)),
+    ("empty bytes", (b"",)),
+    ("bytes with zero", (b"foo\x00bar",)),
+    ("zero byte", (b"\x00",)),
+    ("0xff byte", (b"\xff",)),
+    ("zero and 0xff bytes", (b"\x00\xff\x00\x00",)),
+    ("empty string", ("",)),
+    ("unicode string with zero", ("FÔO\u0000bar",)),
+    ("astral plane string", ("\U0001F600",)),
+    ("int 0", (0,)),
+    ("int -5551212", (-5551212,)),
+    ("mixed", (1, "a", None, b"b")),
+    ("nested nils", (None, None, b"", None)),
+]
+
+# Integers on both sides of every change of the length of their encoding,
+# from 1 to 8 bytes, and the bounds of int64.
+for n in range(1, 9):
+    bound = 1 << (8 * n)
+    for v in (bound // 256, bound - 1, bound):
+        if v < 1 << 63:
+            CASES.append(("int %d" % v, (v,)))
+            CASES.append(("int %d" % -v, (-v,)))
+CASES.append(("int max", ((1 << 63) - 1,)))
+CASES.append(("int min + 1", (-(1 << 63) + 1,)))
+CASES.append(("int min", (-(1 << 63),)))
+
+
+def escape(b):
+    return b.replace(b"\x00", b"\x00\xff") + b"\x00"
+
+
+def pack_spec(t):
+    out = b""
+    for el in t:
+        if el is None:
+            out += b"\x00"
+        elif isinstance(el, bytes):
+            out += b"\x01" + escape(el)
+        elif isinstance(el, str):
+            out += b"\x02" + escape(el.encode("utf-8"))
+        elif isinstance(el, int):
+            if el == 0:
+                out += b"\x14"
+                continue
+            n = (abs(el).bit_length() + 7) // 8
+            if el > 0:
+                out += bytes([0x14 + n]) + el.to_bytes(n, "big")
+            else:
+                out += bytes([0x14 - n]) + ((1 << (8 * n)) - 1 + el).to_bytes(n, "big")
+        else:
+            raise TypeError("unsupported element %r" % (el,))
+    return out
+
+
+def pack_fdb(t):
+    import fdb
+
+    fdb.api_version(630)
+    return fdb.tuple.pack(t)
+
+
+def to_json(el):
+    if el is None:
+        return None
+    if isinstance(el, bytes):
+        return {"bytes": base64.b64encode(el).decode("ascii")}
+    if isinstance(el, str):
+        return {"string": el}
+    return {"int": str(el)}
+
+
+def main():
+    pack = pack_spec if "--spec" in sys.argv[1:] else pack_fdb
+    lines = []
+    for name, t in CASES:
+        v = {"name": name, "tuple": [to_json(el) for el in t], "packed": pack(t).hex()}
+        lines.append("  " + json.dumps(v, ensure_ascii=False))
+    sys.stdout.write("[\n" + ",\n".join(lines) + "\n]\n")
+
+
+if __name__ == "__main__":
+    main()
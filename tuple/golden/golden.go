@@ -0,0 +1,118 @@
+// Package golden ships a corpus of tuple encoding vectors, and verifies that
+// a tuple.Codec reproduces them byte for byte.
+//
+// The corpus, vectors.json, covers the element types defined by the
+// FoundationDB tuple specification which this module supports (nil, byte
+// strings, unicode strings and integers), with the encodings that every
+// FoundationDB binding produces, including the examples of the
+// specification. It is a JSON array of objects holding a name, a tuple in the
+// representation of tuple.ToJSON and the packed tuple in hexadecimal, so that
+// it can be checked against the bindings of other languages as well.
+//
+// The corpus is generated by generate.py, which packs its tuples with the
+// tuple layer of the FoundationDB Python binding, or with a transcription of
+// the specification when run with --spec, and covers integers on both sides
+// of every change of the length of their encoding, up to the bounds of int64.
+package golden
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/abdullin/lex-go/tuple"
+)
+
+//go:embed vectors.json
+var corpus []byte
+
+// Vector is a tuple and its expected encoding.
+type Vector struct {
+	Name   string
+	Tuple  tuple.Tuple
+	Packed []byte
+}
+
+type jsonVector struct {
+	Name   string          `json:"name"`
+	Tuple  json.RawMessage `json:"tuple"`
+	Packed string          `json:"packed"`
+}
+
+// Corpus returns the raw JSON corpus of vectors.
+func Corpus() []byte {
+	return append([]byte{}, corpus...)
+}
+
+// Vectors returns the vectors of the corpus.
+func Vectors() ([]Vector, error) {
+	var js []jsonVector
+	if err := json.Unmarshal(corpus, &js); err != nil {
+		return nil, err
+	}
+
+	vs := make([]Vector, len(js))
+	for i, j := range js {
+		t, err := tuple.FromJSON(j.Tuple)
+		if err != nil {
+			return nil, fmt.Errorf("vector %s: %v", j.Name, err)
+		}
+		b, err := hex.DecodeString(j.Packed)
+		if err != nil {
+			return nil, fmt.Errorf("vector %s: %v", j.Name, err)
+		}
+		vs[i] = Vector{j.Name, t, b}
+	}
+	return vs, nil
+}
+
+// Failure is a vector which a codec did not reproduce.
+type Failure struct {
+	Vector Vector
+
+	// Op is the failed operation, "pack" or "unpack".
+	Op string
+
+	// Packed is the encoding returned by Pack, and Tuple the tuple returned
+	// by Unpack.
+	Packed []byte
+	Tuple  tuple.Tuple
+
+	Err error
+}
+
+func (f Failure) Error() string {
+	switch {
+	case f.Err != nil:
+		return fmt.Sprintf("%s %s: %v", f.Op, f.Vector.Name, f.Err)
+	case f.Op == "pack":
+		return fmt.Sprintf("pack %s: got %x, expected %x", f.Vector.Name, f.Packed, f.Vector.Packed)
+	}
+	return fmt.Sprintf("unpack %s: got %#v, expected %#v", f.Vector.Name, f.Tuple, f.Vector.Tuple)
+}
+
+// Verify packs the tuple of every vector with the codec, comparing the
+// result to the expected encoding, and unpacks the expected encoding,
+// comparing the result to the tuple (see tuple.Tuple.Equal). It returns the
+// failures, or an error if the corpus cannot be read. Verify(tuple.Default)
+// returns no failures.
+func Verify(c tuple.Codec) ([]Failure, error) {
+	vs, err := Vectors()
+	if err != nil {
+		return nil, err
+	}
+
+	var fs []Failure
+	for _, v := range vs {
+		b, err := c.Pack(v.Tuple)
+		if err != nil || string(b) != string(v.Packed) {
+			fs = append(fs, Failure{Vector: v, Op: "pack", Packed: b, Err: err})
+		}
+		t, err := c.Unpack(v.Packed)
+		if err != nil || !t.Equal(v.Tuple) {
+			fs = append(fs, Failure{Vector: v, Op: "unpack", Tuple: t, Err: err})
+		}
+	}
+	return fs, nil
+}
@@ -0,0 +1,17 @@
+package golden
+
+import (
+	"testing"
+
+	"github.com/abdullin/lex-go/tuple"
+)
+
+func TestVerify(t *testing.T) {
+	fs, err := Verify(tuple.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range fs {
+		t.Error(f)
+	}
+}
@@ -0,0 +1,76 @@
+package tuple
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/abdullin/lex-go"
+)
+
+// ipCode is the user typecode of net.IP elements. Following the
+// length-prefix convention for user typecodes (see UnpackLenient), it is
+// followed by the length of the address.
+//
+// net.IP elements are packed in their 16-byte form, IPv4 addresses being
+// mapped to ::ffff:a.b.c.d, so that packed addresses sort numerically and
+// IPv4 addresses sort together. Unpack returns 16-byte addresses. Pack will
+// panic if an address is neither 4 nor 16 bytes long.
+const ipCode = 0x42
+
+func init() {
+	RegisterType(Type{
+		Name:   "ip",
+		Code:   ipCode,
+		Value:  net.IP{},
+		Encode: func(el Element) []byte { return encodeIP(el.(net.IP)) },
+		Decode: func(b []byte) (Element, int, error) { return decodeIP(b) },
+		Size:   fixed(1 + net.IPv6len),
+	})
+}
+
+func encodeIP(ip net.IP) []byte {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		panic(fmt.Sprintf("invalid IP address %v", []byte(ip)))
+	}
+	return append([]byte{ipCode, net.IPv6len}, ip16...)
+}
+
+func decodeIP(b []byte) (net.IP, int, error) {
+	if len(b) < 2+net.IPv6len || b[1] != net.IPv6len {
+		return nil, 0, errTruncated
+	}
+	return append(net.IP{}, b[2:2+net.IPv6len]...), 2 + net.IPv6len, nil
+}
+
+// CIDRRange returns the range of keys which start with the elements of prefix
+// followed by an address of the network n.
+func CIDRRange(prefix Tuple, n *net.IPNet) (lex.KeyRange, error) {
+	lo := n.IP.Mask(n.Mask)
+	if lo == nil {
+		return lex.KeyRange{}, fmt.Errorf("invalid network %v", n)
+	}
+	mask := n.Mask
+	if len(mask) == net.IPv6len && len(lo) == net.IPv4len {
+		mask = mask[12:]
+	}
+	hi := append(net.IP{}, lo...)
+	for i := range hi {
+		hi[i] |= ^mask[i]
+	}
+	return lex.KeyRange{
+		Begin: lex.Key(append(append(Tuple{}, prefix...), lo).Pack()),
+		End:   lex.Key(append(append(append(Tuple{}, prefix...), hi).Pack(), 0xFF)),
+	}, nil
+}
+
+// ParseCIDRRange returns the range of keys which start with the elements of
+// prefix followed by an address of the network in CIDR notation s, such as
+// 192.0.2.0/24 or 2001:db8::/32.
+func ParseCIDRRange(prefix Tuple, s string) (lex.KeyRange, error) {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return lex.KeyRange{}, err
+	}
+	return CIDRRange(prefix, n)
+}
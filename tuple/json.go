@@ -0,0 +1,142 @@
+package tuple
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/abdullin/lex-go"
+)
+
+// jsonElement is the JSON representation of a non-nil element. Exactly one
+// of Bytes, String, Int, Raw and Packed is set.
+type jsonElement struct {
+	Bytes  *[]byte `json:"bytes,omitempty"`
+	String *string `json:"string,omitempty"`
+	Int    string  `json:"int,omitempty"`
+	Raw    *[]byte `json:"raw,omitempty"`
+
+	// Type and Packed represent the elements of registered types and opaque
+	// elements: Type names the type, and Packed holds the encoding of the
+	// element. Value is the element formatted with %v, for readability; it
+	// is ignored by FromJSON.
+	Type   string `json:"type,omitempty"`
+	Packed []byte `json:"packed,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+// ToJSON returns a JSON array representing the tuple, from which FromJSON
+// decodes an Equal tuple. Every element is typed: nil elements are null, and
+// others are objects with a single key naming their type:
+//
+//	{"bytes": "<base64>"}
+//	{"string": "text"}
+//	{"int": "-42"}
+//	{"raw": "<base64>"}
+//
+// Integers are held in strings, so that JSON decoders representing numbers
+// as floating point do not lose precision. Elements of registered types (and
+// opaque elements) are represented by their encoding, in base64, as in
+// {"type": "money", "packed": "<base64>", "value": "{EUR 100}"}.
+//
+// ToJSON returns an error in the same circumstances as Pack panics.
+func ToJSON(t Tuple) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	els := make([]*jsonElement, len(t))
+	for i, el := range t {
+		switch el := el.(type) {
+		case nil:
+		case int64, uint32, uint64, int, byte:
+			n, _ := toInt64(el)
+			els[i] = &jsonElement{Int: strconv.FormatInt(n, 10)}
+		case []byte:
+			b := append([]byte{}, el...)
+			els[i] = &jsonElement{Bytes: &b}
+		case string:
+			els[i] = &jsonElement{String: &el}
+		case RawSuffix:
+			if i != len(t)-1 {
+				panic(fmt.Sprintf("raw suffix at index %d is not the final element", i))
+			}
+			b := append([]byte{}, el...)
+			els[i] = &jsonElement{Raw: &b}
+		case OpaqueElement:
+			name := fmt.Sprintf("%02x", el.Code())
+			if rt, ok := typeOfCode(el.Code()); ok {
+				name = rt.Name
+			}
+			els[i] = &jsonElement{Type: name, Packed: el}
+		case lex.KeyConvertible:
+			b := append([]byte{}, el.LexKey()...)
+			els[i] = &jsonElement{Bytes: &b}
+		default:
+			rt, ok := typeOfElement(el)
+			if !ok {
+				panic(fmt.Sprintf("unencodable element at index %d (%v, type %T)", i, el, el))
+			}
+			els[i] = &jsonElement{Type: rt.Name, Packed: rt.Encode(el), Value: fmt.Sprintf("%v", el)}
+		}
+	}
+	return json.Marshal(els)
+}
+
+// FromJSON decodes a tuple represented as returned by ToJSON. Integers are
+// decoded as int64, and elements of registered types with their Decode
+// function; elements of unknown types are decoded as OpaqueElement.
+func FromJSON(b []byte) (Tuple, error) {
+	var els []*jsonElement
+	if err := json.Unmarshal(b, &els); err != nil {
+		return nil, err
+	}
+
+	t := make(Tuple, len(els))
+	for i, el := range els {
+		var err error
+		if t[i], err = el.decode(); err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+		if _, ok := t[i].(RawSuffix); ok && i != len(els)-1 {
+			return nil, fmt.Errorf("element %d: raw suffix is not the final element", i)
+		}
+	}
+	return t, nil
+}
+
+func (j *jsonElement) decode() (Element, error) {
+	switch {
+	case j == nil:
+		return nil, nil
+	case j.Bytes != nil:
+		return *j.Bytes, nil
+	case j.String != nil:
+		return *j.String, nil
+	case j.Int != "":
+		return strconv.ParseInt(j.Int, 10, 64)
+	case j.Raw != nil:
+		return RawSuffix(*j.Raw), nil
+	case len(j.Packed) > 0:
+		c := j.Packed[0]
+		if builtinCode(c) {
+			return nil, fmt.Errorf("packed element has builtin typecode %02x", c)
+		}
+		rt, ok := typeOfCode(c)
+		if !ok || rt.Decode == nil {
+			return OpaqueElement(j.Packed), nil
+		}
+		el, n, err := rt.Decode(j.Packed)
+		if err != nil {
+			return nil, err
+		}
+		if n != len(j.Packed) {
+			return nil, errors.New("packed element has trailing bytes")
+		}
+		return el, nil
+	}
+	return nil, errors.New("element has no type")
+}
@@ -0,0 +1,176 @@
+package tuple
+
+import (
+	"fmt"
+)
+
+// OpaqueElement is an element which UnpackLenient was able to skip but not to
+// decode. It holds the complete encoding of the element, including its
+// typecode, so packing a Tuple containing an OpaqueElement reproduces the
+// original bytes.
+type OpaqueElement []byte
+
+// Code returns the typecode of the opaque element.
+func (o OpaqueElement) Code() byte {
+	return o[0]
+}
+
+// SizeFunc returns the total length (including the typecode) of the encoded
+// element at the start of b, or an error if b does not hold a complete
+// element.
+type SizeFunc func(b []byte) (int, error)
+
+// RegisterSize registers the SizeFunc used by UnpackLenient to skip elements
+// with the given typecode. It lets readers skip custom element types whose
+// encoding is not self-describing. It is a shorthand for registering, with
+// RegisterType, a type which can be skipped but not decoded, and panics
+// under the same conditions.
+func RegisterSize(code byte, fn SizeFunc) {
+	RegisterType(Type{Name: fmt.Sprintf("%02x", code), Code: code, Size: fn})
+}
+
+// fixedSizes lists the lengths of the element types defined by the
+// FoundationDB tuple specification which have a fixed width (excluding the
+// typecode).
+var fixedSizes = map[byte]int{
+	0x20: 4,  // float
+	0x21: 8,  // double
+	0x22: 10, // long double
+	0x26: 0,  // false
+	0x27: 0,  // true
+	0x30: 16, // UUID
+	0x31: 8,  // 64-bit identifier
+	0x32: 10, // 80-bit versionstamp
+	0x33: 12, // 96-bit versionstamp
+}
+
+func fixed(n int) SizeFunc {
+	return func(b []byte) (int, error) {
+		if len(b) < n+1 {
+			return 0, errTruncated
+		}
+		return n + 1, nil
+	}
+}
+
+// opaqueSize returns the length of an element this package cannot decode, if
+// it can be determined: from the registered types, from the lengths of
+// element types defined by the FoundationDB tuple specification, or from the
+// length-prefix convention for user typecodes (0x40 to 0x4F), where the
+// typecode is followed by a single byte holding the length of the data.
+func opaqueSize(b []byte) (int, error) {
+	var fn SizeFunc
+	t, registered := typeOfCode(b[0])
+	if registered {
+		fn = t.size()
+		registered = fn != nil
+	}
+
+	n, isFixed := fixedSizes[b[0]]
+
+	switch {
+	case registered:
+	case isFixed:
+		fn = fixed(n)
+	case b[0] == 0x0b || b[0] == 0x1d:
+		// Arbitrary-precision integers are followed by their length, which is
+		// inverted for negative integers.
+		fn = func(b []byte) (int, error) {
+			if len(b) < 2 {
+				return 0, errTruncated
+			}
+			n := int(b[1])
+			if b[0] == 0x0b {
+				n = int(^b[1])
+			}
+			return fixed(n + 1)(b)
+		}
+	case b[0] == 0x05:
+		fn = nestedSize
+	case 0x40 <= b[0] && b[0] <= 0x4f:
+		fn = func(b []byte) (int, error) {
+			if len(b) < 2 {
+				return 0, errTruncated
+			}
+			return fixed(int(b[1]) + 1)(b)
+		}
+	default:
+		return 0, fmt.Errorf("unable to skip tuple element with unknown typecode %02x", b[0])
+	}
+
+	n, err := fn(b)
+	if err == nil && (n < 1 || n > len(b)) {
+		err = fmt.Errorf("invalid size %d for element with typecode %02x", n, b[0])
+	}
+	return n, err
+}
+
+// nestedSize returns the length of a nested tuple, which ends with a 0x00
+// byte not followed by 0xFF (a null element within a nested tuple is encoded
+// as 0x00 0xFF).
+func nestedSize(b []byte) (int, error) {
+	for i := 1; i < len(b); {
+		if b[i] == 0x00 {
+			if i+1 < len(b) && b[i+1] == 0xFF {
+				i += 2
+				continue
+			}
+			return i + 1, nil
+		}
+		n, err := lenientSize(b[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return 0, errTruncated
+}
+
+func lenientSize(b []byte) (int, error) {
+	if n, err := elementLen(b); err == nil || err == errTruncated {
+		return n, err
+	}
+	return opaqueSize(b)
+}
+
+// decodable returns true if elements with the typecode c have a decoder, so
+// that failing to decode them means they are malformed, rather than of a type
+// this package does not know.
+func decodable(c byte) bool {
+	if builtinCode(c) {
+		return true
+	}
+	t, ok := typeOfCode(c)
+	return ok && t.Decode != nil
+}
+
+// UnpackLenient is like Unpack, but returns an OpaqueElement for each element
+// whose typecode this package cannot decode, as long as its length can be
+// determined. This lets older readers skip past fields added by newer
+// writers. The length of an unknown element is found, in order, with the
+// SizeFunc of a registered type, from the element types of the
+// FoundationDB tuple specification, or by assuming that user typecodes (0x40
+// to 0x4F) are followed by a single length byte. Malformed elements of types
+// this package can decode return the error of their decoder.
+func UnpackLenient(b []byte) (Tuple, error) {
+	var t Tuple
+
+	for i := 0; i < len(b); {
+		el, off, err := decodeElement(b[i:])
+		if err != nil {
+			if decodable(b[i]) {
+				return nil, err
+			}
+			n, serr := opaqueSize(b[i:])
+			if serr != nil {
+				return nil, serr
+			}
+			el, off = OpaqueElement(b[i:i+n]), n
+		}
+
+		t = append(t, el)
+		i += off
+	}
+
+	return t, nil
+}
@@ -0,0 +1,95 @@
+package tuple
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/abdullin/lex-go"
+)
+
+// moneyCode is the user typecode of Money elements. Following the
+// length-prefix convention for user typecodes (see UnpackLenient), it is
+// followed by the length of the rest of the encoding.
+const moneyCode = 0x40
+
+const moneySize = 3 + 8
+
+// Money is an Element holding an amount of money as a signed number of minor
+// units (cents for USD) of a currency identified by its three-letter ISO 4217
+// code. Packed Money elements sort by currency, then numerically by amount,
+// so per-currency ranges of amounts can be scanned in order. Pack will panic
+// if the currency is not exactly three bytes long.
+type Money struct {
+	Currency string
+	Minor    int64
+}
+
+func init() {
+	RegisterType(Type{
+		Name:   "money",
+		Code:   moneyCode,
+		Value:  Money{},
+		Encode: func(el Element) []byte { return encodeMoney(el.(Money)) },
+		Decode: func(b []byte) (Element, int, error) { return decodeMoney(b) },
+		Size:   fixed(1 + moneySize),
+	})
+}
+
+func encodeMoney(m Money) []byte {
+	if len(m.Currency) != 3 {
+		panic(fmt.Sprintf("invalid currency code %q", m.Currency))
+	}
+	b := make([]byte, 2+moneySize)
+	b[0], b[1] = moneyCode, moneySize
+	copy(b[2:], m.Currency)
+	// Flipping the sign bit makes negative amounts sort before positive ones.
+	binary.BigEndian.PutUint64(b[5:], uint64(m.Minor)^(1<<63))
+	return b
+}
+
+func decodeMoney(b []byte) (Money, int, error) {
+	if len(b) < 2+moneySize || b[1] != moneySize {
+		return Money{}, 0, errTruncated
+	}
+	return Money{string(b[2:5]), int64(binary.BigEndian.Uint64(b[5:13]) ^ (1 << 63))}, 2 + moneySize, nil
+}
+
+func moneyKey(prefix Tuple, m Money) []byte {
+	return append(append(Tuple{}, prefix...), m).Pack()
+}
+
+// MoneyRange returns the range of keys which start with the elements of
+// prefix followed by any amount in the given currency.
+func MoneyRange(prefix Tuple, currency string) lex.KeyRange {
+	return MoneyBetween(prefix, Money{currency, math.MinInt64}, Money{currency, math.MaxInt64})
+}
+
+// MoneyOver returns the range of keys which start with the elements of prefix
+// followed by an amount in the currency of m strictly greater than m.
+func MoneyOver(prefix Tuple, m Money) lex.KeyRange {
+	if m.Minor == math.MaxInt64 {
+		k := lex.Key(moneyKey(prefix, m))
+		return lex.KeyRange{Begin: k, End: k}
+	}
+	return MoneyBetween(prefix, Money{m.Currency, m.Minor + 1}, Money{m.Currency, math.MaxInt64})
+}
+
+// MoneyUnder returns the range of keys which start with the elements of
+// prefix followed by an amount in the currency of m strictly less than m.
+func MoneyUnder(prefix Tuple, m Money) lex.KeyRange {
+	return lex.KeyRange{
+		Begin: lex.Key(moneyKey(prefix, Money{m.Currency, math.MinInt64})),
+		End:   lex.Key(moneyKey(prefix, m)),
+	}
+}
+
+// MoneyBetween returns the range of keys which start with the elements of
+// prefix followed by an amount between lo and hi inclusive, which must be in
+// the same currency.
+func MoneyBetween(prefix Tuple, lo, hi Money) lex.KeyRange {
+	return lex.KeyRange{
+		Begin: lex.Key(moneyKey(prefix, lo)),
+		End:   lex.Key(append(moneyKey(prefix, hi), 0xFF)),
+	}
+}
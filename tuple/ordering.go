@@ -0,0 +1,222 @@
+package tuple
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+)
+
+// typeOrder lists the element types supported by this package in the order
+// defined by the published FoundationDB tuple specification (that is, by
+// ascending typecode). Representative values for each type are listed in
+// ascending order within the type.
+var typeOrder = []struct {
+	name    string
+	samples []Element
+}{
+	{"nil", []Element{nil}},
+	{"bytes", []Element{
+		[]byte{},
+		[]byte{0x00},
+		[]byte{0x00, 0x00},
+		[]byte{0x00, 0xFF},
+		[]byte{0x01},
+		[]byte("a"),
+		[]byte{0xFF},
+		[]byte{0xFF, 0xFF},
+	}},
+	{"string", []Element{
+		"",
+		"\x00",
+		"\x00\xFF",
+		"A",
+		"a",
+		"aa",
+		"b",
+		"é",
+		"\U0001F600",
+	}},
+	{"int", []Element{
+		int64(math.MinInt64 + 1),
+		int64(-1 << 32),
+		int64(-65536),
+		int64(-256),
+		int64(-255),
+		int64(-1),
+		int64(0),
+		int64(1),
+		int64(255),
+		int64(256),
+		int64(65536),
+		int64(1 << 32),
+		int64(math.MaxInt64),
+	}},
+	{"uuid", []Element{
+		UUID{},
+		UUID{0x01, 0x7f, 0x22, 0xe2, 0x79, 0xb0, 0x7c, 0xc3, 0x98, 0xc4},
+		UUID{0x01, 0x7f, 0x22, 0xe2, 0x79, 0xb1, 0x70, 0x00, 0x80},
+		UUID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}},
+	{"money", []Element{
+		Money{"EUR", math.MinInt64},
+		Money{"EUR", -100},
+		Money{"EUR", 0},
+		Money{"EUR", 1},
+		Money{"EUR", math.MaxInt64},
+		Money{"USD", -1},
+		Money{"USD", 100},
+	}},
+	{"ulid", []Element{
+		ULID{},
+		ULID{0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		ULID{0x01, 0x8f, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff},
+		ULID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}},
+	{"ip", []Element{
+		net.IPv6zero,
+		net.ParseIP("::1"),
+		net.ParseIP("0.0.0.0"),
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("255.255.255.255"),
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+	}},
+	{"decimal", []Element{
+		NewDecimal(-1000, 0),
+		NewDecimal(-123, 2),
+		NewDecimal(-12, 1),
+		NewDecimal(-1000, 3),
+		NewDecimal(-1, 9),
+		Decimal{},
+		NewDecimal(0, 2),
+		NewDecimal(1, 9),
+		NewDecimal(15, 1),
+		NewDecimal(150, 2),
+		NewDecimal(151, 2),
+		NewDecimal(2, 0),
+		NewDecimal(1, -3),
+	}},
+	{"prefixed bytes", []Element{
+		PrefixedBytes{},
+		PrefixedBytes{0x00},
+		PrefixedBytes{0xFF},
+		PrefixedBytes{0x00, 0x00},
+		PrefixedBytes{0x00, 0xFF},
+		PrefixedBytes{0xFF, 0xFF},
+		PrefixedBytes(make([]byte, 256)),
+	}},
+	{"bitmap", []Element{
+		Bitmap(0),
+		NewBitmap(false, false, true),
+		NewBitmap(false, true),
+		NewBitmap(true),
+		NewBitmap(true, true),
+		Bitmap(math.MaxUint64),
+	}},
+}
+
+// OrderingSample is a representative value used by AuditOrdering, together
+// with its packed encoding.
+type OrderingSample struct {
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	Packed string `json:"packed"`
+}
+
+// OrderingPair is one cell of the cross-type ordering matrix: it records how
+// every sample of type A compares to every sample of type B once packed.
+type OrderingPair struct {
+	A        string `json:"a"`
+	B        string `json:"b"`
+	Expected int    `json:"expected"`
+	OK       bool   `json:"ok"`
+}
+
+// OrderingViolation describes two samples whose packed encodings do not
+// compare as required by the tuple ordering.
+type OrderingViolation struct {
+	Left     OrderingSample `json:"left"`
+	Right    OrderingSample `json:"right"`
+	Expected int            `json:"expected"`
+	Actual   int            `json:"actual"`
+}
+
+// OrderingReport is the machine-readable result of AuditOrdering. It is
+// designed to be serialized with encoding/json.
+type OrderingReport struct {
+	Types      []string            `json:"types"`
+	Samples    []OrderingSample    `json:"samples"`
+	Matrix     []OrderingPair      `json:"matrix"`
+	Violations []OrderingViolation `json:"violations"`
+}
+
+// OK returns true if the audit found no ordering violations.
+func (r OrderingReport) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// AuditOrdering packs representative values of every supported element type
+// and verifies that the byte order of the packed tuples matches the published
+// FoundationDB tuple ordering: first by type, then by value within a type.
+// Every pair of samples is compared, both within and across types.
+func AuditOrdering() OrderingReport {
+	r := OrderingReport{Violations: []OrderingViolation{}}
+
+	type sample struct {
+		rank int
+		pos  int
+		desc OrderingSample
+		b    []byte
+	}
+
+	var all []sample
+	for rank, t := range typeOrder {
+		r.Types = append(r.Types, t.name)
+		for _, e := range t.samples {
+			b := Tuple{e}.Pack()
+			s := sample{rank, len(all), OrderingSample{t.name, fmt.Sprintf("%#v", e), hex.EncodeToString(b)}, b}
+			all = append(all, s)
+			r.Samples = append(r.Samples, s.desc)
+		}
+	}
+
+	ok := make([][]bool, len(typeOrder))
+	for i := range ok {
+		ok[i] = make([]bool, len(typeOrder))
+		for j := range ok[i] {
+			ok[i][j] = true
+		}
+	}
+
+	for _, a := range all {
+		for _, b := range all {
+			expected := sign(a.pos - b.pos)
+			actual := bytes.Compare(a.b, b.b)
+			if expected != actual {
+				ok[a.rank][b.rank] = false
+				r.Violations = append(r.Violations, OrderingViolation{a.desc, b.desc, expected, actual})
+			}
+		}
+	}
+
+	for i, a := range typeOrder {
+		for j, b := range typeOrder {
+			r.Matrix = append(r.Matrix, OrderingPair{a.name, b.name, sign(i - j), ok[i][j]})
+		}
+	}
+
+	return r
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
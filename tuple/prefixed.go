@@ -0,0 +1,86 @@
+package tuple
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// prefixedBytesCode is the user typecode of PrefixedBytes elements. Like
+// decimalCode, it is not followed by a single byte holding the length, which
+// would limit elements to 255 bytes: it is followed by the number of bytes of
+// the length, the length itself in big-endian order, and the bytes of the
+// element.
+const prefixedBytesCode = 0x44
+
+// PrefixedBytes is an Element holding bytes encoded with their length rather
+// than with the escaping of []byte elements, which doubles the size of every
+// 0x00 byte. Wrapping a []byte in a PrefixedBytes selects this encoding for
+// elements with many 0x00 bytes, such as hashes and compressed blobs, whose
+// packed size is then their length plus at most 10 bytes.
+//
+// The length precedes the bytes, so packed PrefixedBytes elements sort by
+// length first, and by their bytes among elements of the same length: the
+// order of []byte elements is preserved for values of a fixed length, such as
+// hashes, but not across lengths. Unpack returns PrefixedBytes elements.
+type PrefixedBytes []byte
+
+func init() {
+	RegisterType(Type{
+		Name:   "prefixed bytes",
+		Code:   prefixedBytesCode,
+		Value:  PrefixedBytes{},
+		Encode: func(el Element) []byte { return encodePrefixedBytes(el.(PrefixedBytes)) },
+		Decode: func(b []byte) (Element, int, error) { return decodePrefixedBytes(b) },
+		Size:   prefixedBytesSize,
+	})
+}
+
+func encodePrefixedBytes(p PrefixedBytes) []byte {
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(p)))
+	n := bisectLeft(uint64(len(p)))
+	b := make([]byte, 0, 2+n+len(p))
+	b = append(b, prefixedBytesCode, byte(n))
+	b = append(b, l[8-n:]...)
+	return append(b, p...)
+}
+
+// prefixedBytesLen returns the length of the encoding of the PrefixedBytes
+// element at the start of b, and the offset of its bytes.
+func prefixedBytesLen(b []byte) (int, int, error) {
+	if len(b) < 2 {
+		return 0, 0, errTruncated
+	}
+	n := int(b[1])
+	if n > 8 {
+		return 0, 0, fmt.Errorf("invalid length size %d of prefixed bytes", n)
+	}
+	if len(b) < 2+n {
+		return 0, 0, errTruncated
+	}
+	var l [8]byte
+	copy(l[8-n:], b[2:2+n])
+	size := binary.BigEndian.Uint64(l[:])
+	// Every length has a single encoding, on the fewest bytes holding it, so
+	// that equal elements have equal keys.
+	if n != bisectLeft(size) {
+		return 0, 0, fmt.Errorf("non-minimal length size %d of prefixed bytes", n)
+	}
+	if size > uint64(len(b)-2-n) {
+		return 0, 0, errTruncated
+	}
+	return 2 + n + int(size), 2 + n, nil
+}
+
+func prefixedBytesSize(b []byte) (int, error) {
+	n, _, err := prefixedBytesLen(b)
+	return n, err
+}
+
+func decodePrefixedBytes(b []byte) (PrefixedBytes, int, error) {
+	n, off, err := prefixedBytesLen(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append(PrefixedBytes{}, b[off:n]...), n, nil
+}
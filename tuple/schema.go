@@ -0,0 +1,260 @@
+package tuple
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abdullin/lex-go"
+)
+
+// Kind is the type of an element expected by a Schema.
+type Kind int
+
+// The kinds of the element types decoded by this package. KindAny matches
+// every element. The kinds of registered types are returned by TypeKind.
+const (
+	KindAny Kind = iota
+	KindNil
+	KindBytes
+	KindString
+	KindInt
+)
+
+// The kinds of the element types registered by this package.
+const (
+	KindUUID          = Kind(0x100 + uuidCode)
+	KindMoney         = Kind(0x100 + moneyCode)
+	KindULID          = Kind(0x100 + ulidCode)
+	KindDecimal       = Kind(0x100 + decimalCode)
+	KindIP            = Kind(0x100 + ipCode)
+	KindPrefixedBytes = Kind(0x100 + prefixedBytesCode)
+	KindBitmap        = Kind(0x100 + bitmapCode)
+)
+
+// TypeKind returns the kind of the elements of the type registered with the
+// given typecode.
+func TypeKind(code byte) Kind {
+	return Kind(0x100 + int(code))
+}
+
+func (k Kind) String() string {
+	switch k {
+	case KindAny:
+		return "any"
+	case KindNil:
+		return "nil"
+	case KindBytes:
+		return "bytes"
+	case KindString:
+		return "string"
+	case KindInt:
+		return "int"
+	}
+	if k >= 0x100 && k <= 0x1FF {
+		if t, ok := typeOfCode(byte(k - 0x100)); ok {
+			return t.Name
+		}
+		return fmt.Sprintf("type %02x", int(k-0x100))
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// kindOfCode returns the kind of the elements starting with a typecode.
+func kindOfCode(c byte) Kind {
+	switch {
+	case c == 0x00:
+		return KindNil
+	case c == 0x01:
+		return KindBytes
+	case c == 0x02:
+		return KindString
+	case 0x0c <= c && c <= 0x1c:
+		return KindInt
+	}
+	return TypeKind(c)
+}
+
+// kindOfElement returns the kind of an element, and false for elements which
+// only KindAny matches (such as a RawSuffix).
+func kindOfElement(el Element) (Kind, bool) {
+	switch el := el.(type) {
+	case nil:
+		return KindNil, true
+	case int64, uint32, uint64, int, byte:
+		return KindInt, true
+	case []byte, lex.KeyConvertible:
+		return KindBytes, true
+	case string:
+		return KindString, true
+	case OpaqueElement:
+		return kindOfCode(el.Code()), true
+	case RawSuffix:
+		return KindAny, false
+	}
+	if t, ok := typeOfElement(el); ok {
+		return TypeKind(t.Code), true
+	}
+	return KindAny, false
+}
+
+// Field is an element expected by a Schema.
+type Field struct {
+	Name string
+	Kind Kind
+
+	// Optional indicates that the element may be missing from the end of a
+	// tuple. Optional fields must follow every required field.
+	Optional bool
+}
+
+// Schema describes the elements of a family of tuples: a sequence of
+// expected elements, ending with optional elements or with an element which
+// may repeat.
+type Schema struct {
+	Fields []Field
+
+	// Variadic indicates that the last field matches any number of elements,
+	// including none.
+	Variadic bool
+}
+
+// SchemaError is the error returned when a tuple does not match a Schema.
+type SchemaError struct {
+	// Index is the index of the offending element, or the length of the
+	// tuple if required elements are missing.
+	Index int
+
+	// Field is the name of the field expected at Index, if any.
+	Field string
+
+	Msg string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("element %d: %s", e.Index, e.Msg)
+	}
+	return fmt.Sprintf("element %d (%s): %s", e.Index, e.Field, e.Msg)
+}
+
+// Index returns the index of the named field, or -1 if the schema has no
+// such field.
+func (s Schema) Index(name string) int {
+	for i, f := range s.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate returns a *SchemaError if the tuple does not match the schema.
+func (s Schema) Validate(t Tuple) error {
+	return s.check(len(t), func(i int) (Kind, bool) {
+		return kindOfElement(t[i])
+	})
+}
+
+// ValidateKey returns an error if the packed tuple b does not match the
+// schema: a *SchemaError if its elements do not match, or the error of Spans
+// if b is not a well-formed tuple. The elements are not decoded.
+func (s Schema) ValidateKey(b []byte) error {
+	spans, err := Spans(b)
+	if err != nil {
+		return err
+	}
+	return s.check(len(spans), func(i int) (Kind, bool) {
+		return kindOfCode(b[spans[i].Begin]), true
+	})
+}
+
+func (s Schema) check(n int, kind func(i int) (Kind, bool)) error {
+	fields := s.Fields
+	if s.Variadic && len(fields) > 0 {
+		fields = fields[:len(fields)-1]
+	}
+	for i := n; i < len(fields); i++ {
+		if !fields[i].Optional {
+			return &SchemaError{n, fields[i].Name, "missing element"}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		var f Field
+		switch {
+		case i < len(s.Fields):
+			f = s.Fields[i]
+		case s.Variadic && len(s.Fields) > 0:
+			f = s.Fields[len(s.Fields)-1]
+		default:
+			k, _ := kind(i)
+			return &SchemaError{i, "", fmt.Sprintf("unexpected %s element, expected %d elements at most", k, len(s.Fields))}
+		}
+		if f.Kind == KindAny {
+			continue
+		}
+		if k, ok := kind(i); !ok || k != f.Kind {
+			got := k.String()
+			if !ok {
+				got = "unsupported"
+			}
+			return &SchemaError{i, f.Name, fmt.Sprintf("expected %s, got %s", f.Kind, got)}
+		}
+	}
+	return nil
+}
+
+// String returns the fields of the schema, such as (id int, name string?,
+// tags string...), where optional fields are marked with ? and a repeating
+// field with an ellipsis.
+func (s Schema) String() string {
+	parts := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		parts[i] = f.Name + " " + f.Kind.String()
+		switch {
+		case s.Variadic && i == len(s.Fields)-1:
+			parts[i] += "..."
+		case f.Optional:
+			parts[i] += "?"
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// DecodeToMap decodes the packed tuple b and returns its elements keyed by
+// the names of their fields, so that tools can show keys as named fields
+// rather than positional values. Missing optional elements are absent from
+// the map, the elements matched by a variadic field are gathered in a Tuple,
+// and the elements of fields without a name are keyed by their index.
+// DecodeToMap returns the error of ValidateKey if b does not match the
+// schema.
+func (s Schema) DecodeToMap(b []byte) (map[string]interface{}, error) {
+	if err := s.ValidateKey(b); err != nil {
+		return nil, err
+	}
+	t, err := Unpack(b)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{}, len(s.Fields))
+	for i, f := range s.Fields {
+		name := f.Name
+		if name == "" {
+			name = fmt.Sprint(i)
+		}
+		switch {
+		case s.Variadic && i == len(s.Fields)-1:
+			// Missing optional fields may leave the variadic field past the
+			// end of the tuple.
+			rest := Tuple{}
+			if i <= len(t) {
+				rest = append(rest, t[i:]...)
+			}
+			m[name] = rest
+		case i < len(t):
+			m[name] = t[i]
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,35 @@
+package tuple
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeToMapMissingOptional(t *testing.T) {
+	s := Schema{
+		Fields: []Field{
+			{"id", KindInt, true},
+			{"name", KindString, true},
+			{"tags", KindString, false},
+		},
+		Variadic: true,
+	}
+	tests := []struct {
+		key  Tuple
+		want map[string]interface{}
+	}{
+		{Tuple{}, map[string]interface{}{"tags": Tuple{}}},
+		{Tuple{int64(1)}, map[string]interface{}{"id": int64(1), "tags": Tuple{}}},
+		{Tuple{int64(1), "a"}, map[string]interface{}{"id": int64(1), "name": "a", "tags": Tuple{}}},
+		{Tuple{int64(1), "a", "x", "y"}, map[string]interface{}{"id": int64(1), "name": "a", "tags": Tuple{"x", "y"}}},
+	}
+	for _, tt := range tests {
+		got, err := s.DecodeToMap(tt.key.Pack())
+		if err != nil {
+			t.Fatalf("DecodeToMap(%v): %v", tt.key, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DecodeToMap(%v) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package tuple
+
+import "sort"
+
+// Slice attaches the methods of sort.Interface to []Tuple, sorting in the
+// order of the packed tuples (see Compare).
+type Slice []Tuple
+
+func (p Slice) Len() int           { return len(p) }
+func (p Slice) Less(i, j int) bool { return Compare(p[i], p[j]) < 0 }
+func (p Slice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// Sort sorts a slice of tuples in the order of their packed encodings, the
+// order in which keys packing them are stored. Sort will panic in the same
+// circumstances as Pack.
+func Sort(ts []Tuple) {
+	sort.Sort(Slice(ts))
+}
@@ -0,0 +1,168 @@
+package tuple
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Span is the half-open byte interval [Begin, End) occupied by the encoding of
+// a single element within a packed tuple.
+type Span struct {
+	Begin, End int
+}
+
+// errTruncated is returned when a packed tuple ends in the middle of an
+// element.
+var errTruncated = errors.New("packed tuple is truncated")
+
+// elementLen returns the length of the encoded element at the start of b,
+// without decoding it.
+func elementLen(b []byte) (int, error) {
+	switch {
+	case b[0] == 0x00:
+		return 1, nil
+	case b[0] == 0x01 || b[0] == 0x02:
+		for i := 1; i < len(b); i++ {
+			if b[i] != 0x00 {
+				continue
+			}
+			if i+1 < len(b) && b[i+1] == 0xFF {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+		return 0, errTruncated
+	case 0x0c <= b[0] && b[0] <= 0x1c:
+		n := int(b[0]) - 0x14
+		if n < 0 {
+			n = -n
+		}
+		if len(b) < n+1 {
+			return 0, errTruncated
+		}
+		return n + 1, nil
+	}
+	if t, ok := typeOfCode(b[0]); ok && t.Decode != nil {
+		return t.size()(b)
+	}
+	return 0, fmt.Errorf("unable to decode tuple element with unknown typecode %02x", b[0])
+}
+
+// Spans returns the byte spans of every element encoded in the packed tuple b.
+// The elements are not decoded, which makes Spans considerably cheaper than
+// Unpack when only the layout of a key is needed.
+func Spans(b []byte) ([]Span, error) {
+	var spans []Span
+
+	for i := 0; i < len(b); {
+		n, err := elementLen(b[i:])
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, Span{i, i + n})
+		i += n
+	}
+
+	return spans, nil
+}
+
+// UnpackElement decodes the single element occupying span s of the packed
+// tuple b.
+func UnpackElement(b []byte, s Span) (Element, error) {
+	if s.Begin < 0 || s.End > len(b) || s.Begin >= s.End {
+		return nil, fmt.Errorf("span [%d, %d) is out of bounds", s.Begin, s.End)
+	}
+	el, off, err := decodeElement(b[s.Begin:s.End])
+	if err != nil {
+		return nil, err
+	}
+	if off != s.End-s.Begin {
+		return nil, fmt.Errorf("span [%d, %d) does not hold exactly one element", s.Begin, s.End)
+	}
+	return el, nil
+}
+
+// Replace returns a new packed tuple in which the element at index i of the
+// packed tuple b is replaced by the encoding of el. An index equal to the
+// number of elements appends el. Replace will panic if el cannot be encoded.
+func Replace(b []byte, i int, el Element) ([]byte, error) {
+	spans, err := Spans(b)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i > len(spans) {
+		return nil, fmt.Errorf("element index %d out of range [0, %d]", i, len(spans))
+	}
+
+	s := Span{len(b), len(b)}
+	if i < len(spans) {
+		s = spans[i]
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(b))
+	buf.Write(b[:s.Begin])
+	buf.Write(Tuple{el}.Pack())
+	buf.Write(b[s.End:])
+	return buf.Bytes(), nil
+}
+
+// Mask returns a new packed tuple holding only the elements of the packed
+// tuple b at the indices listed in keep, in the order of keep. The elements
+// are copied without being decoded, which makes Mask suitable for deriving
+// aggregation keys from detail keys at high throughput.
+func Mask(b []byte, keep []int) ([]byte, error) {
+	spans, err := Spans(b)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for _, i := range keep {
+		if i < 0 || i >= len(spans) {
+			return nil, fmt.Errorf("element index %d out of range [0, %d)", i, len(spans))
+		}
+		n += spans[i].End - spans[i].Begin
+	}
+
+	r := make([]byte, 0, n)
+	for _, i := range keep {
+		r = append(r, b[spans[i].Begin:spans[i].End]...)
+	}
+	return r, nil
+}
+
+// Prefix returns the first n elements of the tuple, such as the tuple of a
+// parent key. The prefix shares the elements of t, but has its capacity
+// capped, so that appending to it does not overwrite the elements of t.
+// Prefix will panic if n is negative or greater than the length of t.
+func (t Tuple) Prefix(n int) Tuple {
+	return t[:n:n]
+}
+
+// PackedPrefixLen returns the length of the encoding of the first n elements
+// of the packed tuple b, so that b[:PackedPrefixLen(b, n)] is the packed
+// prefix of n elements, from which parent keys and prefix ranges can be
+// derived without decoding and re-encoding b.
+func PackedPrefixLen(b []byte, n int) (int, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("negative element count %d", n)
+	}
+
+	var i int
+
+	for k := 0; k < n; k++ {
+		if i >= len(b) {
+			return 0, fmt.Errorf("expected at least %d elements, found %d", n, k)
+		}
+		l, err := elementLen(b[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += l
+	}
+
+	return i, nil
+}
@@ -0,0 +1,117 @@
+package tuple
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/abdullin/lex-go"
+)
+
+// maxFrameSize is the length above which a StreamDecoder rejects a frame,
+// rather than allocating a buffer for it, as a corrupt length would make it
+// do.
+const maxFrameSize = 1 << 24
+
+// StreamDecoder reads a stream of length-delimited packed tuples, such as a
+// dump of keys or a replication feed, one tuple at a time, without loading
+// the stream in memory. Every packed tuple of the stream is preceded by its
+// length as an unsigned varint (see encoding/binary), as a StreamEncoder
+// writes it.
+type StreamDecoder struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewStreamDecoder returns a StreamDecoder reading from r.
+func NewStreamDecoder(r io.Reader) *StreamDecoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &StreamDecoder{r: br}
+}
+
+// Next returns the next packed tuple of the stream, without decoding it, or
+// io.EOF once the stream ends. The slice is only valid until the next call to
+// Next or Decode. A stream ending in the middle of a frame returns
+// io.ErrUnexpectedEOF.
+func (d *StreamDecoder) Next() ([]byte, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes is longer than %d bytes", n, maxFrameSize)
+	}
+	if uint64(cap(d.buf)) < n {
+		d.buf = make([]byte, n)
+	}
+	d.buf = d.buf[:n]
+	if _, err := io.ReadFull(d.r, d.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return d.buf, nil
+}
+
+// Decode returns the next tuple of the stream, or io.EOF once the stream
+// ends. The tuple does not alias the buffer of the decoder.
+func (d *StreamDecoder) Decode() (Tuple, error) {
+	b, err := d.Next()
+	if err != nil {
+		return nil, err
+	}
+	return Unpack(b)
+}
+
+// StreamEncoder writes length-delimited packed tuples, in the framing read by
+// StreamDecoder, such as to export a set of keys to a file or over the wire.
+// Writes are buffered: Flush must be called once every tuple was encoded.
+type StreamEncoder struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewStreamEncoder returns a StreamEncoder writing to w.
+func NewStreamEncoder(w io.Writer) *StreamEncoder {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	return &StreamEncoder{w: bw}
+}
+
+// Encode writes the packed tuple. Encode will panic in the same circumstances
+// as Pack.
+func (e *StreamEncoder) Encode(t Tuple) error {
+	e.buf = t.AppendPack(e.buf[:0])
+	return e.Write(e.buf)
+}
+
+// EncodeKey writes the key, such as a key holding a packed tuple after the
+// prefix of its subspace.
+func (e *StreamEncoder) EncodeKey(k lex.KeyConvertible) error {
+	return e.Write(k.LexKey())
+}
+
+// Write writes b, which should be a packed tuple or a key, as a frame.
+func (e *StreamEncoder) Write(b []byte) error {
+	if len(b) > maxFrameSize {
+		return fmt.Errorf("frame of %d bytes is longer than %d bytes", len(b), maxFrameSize)
+	}
+	var n [binary.MaxVarintLen64]byte
+	if _, err := e.w.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+// Flush writes the buffered frames to the underlying writer.
+func (e *StreamEncoder) Flush() error {
+	return e.w.Flush()
+}
@@ -16,6 +16,7 @@ import "github.com/abdullin/lex-go"
 import "encoding/binary"
 import "bytes"
 import "fmt"
+import "strings"
 
 // A Element is one of the types that may be encoded in FoundationDB
 // tuples. Although the Go compiler cannot enforce this, it is a programming
@@ -23,7 +24,8 @@ import "fmt"
 // result in a runtime panic).
 //
 // The valid types for Element are []byte (or lex.KeyConvertible), string,
-// int64 (or int), and nil.
+// int64 (or int), nil and the types registered with RegisterType (such as
+// Money). A RawSuffix may be used as the final element.
 type Element interface{}
 
 // Tuple is a slice of objects that can be encoded as FoundationDB tuples. If
@@ -35,6 +37,16 @@ type Element interface{}
 // packing T (modulo type normalization to []byte and int64).
 type Tuple []Element
 
+// RawSuffix is an Element whose bytes are appended to a packed tuple verbatim:
+// without a typecode, escaping or terminator. It exists for interoperability
+// with keyspaces whose last component is a raw byte blob that must match
+// byte-for-byte. A RawSuffix may only appear as the final element of a Tuple;
+// Pack will panic otherwise.
+//
+// Since a RawSuffix is not self-delimiting, Unpack cannot recover it; use
+// UnpackWithSuffix to decode tuples that end with one.
+type RawSuffix []byte
+
 var sizeLimits = []uint64{
 	1<<(0*8) - 1,
 	1<<(1*8) - 1,
@@ -49,7 +61,24 @@ var sizeLimits = []uint64{
 
 func encodeBytes(buf *bytes.Buffer, code byte, b []byte) {
 	buf.WriteByte(code)
-	buf.Write(bytes.Replace(b, []byte{0x00}, []byte{0x00, 0xFF}, -1))
+	for i := bytes.IndexByte(b, 0x00); i >= 0; i = bytes.IndexByte(b, 0x00) {
+		buf.Write(b[:i+1])
+		buf.WriteByte(0xFF)
+		b = b[i+1:]
+	}
+	buf.Write(b)
+	buf.WriteByte(0x00)
+}
+
+// encodeString is like encodeBytes for strings, without copying them.
+func encodeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(0x02)
+	for i := strings.IndexByte(s, 0x00); i >= 0; i = strings.IndexByte(s, 0x00) {
+		buf.WriteString(s[:i+1])
+		buf.WriteByte(0xFF)
+		s = s[i+1:]
+	}
+	buf.WriteString(s)
 	buf.WriteByte(0x00)
 }
 
@@ -68,32 +97,46 @@ func encodeInt(buf *bytes.Buffer, i int64) {
 	}
 
 	var n int
-	var ibuf bytes.Buffer
+	var ibuf [8]byte
 
 	switch {
 	case i > 0:
 		n = bisectLeft(uint64(i))
 		buf.WriteByte(byte(0x14 + n))
-		binary.Write(&ibuf, binary.BigEndian, i)
+		binary.BigEndian.PutUint64(ibuf[:], uint64(i))
 	case i < 0:
 		n = bisectLeft(uint64(-i))
 		buf.WriteByte(byte(0x14 - n))
-		binary.Write(&ibuf, binary.BigEndian, int64(sizeLimits[n])+i)
+		binary.BigEndian.PutUint64(ibuf[:], uint64(int64(sizeLimits[n])+i))
 	}
 
-	buf.Write(ibuf.Bytes()[8-n:])
+	buf.Write(ibuf[8-n:])
 }
 
 // Pack returns a new byte slice encoding the provided tuple. Pack will panic if
 // the tuple contains an element of any type other than []byte,
-// lex.KeyConvertible, string, int64, int or nil.
+// lex.KeyConvertible, string, int64, int, nil or a registered type (or a
+// RawSuffix anywhere but in the final position).
 //
 // Tuple satisfies the lex.KeyConvertible interface, so it is not necessary to
 // call Pack when using a Tuple with a FoundationDB API function that requires a
 // key.
 func (t Tuple) Pack() []byte {
 	buf := new(bytes.Buffer)
+	t.pack(buf)
+	return buf.Bytes()
+}
+
+// AppendPack appends the encoding of the tuple to dst and returns the
+// extended slice, growing dst only if its capacity is exceeded. AppendPack
+// will panic in the same circumstances as Pack.
+func (t Tuple) AppendPack(dst []byte) []byte {
+	buf := bytes.NewBuffer(dst)
+	t.pack(buf)
+	return buf.Bytes()
+}
 
+func (t Tuple) pack(buf *bytes.Buffer) {
 	for i, e := range t {
 		switch e := e.(type) {
 		case nil:
@@ -113,45 +156,51 @@ func (t Tuple) Pack() []byte {
 		case lex.KeyConvertible:
 			encodeBytes(buf, 0x01, []byte(e.LexKey()))
 		case string:
-			encodeBytes(buf, 0x02, []byte(e))
+			encodeString(buf, e)
+		case RawSuffix:
+			if i != len(t)-1 {
+				panic(fmt.Sprintf("raw suffix at index %d is not the final element", i))
+			}
+			buf.Write(e)
+		case OpaqueElement:
+			buf.Write(e)
 		default:
-			panic(fmt.Sprintf("unencodable element at index %d (%v, type %T)", i, t[i], t[i]))
+			rt, ok := typeOfElement(e)
+			if !ok {
+				panic(fmt.Sprintf("unencodable element at index %d (%v, type %T)", i, t[i], t[i]))
+			}
+			buf.Write(rt.Encode(e))
 		}
 	}
-
-	return buf.Bytes()
 }
 
-func findTerminator(b []byte) int {
-	bp := b
-	var length int
-
-	for {
-		idx := bytes.IndexByte(bp, 0x00)
-		length += idx
-		if idx+1 == len(bp) || bp[idx+1] != 0xFF {
-			break
-		}
-		length += 2
-		bp = bp[idx+2:]
+// PackChecked is like Pack, but returns a lex.ErrKeyTooLarge if the packed
+// tuple is longer than lex.MaxKeySize, the limit FoundationDB enforces on
+// keys, rather than letting the oversized key fail when it is written.
+func (t Tuple) PackChecked() ([]byte, error) {
+	b := t.Pack()
+	if err := lex.CheckKey(lex.Key(b)); err != nil {
+		return nil, err
 	}
-
-	return length
+	return b, nil
 }
 
-func decodeBytes(b []byte) ([]byte, int) {
-	idx := findTerminator(b[1:])
-	return bytes.Replace(b[1:idx+1], []byte{0x00, 0xFF}, []byte{0x00}, -1), idx + 2
+func decodeBytes(b []byte) ([]byte, int, error) {
+	n, err := elementLen(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.Replace(b[1:n-1], []byte{0x00, 0xFF}, []byte{0x00}, -1), n, nil
 }
 
-func decodeString(b []byte) (string, int) {
-	bp, idx := decodeBytes(b)
-	return string(bp), idx
+func decodeString(b []byte) (string, int, error) {
+	bp, n, err := decodeBytes(b)
+	return string(bp), n, err
 }
 
-func decodeInt(b []byte) (int64, int) {
+func decodeInt(b []byte) (int64, int, error) {
 	if b[0] == 0x14 {
-		return 0, 1
+		return 0, 1, nil
 	}
 
 	var neg bool
@@ -161,6 +210,9 @@ func decodeInt(b []byte) (int64, int) {
 		n = -n
 		neg = true
 	}
+	if len(b) < n+1 {
+		return 0, 0, errTruncated
+	}
 
 	bp := make([]byte, 8)
 	copy(bp[8-n:], b[1:n+1])
@@ -173,7 +225,27 @@ func decodeInt(b []byte) (int64, int) {
 		ret -= int64(sizeLimits[n])
 	}
 
-	return ret, n + 1
+	return ret, n + 1, nil
+}
+
+func decodeElement(b []byte) (Element, int, error) {
+	switch {
+	case b[0] == 0x00:
+		return nil, 1, nil
+	case b[0] == 0x01:
+		el, off, err := decodeBytes(b)
+		return el, off, err
+	case b[0] == 0x02:
+		el, off, err := decodeString(b)
+		return el, off, err
+	case 0x0c <= b[0] && b[0] <= 0x1c:
+		el, off, err := decodeInt(b)
+		return el, off, err
+	}
+	if t, ok := typeOfCode(b[0]); ok && t.Decode != nil {
+		return t.Decode(b)
+	}
+	return nil, 0, fmt.Errorf("unable to decode tuple element with unknown typecode %02x", b[0])
 }
 
 // Unpack returns the tuple encoded by the provided byte slice, or an error if
@@ -184,21 +256,9 @@ func Unpack(b []byte) (Tuple, error) {
 	var i int
 
 	for i < len(b) {
-		var el interface{}
-		var off int
-
-		switch {
-		case b[i] == 0x00:
-			el = nil
-			off = 1
-		case b[i] == 0x01:
-			el, off = decodeBytes(b[i:])
-		case b[i] == 0x02:
-			el, off = decodeString(b[i:])
-		case 0x0c <= b[i] && b[i] <= 0x1c:
-			el, off = decodeInt(b[i:])
-		default:
-			return nil, fmt.Errorf("unable to decode tuple element with unknown typecode %02x", b[i])
+		el, off, err := decodeElement(b[i:])
+		if err != nil {
+			return nil, err
 		}
 
 		t = append(t, el)
@@ -208,6 +268,54 @@ func Unpack(b []byte) (Tuple, error) {
 	return t, nil
 }
 
+// UnpackInto decodes the tuple encoded by the provided byte slice into t,
+// reusing its backing array, so that loops decoding many keys do not allocate
+// a new Tuple for each of them. On error, t holds the elements decoded before
+// the malformed one.
+func UnpackInto(b []byte, t *Tuple) error {
+	*t = (*t)[:0]
+
+	var i int
+
+	for i < len(b) {
+		el, off, err := decodeElement(b[i:])
+		if err != nil {
+			return err
+		}
+
+		*t = append(*t, el)
+		i += off
+	}
+
+	return nil
+}
+
+// UnpackWithSuffix decodes the first n elements of the tuple encoded by the
+// provided byte slice and returns them followed by a RawSuffix holding all
+// remaining bytes (which may be empty). It returns an error if fewer than n
+// elements are encoded or if any of them is malformed.
+func UnpackWithSuffix(b []byte, n int) (Tuple, error) {
+	t := make(Tuple, 0, n+1)
+
+	var i int
+
+	for len(t) < n {
+		if i >= len(b) {
+			return nil, fmt.Errorf("expected %d elements before raw suffix, found %d", n, len(t))
+		}
+
+		el, off, err := decodeElement(b[i:])
+		if err != nil {
+			return nil, err
+		}
+
+		t = append(t, el)
+		i += off
+	}
+
+	return append(t, RawSuffix(b[i:])), nil
+}
+
 // LexKey returns the packed representation of a Tuple, and allows Tuple to
 // satisfy the lex.KeyConvertible interface. LexKey will panic in the same
 // circumstances as Pack.
@@ -233,6 +341,13 @@ func (t Tuple) LexRangeKeySelectors() (lex.Selectable, lex.Selectable) {
 	return lex.FirstGreaterOrEqual(b), lex.FirstGreaterOrEqual(e)
 }
 
+// Hash64 returns the hash of the packed tuple (see lex.Key.Hash64). Tuples
+// which are Equal have the same hash. Hash64 will panic in the same
+// circumstances as Pack.
+func (t Tuple) Hash64() uint64 {
+	return lex.Key(t.Pack()).Hash64()
+}
+
 func concat(a []byte, b ...byte) []byte {
 	r := make([]byte, len(a)+len(b))
 	copy(r, a)
@@ -0,0 +1,125 @@
+package tuple
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Type describes an element type registered with RegisterType. Registering
+// types lets optional packages add element types to the tuple layer: such a
+// package registers its types from an init function, so that importing it
+// (even with a blank import) makes them available to Pack and Unpack.
+type Type struct {
+	// Name identifies the type in error messages.
+	Name string
+
+	// Code is the typecode starting the encoding of every element of the
+	// type.
+	Code byte
+
+	// Value is an element of the type. Pack encodes elements of the same Go
+	// type as Value with Encode. A nil Value registers a type which can only
+	// be decoded.
+	Value Element
+
+	// Encode returns the complete encoding of an element, including its
+	// typecode.
+	Encode func(el Element) []byte
+
+	// Decode decodes the element at the start of b, returning it and the
+	// length of its encoding. A nil Decode registers a type which Unpack
+	// cannot decode, but which UnpackLenient skips using Size.
+	Decode func(b []byte) (Element, int, error)
+
+	// Size returns the length of the encoding of the element at the start
+	// of b. A nil Size determines the length with Decode.
+	Size SizeFunc
+}
+
+// size returns the SizeFunc of the type.
+func (t *Type) size() SizeFunc {
+	if t.Size != nil || t.Decode == nil {
+		return t.Size
+	}
+	return func(b []byte) (int, error) {
+		_, n, err := t.Decode(b)
+		return n, err
+	}
+}
+
+var (
+	typesMu sync.RWMutex
+	byCode  = map[byte]*Type{}
+	byGo    = map[reflect.Type]*Type{}
+)
+
+// builtinCode returns true for the typecodes decoded by this package without
+// registration.
+func builtinCode(c byte) bool {
+	return c <= 0x02 || (0x0c <= c && c <= 0x1c)
+}
+
+// builtinGo lists the Go types encoded by Pack without registration.
+var builtinGo = map[reflect.Type]bool{
+	reflect.TypeOf(int64(0)):        true,
+	reflect.TypeOf(uint32(0)):       true,
+	reflect.TypeOf(uint64(0)):       true,
+	reflect.TypeOf(int(0)):          true,
+	reflect.TypeOf(byte(0)):         true,
+	reflect.TypeOf([]byte(nil)):     true,
+	reflect.TypeOf(""):              true,
+	reflect.TypeOf(RawSuffix(nil)):  true,
+	reflect.TypeOf(OpaqueElement{}): true,
+}
+
+// RegisterType registers an element type. RegisterType panics if the
+// typecode of the type is decoded by this package or registered already, or
+// if its Go type is encoded by this package or registered already.
+func RegisterType(t Type) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+
+	if builtinCode(t.Code) {
+		panic(fmt.Sprintf("typecode %02x of type %s is reserved", t.Code, t.Name))
+	}
+	if o, ok := byCode[t.Code]; ok {
+		panic(fmt.Sprintf("typecode %02x of type %s is already registered by type %s", t.Code, t.Name, o.Name))
+	}
+
+	var gt reflect.Type
+	if t.Value != nil {
+		if t.Encode == nil {
+			panic(fmt.Sprintf("type %s has a Value but no Encode", t.Name))
+		}
+		gt = reflect.TypeOf(t.Value)
+		if builtinGo[gt] {
+			panic(fmt.Sprintf("Go type %s of type %s is reserved", gt, t.Name))
+		}
+		if o, ok := byGo[gt]; ok {
+			panic(fmt.Sprintf("Go type %s of type %s is already registered by type %s", gt, t.Name, o.Name))
+		}
+	}
+
+	r := t
+	byCode[t.Code] = &r
+	if gt != nil {
+		byGo[gt] = &r
+	}
+}
+
+// typeOfCode returns the type registered for a typecode.
+func typeOfCode(c byte) (*Type, bool) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	t, ok := byCode[c]
+	return t, ok
+}
+
+// typeOfElement returns the type registered for the Go type of an element.
+func typeOfElement(el Element) (*Type, bool) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	t, ok := byGo[reflect.TypeOf(el)]
+	return t, ok
+}
@@ -0,0 +1,118 @@
+package tuple
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ulidCode is the user typecode of ULID elements. Following the
+// length-prefix convention for user typecodes (see UnpackLenient), it is
+// followed by the length of the ULID.
+const ulidCode = 0x41
+
+// ULID is an Element holding a Universally Unique Lexicographically Sortable
+// Identifier: a 48-bit timestamp in milliseconds followed by 80 random bits.
+// Packed ULIDs sort like their bytes, and so by time.
+type ULID [16]byte
+
+func init() {
+	RegisterType(Type{
+		Name:   "ulid",
+		Code:   ulidCode,
+		Value:  ULID{},
+		Encode: func(el Element) []byte { return encodeULID(el.(ULID)) },
+		Decode: func(b []byte) (Element, int, error) { return decodeULID(b) },
+		Size:   fixed(1 + len(ULID{})),
+	})
+}
+
+func encodeULID(u ULID) []byte {
+	return append([]byte{ulidCode, byte(len(u))}, u[:]...)
+}
+
+func decodeULID(b []byte) (ULID, int, error) {
+	var u ULID
+	if len(b) < 2+len(u) || int(b[1]) != len(u) {
+		return u, 0, errTruncated
+	}
+	copy(u[:], b[2:])
+	return u, 2 + len(u), nil
+}
+
+// NewULID returns a ULID holding the time t, truncated to milliseconds, and
+// 80 bits read from entropy (such as crypto/rand.Reader).
+func NewULID(t time.Time, entropy io.Reader) (ULID, error) {
+	var u ULID
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	if ms >= 1<<48 {
+		return u, fmt.Errorf("time %v does not fit in a ULID", t)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], ms)
+	copy(u[:6], b[2:])
+	if _, err := io.ReadFull(entropy, u[6:]); err != nil {
+		return u, err
+	}
+	return u, nil
+}
+
+// Time returns the time held by the ULID.
+func (u ULID) Time() time.Time {
+	var b [8]byte
+	copy(b[2:], u[:6])
+	ms := int64(binary.BigEndian.Uint64(b[:]))
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// String returns the canonical representation of the ULID: 26 characters of
+// Crockford's base32, which sort like the ULID.
+func (u ULID) String() string {
+	var s [26]byte
+	for i := range s {
+		var v byte
+		// The 128 bits are preceded by 2 zero bits to fill 26 characters.
+		for j := 0; j < 5; j++ {
+			bit := i*5 + j - 2
+			v <<= 1
+			if bit >= 0 && u[bit/8]&(0x80>>uint(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		s[i] = crockford[v]
+	}
+	return string(s[:])
+}
+
+// ParseULID parses the canonical representation of a ULID, in upper or lower
+// case.
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != 26 {
+		return u, fmt.Errorf("invalid ULID %q: expected 26 characters", s)
+	}
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(crockford, upper(s[i]))
+		if v < 0 || (i == 0 && v > 7) {
+			return u, fmt.Errorf("invalid ULID %q: invalid character at offset %d", s, i)
+		}
+		for j := 0; j < 5; j++ {
+			bit := i*5 + j - 2
+			if bit >= 0 && v&(0x10>>uint(j)) != 0 {
+				u[bit/8] |= 0x80 >> uint(bit%8)
+			}
+		}
+	}
+	return u, nil
+}
+
+func upper(c byte) byte {
+	if 'a' <= c && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
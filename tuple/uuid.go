@@ -0,0 +1,132 @@
+package tuple
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/abdullin/lex-go"
+)
+
+// uuidCode is the typecode of UUID elements defined by the FoundationDB tuple
+// specification. It is followed by the 16 bytes of the UUID.
+const uuidCode = 0x30
+
+// UUID is an Element holding a UUID, encoded as defined by the FoundationDB
+// tuple specification. Packed UUIDs sort like their bytes, so version 7 UUIDs
+// (see NewUUIDv7) sort by the time they hold.
+type UUID [16]byte
+
+func init() {
+	RegisterType(Type{
+		Name:   "uuid",
+		Code:   uuidCode,
+		Value:  UUID{},
+		Encode: func(el Element) []byte { return encodeUUID(el.(UUID)) },
+		Decode: func(b []byte) (Element, int, error) { return decodeUUID(b) },
+		Size:   fixed(len(UUID{})),
+	})
+}
+
+func encodeUUID(u UUID) []byte {
+	return append([]byte{uuidCode}, u[:]...)
+}
+
+func decodeUUID(b []byte) (UUID, int, error) {
+	var u UUID
+	if len(b) < 1+len(u) {
+		return u, 0, errTruncated
+	}
+	copy(u[:], b[1:])
+	return u, 1 + len(u), nil
+}
+
+// NewUUIDv7 returns a version 7 UUID holding the time t, truncated to
+// milliseconds, and 74 bits read from entropy (such as crypto/rand.Reader).
+func NewUUIDv7(t time.Time, entropy io.Reader) (UUID, error) {
+	u, err := uuidv7(t)
+	if err != nil {
+		return u, err
+	}
+	if _, err := io.ReadFull(entropy, u[6:]); err != nil {
+		return u, err
+	}
+	u[6] = 0x70 | u[6]&0x0f
+	u[8] = 0x80 | u[8]&0x3f
+	return u, nil
+}
+
+// uuidv7 returns the least version 7 UUID holding the time t.
+func uuidv7(t time.Time) (UUID, error) {
+	var u UUID
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	if ms >= 1<<48 {
+		return u, fmt.Errorf("time %v does not fit in a UUIDv7", t)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], ms)
+	copy(u[:6], b[2:])
+	u[6], u[8] = 0x70, 0x80
+	return u, nil
+}
+
+// Version returns the version of the UUID.
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// Time returns the time held by a version 7 UUID. The result is meaningless
+// for UUIDs of other versions.
+func (u UUID) Time() time.Time {
+	var b [8]byte
+	copy(b[2:], u[:6])
+	ms := int64(binary.BigEndian.Uint64(b[:]))
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+}
+
+// String returns the canonical representation of the UUID, such as
+// 017f22e2-79b0-7cc3-98c4-dc0c0c07398f.
+func (u UUID) String() string {
+	var s [36]byte
+	hex.Encode(s[0:8], u[0:4])
+	hex.Encode(s[9:13], u[4:6])
+	hex.Encode(s[14:18], u[6:8])
+	hex.Encode(s[19:23], u[8:10])
+	hex.Encode(s[24:], u[10:])
+	s[8], s[13], s[18], s[23] = '-', '-', '-', '-'
+	return string(s[:])
+}
+
+// ParseUUID parses the canonical representation of a UUID, in upper or lower
+// case.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	h := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %v", s, err)
+	}
+	return u, nil
+}
+
+// UUIDv7Range returns the range of keys which start with the elements of
+// prefix followed by a version 7 UUID holding a time from from included to to
+// excluded, both truncated to milliseconds.
+func UUIDv7Range(prefix Tuple, from, to time.Time) (lex.KeyRange, error) {
+	lo, err := uuidv7(from)
+	if err != nil {
+		return lex.KeyRange{}, err
+	}
+	hi, err := uuidv7(to)
+	if err != nil {
+		return lex.KeyRange{}, err
+	}
+	return lex.KeyRange{
+		Begin: lex.Key(append(append(Tuple{}, prefix...), lo).Pack()),
+		End:   lex.Key(append(append(Tuple{}, prefix...), hi).Pack()),
+	}, nil
+}
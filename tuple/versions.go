@@ -0,0 +1,226 @@
+package tuple
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Versions is a set of schemas for a family of tuples holding an int with the
+// version of their format, as their first element or following a fixed number
+// of leading elements (see NewVersionsAfter). The version selects the schema
+// of the remaining elements, so readers keep decoding the tuples written in
+// an older format while writers move to a newer one.
+type Versions struct {
+	mu      sync.RWMutex
+	schemas map[int64]Schema
+	latest  int64
+	leading Schema
+}
+
+// NewVersions returns an empty set of schemas for tuples starting with their
+// version.
+func NewVersions() *Versions {
+	return &Versions{schemas: map[int64]Schema{}}
+}
+
+// NewVersionsAfter returns an empty set of schemas for tuples whose version
+// follows the elements of the leading schema, such as the tenant or the
+// entity a key belongs to, which every version shares. The leading schema
+// must have a fixed number of elements: NewVersionsAfter panics if it has
+// optional or variadic fields.
+func NewVersionsAfter(leading Schema) *Versions {
+	if leading.Variadic {
+		panic("leading schema is variadic")
+	}
+	for _, f := range leading.Fields {
+		if f.Optional {
+			panic(fmt.Sprintf("leading field %s is optional", f.Name))
+		}
+	}
+	return &Versions{schemas: map[int64]Schema{}, leading: leading}
+}
+
+// Leading returns the schema of the elements preceding the version.
+func (v *Versions) Leading() Schema {
+	return v.leading
+}
+
+// Position returns the index of the version in the tuples.
+func (v *Versions) Position() int {
+	return len(v.leading.Fields)
+}
+
+// Register adds the schema of a version. It returns an error if the version
+// is registered already.
+func (v *Versions) Register(version int64, s Schema) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.schemas[version]; ok {
+		return fmt.Errorf("version %d is already registered", version)
+	}
+	if len(v.schemas) == 0 || version > v.latest {
+		v.latest = version
+	}
+	v.schemas[version] = s
+	return nil
+}
+
+// MustRegister is like Register but panics if the version cannot be
+// registered. It returns v, so that registrations can be chained.
+func (v *Versions) MustRegister(version int64, s Schema) *Versions {
+	if err := v.Register(version, s); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Latest returns the highest registered version and its schema, which is the
+// format new tuples are written in.
+func (v *Versions) Latest() (int64, Schema) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.latest, v.schemas[v.latest]
+}
+
+// Schema returns the schema of a version.
+func (v *Versions) Schema(version int64) (Schema, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	s, ok := v.schemas[version]
+	return s, ok
+}
+
+// List returns the registered versions in ascending order.
+func (v *Versions) List() []int64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	vs := make([]int64, 0, len(v.schemas))
+	for version := range v.schemas {
+		vs = append(vs, version)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i] < vs[j] })
+	return vs
+}
+
+// Tag returns the elements with the latest version inserted at its position.
+func (v *Versions) Tag(el ...Element) Tuple {
+	version, _ := v.Latest()
+	return v.TagVersion(version, el...)
+}
+
+// TagVersion returns the elements with the given version inserted at its
+// position, such as to write tuples in the format of an older version.
+func (v *Versions) TagVersion(version int64, el ...Element) Tuple {
+	pos := v.Position()
+	if pos > len(el) {
+		pos = len(el)
+	}
+	t := make(Tuple, 0, len(el)+1)
+	t = append(t, el[:pos]...)
+	t = append(t, version)
+	return append(t, el[pos:]...)
+}
+
+// Validate returns the version of the tuple, and a *SchemaError if its
+// leading elements do not match the leading schema, if its version is not a
+// registered version or if the following elements do not match the schema
+// of the version.
+func (v *Versions) Validate(t Tuple) (int64, error) {
+	pos := v.Position()
+	leading := t
+	if len(t) > pos {
+		leading = t[:pos]
+	}
+	if err := v.leading.Validate(leading); err != nil {
+		return 0, err
+	}
+	if len(t) == pos {
+		return 0, &SchemaError{pos, "version", "missing element"}
+	}
+	if k, _ := kindOfElement(t[pos]); k != KindInt {
+		return 0, &SchemaError{pos, "version", fmt.Sprintf("expected int, got %s", k)}
+	}
+	version, ok := toInt64(t[pos])
+	if !ok {
+		return 0, &SchemaError{pos, "version", fmt.Sprintf("invalid int %x", t[pos])}
+	}
+	s, err := v.lookup(version)
+	if err != nil {
+		return version, err
+	}
+	return version, shift(s.Validate(t[pos+1:]), pos+1)
+}
+
+// ValidateKey is like Validate, but validates the packed tuple b without
+// decoding the elements other than the version.
+func (v *Versions) ValidateKey(b []byte) (int64, error) {
+	pos := v.Position()
+	n, err := PackedPrefixLen(b, pos)
+	if err != nil {
+		// b is malformed or holds fewer elements than the leading schema,
+		// which ValidateKey reports like it does for complete tuples.
+		if verr := v.leading.ValidateKey(b); verr != nil {
+			return 0, verr
+		}
+		return 0, err
+	}
+	if err := v.leading.ValidateKey(b[:n]); err != nil {
+		return 0, err
+	}
+	b = b[n:]
+	if len(b) == 0 {
+		return 0, &SchemaError{pos, "version", "missing element"}
+	}
+	if k := kindOfCode(b[0]); k != KindInt {
+		return 0, &SchemaError{pos, "version", fmt.Sprintf("expected int, got %s", k)}
+	}
+	el, n, err := decodeElement(b)
+	if err != nil {
+		return 0, err
+	}
+	version := el.(int64)
+	s, err := v.lookup(version)
+	if err != nil {
+		return version, err
+	}
+	return version, shift(s.ValidateKey(b[n:]), pos+1)
+}
+
+func (v *Versions) lookup(version int64) (Schema, error) {
+	s, ok := v.Schema(version)
+	if !ok {
+		return s, &SchemaError{v.Position(), "version", fmt.Sprintf("unknown version %d", version)}
+	}
+	return s, nil
+}
+
+// shift adjusts by n the index of a *SchemaError returned for the elements
+// following the version.
+func shift(err error, n int) error {
+	if e, ok := err.(*SchemaError); ok {
+		return &SchemaError{e.Index + n, e.Field, e.Msg}
+	}
+	return err
+}
+
+// toInt64 converts an element of KindInt to an int64. It returns false for an
+// OpaqueElement of an integer typecode which does not hold exactly one
+// integer.
+func toInt64(el Element) (int64, bool) {
+	switch el := el.(type) {
+	case uint32:
+		return int64(el), true
+	case uint64:
+		return int64(el), true
+	case int:
+		return int64(el), true
+	case byte:
+		return int64(el), true
+	case OpaqueElement:
+		i, n, err := decodeInt(el)
+		return i, err == nil && n == len(el)
+	}
+	return el.(int64), true
+}
@@ -0,0 +1,23 @@
+package tuple
+
+import "testing"
+
+func TestVersionsOpaqueVersion(t *testing.T) {
+	v := NewVersions()
+	if err := v.Register(1, Schema{Fields: []Field{{"id", KindInt, false}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An opaque version holding an integer is decoded.
+	version, err := v.Validate(Tuple{OpaqueElement{0x15, 0x01}, int64(7)})
+	if err != nil || version != 1 {
+		t.Errorf("Validate with an opaque version = %d, %v, want 1, nil", version, err)
+	}
+
+	// A malformed one is rejected rather than panicking.
+	for _, el := range []OpaqueElement{{0x15}, {0x15, 0x01, 0x02}} {
+		if _, err := v.Validate(Tuple{el, int64(7)}); err == nil {
+			t.Errorf("Validate accepted the opaque version %x", []byte(el))
+		}
+	}
+}